@@ -4,6 +4,7 @@ using System.IO;
 using System.Net;
 using System.Net.Sockets;
 using System.Collections.Generic;
+using System.Security.Cryptography;
 
 // TODO: This has become way more than just network -- it is now also game state.
 // We should separate them and make the parent 'game state manager' that persists across scenes.
@@ -23,6 +24,24 @@ public class NetworkMessenger : MonoBehaviour
 	private Dictionary<uint, Multipart[]> multipart_cache = new Dictionary<uint, Multipart[]>();
 	private uint multi_groupid = 0;
 
+	// Encrypted session with the server, works the same as messages.Session on the server.
+	// Account names and passwords are only sent once the server says the session is ready.
+	private const byte TO_SERVER = 1;
+	private const byte TO_CLIENT = 2;
+	private const int SESSION_KEY_LEN = 32;
+	private const int TAG_LEN = 16;
+	private const int SECURE_OVERHEAD = NetPacket.DEFAULT_FRAME_LEN + 12 + TAG_LEN;
+	private AesGcm session;
+	private bool session_ready = false;
+	private ulong sent_counter = 0;
+	private ulong received_counter = 0;
+	private ulong received_window = 0; // Bit n set means received_counter-(n+1) was seen.
+	private Queue<KeyValuePair<MsgType, INet>> waiting_for_session = new Queue<KeyValuePair<MsgType, INet>>();
+	// Handshake messages aren't reliable, the last one is sent again until the server answers.
+	private const float HANDSHAKE_RESEND = 1.0f;
+	private KeyValuePair<MsgType, INet>? handshake;
+	private float handshake_sent;
+
 	// Use this for initialization
 	void Start()
 	{
@@ -31,7 +50,10 @@ public class NetworkMessenger : MonoBehaviour
 		this.sending_end_point = new IPEndPoint(send_to_address, 24816);
 		sending_socket.Connect(this.sending_end_point);
 
-		// 1. Fetch network!
+		// 1. Ask for the server key to set up an encrypted session.
+		this.sendHandshake(MsgType.KeyRequest, new KeyRequest());
+
+		// 2. Fetch network!
 		ListGames outmsg = new ListGames();
 		this.sendNetPacket(MsgType.ListGames, outmsg);
 
@@ -55,11 +77,16 @@ public class NetworkMessenger : MonoBehaviour
 		BinaryWriter buffer = new BinaryWriter(stream);
 		outmsg.Serialize(buffer);
 
-		if (buffer.BaseStream.Length + NetPacket.DEFAULT_FRAME_LEN > 512)
+		int limit = 512;
+		if (this.session_ready)
+		{
+			limit -= SECURE_OVERHEAD;
+		}
+		if (buffer.BaseStream.Length + NetPacket.DEFAULT_FRAME_LEN > limit)
 		{
 			msg.message_type = (byte)MsgType.Multipart;
 			//  calculate how many parts we have to split this into
-			int maxsize = 512 - (12+NetPacket.DEFAULT_FRAME_LEN);
+			int maxsize = limit - (12+NetPacket.DEFAULT_FRAME_LEN);
 			int parts = ((int)buffer.BaseStream.Length / maxsize) + 1;
 			this.multi_groupid++;
 			int bstart = 0;
@@ -81,7 +108,7 @@ public class NetworkMessenger : MonoBehaviour
 
 				msg.content = pstream.ToArray();
 				msg.content_length = (ushort)pstream.Length;
-				this.sending_socket.Send(msg.MessageBytes());
+				this.sendPacket(msg);
                 bstart = bend;
 			}
 		}
@@ -90,9 +117,173 @@ public class NetworkMessenger : MonoBehaviour
 			msg.content = stream.ToArray();
 			msg.content_length = (ushort)msg.content.Length;
 			msg.message_type = (byte)t;
-			this.sending_socket.Send(msg.MessageBytes());
+			this.sendPacket(msg);
+		}
+
+	}
+
+	// sendPacket writes the packet to the server, encrypted once the session is ready.
+	private void sendPacket(NetPacket msg)
+	{
+		if (this.session_ready)
+		{
+			msg = this.seal(msg);
+		}
+		this.sending_socket.Send(msg.MessageBytes());
+	}
+
+	// sendHandshake sends a handshake message and keeps it to send again if the server doesn't answer.
+	private void sendHandshake(MsgType t, INet outmsg)
+	{
+		this.handshake = new KeyValuePair<MsgType, INet>(t, outmsg);
+		this.handshake_sent = Time.time;
+		this.sendNetPacket(t, outmsg);
+	}
+
+	// sendAccountPacket sends a message with a password in it, waiting for the session if it isn't ready.
+	private void sendAccountPacket(MsgType t, INet outmsg)
+	{
+		if (!this.session_ready)
+		{
+			this.waiting_for_session.Enqueue(new KeyValuePair<MsgType, INet>(t, outmsg));
+			return;
+		}
+		this.sendNetPacket(t, outmsg);
+	}
+
+	// startSession answers the server key with a new session key encrypted by it.
+	private void startSession(ServerKey sk)
+	{
+		if (this.session != null)
+		{
+			return; // Resent key, the session is already started.
+		}
+		byte[] key = new byte[SESSION_KEY_LEN];
+		using (RandomNumberGenerator rng = RandomNumberGenerator.Create())
+		{
+			rng.GetBytes(key);
+		}
+		SessionKey outmsg = new SessionKey();
+		using (RSA rsa = RSA.Create())
+		{
+			rsa.ImportSubjectPublicKeyInfo(sk.Key, out _);
+			outmsg.Key = rsa.Encrypt(key, RSAEncryptionPadding.OaepSHA256);
+		}
+		// Keep the session before sending the key so the encrypted SessionReady can be opened.
+		// The key itself goes out unencrypted since session_ready is still false.
+		this.session = new AesGcm(key);
+		this.sendHandshake(MsgType.SessionKey, outmsg);
+	}
+
+	// seal encrypts the packet into a Secure packet that keeps the sequence of the original.
+	private NetPacket seal(NetPacket msg)
+	{
+		this.sent_counter++;
+		NetPacket outer = new NetPacket();
+		outer.message_type = (ushort)MsgType.Secure;
+		outer.sequence = msg.sequence;
+
+		NetPacket inner = new NetPacket();
+		inner.message_type = msg.message_type;
+		inner.content_length = msg.content_length;
+		inner.content = msg.content;
+		byte[] plain = inner.MessageBytes();
+		byte[] cipher = new byte[plain.Length];
+		byte[] tag = new byte[TAG_LEN];
+		this.session.Encrypt(Nonce(TO_SERVER, this.sent_counter), plain, cipher, tag, FrameData(outer));
+
+		Secure sec = new Secure();
+		sec.Counter = this.sent_counter;
+		sec.Content = new byte[cipher.Length + TAG_LEN];
+		Array.Copy(cipher, 0, sec.Content, 0, cipher.Length);
+		Array.Copy(tag, 0, sec.Content, cipher.Length, TAG_LEN);
+		MemoryStream stream = new MemoryStream();
+		sec.Serialize(new BinaryWriter(stream));
+		outer.content = stream.ToArray();
+		outer.content_length = (ushort)outer.content.Length;
+		return outer;
+	}
+
+	// open decrypts a Secure packet from the server, returns null if it isn't authentic or was already seen.
+	private NetPacket open(NetPacket outer, Secure sec)
+	{
+		if (this.session == null || sec.Content.Length < TAG_LEN)
+		{
+			return null;
+		}
+		int n = sec.Content.Length - TAG_LEN;
+		byte[] cipher = new byte[n];
+		byte[] tag = new byte[TAG_LEN];
+		byte[] plain = new byte[n];
+		Array.Copy(sec.Content, 0, cipher, 0, n);
+		Array.Copy(sec.Content, n, tag, 0, TAG_LEN);
+		try
+		{
+			this.session.Decrypt(Nonce(TO_CLIENT, sec.Counter), cipher, tag, plain, FrameData(outer));
+		}
+		catch (CryptographicException)
+		{
+			Debug.LogError("Failed to open secure message.");
+			return null;
+		}
+		if (!this.checkCounter(sec.Counter))
+		{
+			return null;
+		}
+		NetPacket inner = NetPacket.fromBytes(plain);
+		if (inner == null || inner.full_content == null)
+		{
+			return null;
+		}
+		inner.sequence = outer.sequence;
+		return inner;
+	}
+
+	// checkCounter records the counter in the replay window, returns false if it was already seen.
+	private bool checkCounter(ulong counter)
+	{
+		if (counter > this.received_counter)
+		{
+			ulong shift = counter - this.received_counter;
+			this.received_window = shift > 64 ? 0 : (this.received_window << (int)shift) | (1UL << (int)(shift - 1));
+			this.received_counter = counter;
+			return true;
+		}
+		ulong diff = this.received_counter - counter;
+		if (diff == 0 || diff > 64)
+		{
+			return false;
+		}
+		ulong bit = 1UL << (int)(diff - 1);
+		if ((this.received_window & bit) != 0)
+		{
+			return false;
+		}
+		this.received_window |= bit;
+		return true;
+	}
+
+	private static byte[] Nonce(byte dir, ulong counter)
+	{
+		byte[] nonce = new byte[12];
+		nonce[0] = dir;
+		for (int i = 0; i < 8; i++)
+		{
+			nonce[4 + i] = (byte)(counter >> (8 * i)); // Little endian in the last 8 bytes.
 		}
+		return nonce;
+	}
 
+	// FrameData is the part of the outer frame that is authenticated.
+	private static byte[] FrameData(NetPacket outer)
+	{
+		MemoryStream stream = new MemoryStream();
+		using (BinaryWriter writer = new BinaryWriter(stream))
+		{
+			writer.Write(outer.message_type);
+			writer.Write(outer.sequence);
+		}
+		return stream.ToArray();
 	}
 
 	public void CreateAccount(string name, string password)
@@ -101,7 +292,7 @@ public class NetworkMessenger : MonoBehaviour
 		outmsg.Name = name;
 		outmsg.Password = password;
         outmsg.CharName = name;
-		this.sendNetPacket(MsgType.CreateAcct, outmsg);
+		this.sendAccountPacket(MsgType.CreateAcct, outmsg);
 	}
 
 	public void Login(string name, string password)
@@ -109,7 +300,7 @@ public class NetworkMessenger : MonoBehaviour
 		Login login_msg = new Login();
 		login_msg.Name = name;
 		login_msg.Password = password;
-		this.sendNetPacket(MsgType.Login, login_msg);
+		this.sendAccountPacket(MsgType.Login, login_msg);
 	}
 
     public void CreateGame(string name)
@@ -189,6 +380,10 @@ public class NetworkMessenger : MonoBehaviour
 	// Update is called once per frame
 	void Update()
 	{
+		if (this.handshake.HasValue && Time.time - this.handshake_sent > HANDSHAKE_RESEND)
+		{
+			this.sendHandshake(this.handshake.Value.Key, this.handshake.Value.Value);
+		}
 		int loops = this.message_queue.Count;
 		for (int i = 0; i < loops; i++)
 		{
@@ -204,6 +399,25 @@ public class NetworkMessenger : MonoBehaviour
 		// Send updates to each object.
 		switch ((MsgType)np.message_type)
 		{
+			case MsgType.ServerKey:
+				this.startSession((ServerKey)parsedMsg);
+				break;
+			case MsgType.SessionReady:
+				this.handshake = null;
+				this.session_ready = true;
+				while (this.waiting_for_session.Count > 0)
+				{
+					KeyValuePair<MsgType, INet> waiting = this.waiting_for_session.Dequeue();
+					this.sendNetPacket(waiting.Key, waiting.Value);
+				}
+				break;
+			case MsgType.Secure:
+				NetPacket inner = this.open(np, (Secure)parsedMsg);
+				if (inner != null)
+				{
+					this.ParseAndProcess(inner);
+				}
+				break;
 			case MsgType.Multipart:
 				Multipart mpmsg = (Multipart)parsedMsg;
 				// 1. If this group doesn't exist, create it
@@ -7,7 +7,7 @@ interface INet {
 	void Deserialize(BinaryReader buffer);
 }
 
-enum MsgType : ushort {Unknown=0,Ack=1,Multipart=2,Heartbeat=3,Connected=4,Disconnected=5,CreateAcct=6,CreateAcctResp=7,Login=8,LoginResp=9,Character=10,ListGames=11,ListGamesResp=12,CreateGame=13,CreateGameResp=14,JoinGame=15,GameConnected=16,GameMasterFrame=17,Entity=18,MovePlayer=19,UseAbility=20,AbilityResult=21,EndGame=22}
+enum MsgType : ushort {Unknown=0,Ack=1,Multipart=2,Heartbeat=3,Connected=4,Disconnected=5,CreateAcct=6,CreateAcctResp=7,Login=8,LoginResp=9,Character=10,ListGames=11,ListGamesResp=12,CreateGame=13,CreateGameResp=14,JoinGame=15,GameConnected=16,GameMasterFrame=17,Entity=18,MovePlayer=19,UseAbility=20,AbilityResult=21,EndGame=22,KeyRequest=23,ServerKey=24,SessionKey=25,SessionReady=26,Secure=27,JoinGameFailed=28,EntitiesEntered=29,EntitiesLeft=30,GameDeltaFrame=31,FrameAck=32,ProjectileHit=33,CharacterDied=34,Stats=35,Item=36,PickupItem=37,DropItem=38,EquipItem=39,UnequipItem=40,InventoryUpdate=41,Harvest=42,Craft=43,CraftResult=44,Consume=45,CharacterStatus=46}
 
 static class Messages {
 // ParseNetMessage accepts input of raw bytes from a NetMessage. Parses and returns a Net message.
@@ -16,6 +16,9 @@ public static INet Parse(ushort msgType, byte[] content) {
 	MsgType mt = (MsgType)msgType;
 	switch (mt)
 	{
+		case MsgType.Ack:
+			msg = new Ack();
+			break;
 		case MsgType.Multipart:
 			msg = new Multipart();
 			break;
@@ -79,6 +82,78 @@ public static INet Parse(ushort msgType, byte[] content) {
 		case MsgType.EndGame:
 			msg = new EndGame();
 			break;
+		case MsgType.KeyRequest:
+			msg = new KeyRequest();
+			break;
+		case MsgType.ServerKey:
+			msg = new ServerKey();
+			break;
+		case MsgType.SessionKey:
+			msg = new SessionKey();
+			break;
+		case MsgType.SessionReady:
+			msg = new SessionReady();
+			break;
+		case MsgType.Secure:
+			msg = new Secure();
+			break;
+		case MsgType.JoinGameFailed:
+			msg = new JoinGameFailed();
+			break;
+		case MsgType.EntitiesEntered:
+			msg = new EntitiesEntered();
+			break;
+		case MsgType.EntitiesLeft:
+			msg = new EntitiesLeft();
+			break;
+		case MsgType.GameDeltaFrame:
+			msg = new GameDeltaFrame();
+			break;
+		case MsgType.FrameAck:
+			msg = new FrameAck();
+			break;
+		case MsgType.ProjectileHit:
+			msg = new ProjectileHit();
+			break;
+		case MsgType.CharacterDied:
+			msg = new CharacterDied();
+			break;
+		case MsgType.Stats:
+			msg = new Stats();
+			break;
+		case MsgType.Item:
+			msg = new Item();
+			break;
+		case MsgType.PickupItem:
+			msg = new PickupItem();
+			break;
+		case MsgType.DropItem:
+			msg = new DropItem();
+			break;
+		case MsgType.EquipItem:
+			msg = new EquipItem();
+			break;
+		case MsgType.UnequipItem:
+			msg = new UnequipItem();
+			break;
+		case MsgType.InventoryUpdate:
+			msg = new InventoryUpdate();
+			break;
+		case MsgType.Harvest:
+			msg = new Harvest();
+			break;
+		case MsgType.Craft:
+			msg = new Craft();
+			break;
+		case MsgType.CraftResult:
+			msg = new CraftResult();
+			break;
+		case MsgType.Consume:
+			msg = new Consume();
+			break;
+		case MsgType.CharacterStatus:
+			msg = new CharacterStatus();
+			break;
 	}
 	MemoryStream ms = new MemoryStream(content);
 	msg.Deserialize(new BinaryReader(ms));
@@ -86,6 +161,21 @@ public static INet Parse(ushort msgType, byte[] content) {
 }
 }
 
+public class Ack : INet {
+	public ushort Seq;
+	public uint Bits;
+
+	public void Serialize(BinaryWriter buffer) {
+		buffer.Write(this.Seq);
+		buffer.Write(this.Bits);
+	}
+
+	public void Deserialize(BinaryReader buffer) {
+		this.Seq = buffer.ReadUInt16();
+		this.Bits = buffer.ReadUInt32();
+	}
+}
+
 public class Multipart : INet {
 	public ushort ID;
 	public uint GroupID;
@@ -375,10 +465,12 @@ public class GameConnected : INet {
 
 public class GameMasterFrame : INet {
 	public uint ID;
+	public uint TickID;
 	public Entity[] Entities;
 
 	public void Serialize(BinaryWriter buffer) {
 		buffer.Write(this.ID);
+		buffer.Write(this.TickID);
 		buffer.Write((Int32)this.Entities.Length);
 		for (int v2 = 0; v2 < this.Entities.Length; v2++) {
 			this.Entities[v2].Serialize(buffer);
@@ -387,9 +479,10 @@ public class GameMasterFrame : INet {
 
 	public void Deserialize(BinaryReader buffer) {
 		this.ID = buffer.ReadUInt32();
-		int l1_1 = buffer.ReadInt32();
-		this.Entities = new Entity[l1_1];
-		for (int v2 = 0; v2 < l1_1; v2++) {
+		this.TickID = buffer.ReadUInt32();
+		int l2_1 = buffer.ReadInt32();
+		this.Entities = new Entity[l2_1];
+		for (int v2 = 0; v2 < l2_1; v2++) {
 			this.Entities[v2] = new Entity();
 			this.Entities[v2].Deserialize(buffer);
 		}
@@ -475,17 +568,23 @@ public class UseAbility : INet {
 }
 
 public class AbilityResult : INet {
+	public uint EntityID;
+	public uint AbilityID;
 	public Entity Target;
 	public int Damage;
 	public byte State;
 
 	public void Serialize(BinaryWriter buffer) {
+		buffer.Write(this.EntityID);
+		buffer.Write(this.AbilityID);
 		this.Target.Serialize(buffer);
 		buffer.Write(this.Damage);
 		buffer.Write(this.State);
 	}
 
 	public void Deserialize(BinaryReader buffer) {
+		this.EntityID = buffer.ReadUInt32();
+		this.AbilityID = buffer.ReadUInt32();
 		this.Target = new Entity();
 		this.Target.Deserialize(buffer);
 		this.Damage = buffer.ReadInt32();
@@ -505,3 +604,443 @@ public class EndGame : INet {
 	}
 }
 
+public class KeyRequest : INet {
+
+	public void Serialize(BinaryWriter buffer) {
+	}
+
+	public void Deserialize(BinaryReader buffer) {
+	}
+}
+
+public class ServerKey : INet {
+	public byte[] Key;
+
+	public void Serialize(BinaryWriter buffer) {
+		buffer.Write((Int32)this.Key.Length);
+		for (int v2 = 0; v2 < this.Key.Length; v2++) {
+			buffer.Write(this.Key[v2]);
+		}
+	}
+
+	public void Deserialize(BinaryReader buffer) {
+		int l0_1 = buffer.ReadInt32();
+		this.Key = new byte[l0_1];
+		for (int v2 = 0; v2 < l0_1; v2++) {
+			this.Key[v2] = buffer.ReadByte();
+		}
+	}
+}
+
+public class SessionKey : INet {
+	public byte[] Key;
+
+	public void Serialize(BinaryWriter buffer) {
+		buffer.Write((Int32)this.Key.Length);
+		for (int v2 = 0; v2 < this.Key.Length; v2++) {
+			buffer.Write(this.Key[v2]);
+		}
+	}
+
+	public void Deserialize(BinaryReader buffer) {
+		int l0_1 = buffer.ReadInt32();
+		this.Key = new byte[l0_1];
+		for (int v2 = 0; v2 < l0_1; v2++) {
+			this.Key[v2] = buffer.ReadByte();
+		}
+	}
+}
+
+public class SessionReady : INet {
+
+	public void Serialize(BinaryWriter buffer) {
+	}
+
+	public void Deserialize(BinaryReader buffer) {
+	}
+}
+
+public class Secure : INet {
+	public ulong Counter;
+	public byte[] Content;
+
+	public void Serialize(BinaryWriter buffer) {
+		buffer.Write(this.Counter);
+		buffer.Write((Int32)this.Content.Length);
+		for (int v2 = 0; v2 < this.Content.Length; v2++) {
+			buffer.Write(this.Content[v2]);
+		}
+	}
+
+	public void Deserialize(BinaryReader buffer) {
+		this.Counter = buffer.ReadUInt64();
+		int l1_1 = buffer.ReadInt32();
+		this.Content = new byte[l1_1];
+		for (int v2 = 0; v2 < l1_1; v2++) {
+			this.Content[v2] = buffer.ReadByte();
+		}
+	}
+}
+
+public class JoinGameFailed : INet {
+	public uint ID;
+	public byte Reason;
+
+	public void Serialize(BinaryWriter buffer) {
+		buffer.Write(this.ID);
+		buffer.Write(this.Reason);
+	}
+
+	public void Deserialize(BinaryReader buffer) {
+		this.ID = buffer.ReadUInt32();
+		this.Reason = buffer.ReadByte();
+	}
+}
+
+public class EntitiesEntered : INet {
+	public uint GameID;
+	public uint TickID;
+	public Entity[] Entities;
+
+	public void Serialize(BinaryWriter buffer) {
+		buffer.Write(this.GameID);
+		buffer.Write(this.TickID);
+		buffer.Write((Int32)this.Entities.Length);
+		for (int v2 = 0; v2 < this.Entities.Length; v2++) {
+			this.Entities[v2].Serialize(buffer);
+		}
+	}
+
+	public void Deserialize(BinaryReader buffer) {
+		this.GameID = buffer.ReadUInt32();
+		this.TickID = buffer.ReadUInt32();
+		int l2_1 = buffer.ReadInt32();
+		this.Entities = new Entity[l2_1];
+		for (int v2 = 0; v2 < l2_1; v2++) {
+			this.Entities[v2] = new Entity();
+			this.Entities[v2].Deserialize(buffer);
+		}
+	}
+}
+
+public class EntitiesLeft : INet {
+	public uint GameID;
+	public uint TickID;
+	public uint[] IDs;
+
+	public void Serialize(BinaryWriter buffer) {
+		buffer.Write(this.GameID);
+		buffer.Write(this.TickID);
+		buffer.Write((Int32)this.IDs.Length);
+		for (int v2 = 0; v2 < this.IDs.Length; v2++) {
+			buffer.Write(this.IDs[v2]);
+		}
+	}
+
+	public void Deserialize(BinaryReader buffer) {
+		this.GameID = buffer.ReadUInt32();
+		this.TickID = buffer.ReadUInt32();
+		int l2_1 = buffer.ReadInt32();
+		this.IDs = new uint[l2_1];
+		for (int v2 = 0; v2 < l2_1; v2++) {
+			this.IDs[v2] = buffer.ReadUInt32();
+		}
+	}
+}
+
+public class GameDeltaFrame : INet {
+	public uint ID;
+	public uint TickID;
+	public uint BaseTick;
+	public byte[] Deltas;
+
+	public void Serialize(BinaryWriter buffer) {
+		buffer.Write(this.ID);
+		buffer.Write(this.TickID);
+		buffer.Write(this.BaseTick);
+		buffer.Write((Int32)this.Deltas.Length);
+		for (int v2 = 0; v2 < this.Deltas.Length; v2++) {
+			buffer.Write(this.Deltas[v2]);
+		}
+	}
+
+	public void Deserialize(BinaryReader buffer) {
+		this.ID = buffer.ReadUInt32();
+		this.TickID = buffer.ReadUInt32();
+		this.BaseTick = buffer.ReadUInt32();
+		int l3_1 = buffer.ReadInt32();
+		this.Deltas = new byte[l3_1];
+		for (int v2 = 0; v2 < l3_1; v2++) {
+			this.Deltas[v2] = buffer.ReadByte();
+		}
+	}
+}
+
+public class FrameAck : INet {
+	public uint GameID;
+	public uint TickID;
+
+	public void Serialize(BinaryWriter buffer) {
+		buffer.Write(this.GameID);
+		buffer.Write(this.TickID);
+	}
+
+	public void Deserialize(BinaryReader buffer) {
+		this.GameID = buffer.ReadUInt32();
+		this.TickID = buffer.ReadUInt32();
+	}
+}
+
+public class ProjectileHit : INet {
+	public uint ProjectileID;
+	public uint TargetID;
+	public int Damage;
+
+	public void Serialize(BinaryWriter buffer) {
+		buffer.Write(this.ProjectileID);
+		buffer.Write(this.TargetID);
+		buffer.Write(this.Damage);
+	}
+
+	public void Deserialize(BinaryReader buffer) {
+		this.ProjectileID = buffer.ReadUInt32();
+		this.TargetID = buffer.ReadUInt32();
+		this.Damage = buffer.ReadInt32();
+	}
+}
+
+public class CharacterDied : INet {
+	public uint EntityID;
+	public uint KillerID;
+	public uint RespawnTick;
+
+	public void Serialize(BinaryWriter buffer) {
+		buffer.Write(this.EntityID);
+		buffer.Write(this.KillerID);
+		buffer.Write(this.RespawnTick);
+	}
+
+	public void Deserialize(BinaryReader buffer) {
+		this.EntityID = buffer.ReadUInt32();
+		this.KillerID = buffer.ReadUInt32();
+		this.RespawnTick = buffer.ReadUInt32();
+	}
+}
+
+public class Stats : INet {
+	public int HP;
+	public short Stamina;
+	public short Concentration;
+	public short Speed;
+	public short MagicStrength;
+	public short PhysicalStrength;
+
+	public void Serialize(BinaryWriter buffer) {
+		buffer.Write(this.HP);
+		buffer.Write(this.Stamina);
+		buffer.Write(this.Concentration);
+		buffer.Write(this.Speed);
+		buffer.Write(this.MagicStrength);
+		buffer.Write(this.PhysicalStrength);
+	}
+
+	public void Deserialize(BinaryReader buffer) {
+		this.HP = buffer.ReadInt32();
+		this.Stamina = buffer.ReadInt16();
+		this.Concentration = buffer.ReadInt16();
+		this.Speed = buffer.ReadInt16();
+		this.MagicStrength = buffer.ReadInt16();
+		this.PhysicalStrength = buffer.ReadInt16();
+	}
+}
+
+public class Item : INet {
+	public uint ID;
+	public ushort Kind;
+	public ushort Count;
+	public byte Slot;
+	public Stats Stats;
+
+	public void Serialize(BinaryWriter buffer) {
+		buffer.Write(this.ID);
+		buffer.Write(this.Kind);
+		buffer.Write(this.Count);
+		buffer.Write(this.Slot);
+		this.Stats.Serialize(buffer);
+	}
+
+	public void Deserialize(BinaryReader buffer) {
+		this.ID = buffer.ReadUInt32();
+		this.Kind = buffer.ReadUInt16();
+		this.Count = buffer.ReadUInt16();
+		this.Slot = buffer.ReadByte();
+		this.Stats = new Stats();
+		this.Stats.Deserialize(buffer);
+	}
+}
+
+public class PickupItem : INet {
+	public uint EntityID;
+
+	public void Serialize(BinaryWriter buffer) {
+		buffer.Write(this.EntityID);
+	}
+
+	public void Deserialize(BinaryReader buffer) {
+		this.EntityID = buffer.ReadUInt32();
+	}
+}
+
+public class DropItem : INet {
+	public uint ItemID;
+
+	public void Serialize(BinaryWriter buffer) {
+		buffer.Write(this.ItemID);
+	}
+
+	public void Deserialize(BinaryReader buffer) {
+		this.ItemID = buffer.ReadUInt32();
+	}
+}
+
+public class EquipItem : INet {
+	public uint ItemID;
+	public byte Slot;
+
+	public void Serialize(BinaryWriter buffer) {
+		buffer.Write(this.ItemID);
+		buffer.Write(this.Slot);
+	}
+
+	public void Deserialize(BinaryReader buffer) {
+		this.ItemID = buffer.ReadUInt32();
+		this.Slot = buffer.ReadByte();
+	}
+}
+
+public class UnequipItem : INet {
+	public byte Slot;
+
+	public void Serialize(BinaryWriter buffer) {
+		buffer.Write(this.Slot);
+	}
+
+	public void Deserialize(BinaryReader buffer) {
+		this.Slot = buffer.ReadByte();
+	}
+}
+
+public class InventoryUpdate : INet {
+	public Item[] Equipped;
+	public Item[] Inventory;
+	public Stats Current;
+	public Stats Max;
+
+	public void Serialize(BinaryWriter buffer) {
+		buffer.Write((Int32)this.Equipped.Length);
+		for (int v2 = 0; v2 < this.Equipped.Length; v2++) {
+			this.Equipped[v2].Serialize(buffer);
+		}
+		buffer.Write((Int32)this.Inventory.Length);
+		for (int v2 = 0; v2 < this.Inventory.Length; v2++) {
+			this.Inventory[v2].Serialize(buffer);
+		}
+		this.Current.Serialize(buffer);
+		this.Max.Serialize(buffer);
+	}
+
+	public void Deserialize(BinaryReader buffer) {
+		int l0_1 = buffer.ReadInt32();
+		this.Equipped = new Item[l0_1];
+		for (int v2 = 0; v2 < l0_1; v2++) {
+			this.Equipped[v2] = new Item();
+			this.Equipped[v2].Deserialize(buffer);
+		}
+		int l1_1 = buffer.ReadInt32();
+		this.Inventory = new Item[l1_1];
+		for (int v2 = 0; v2 < l1_1; v2++) {
+			this.Inventory[v2] = new Item();
+			this.Inventory[v2].Deserialize(buffer);
+		}
+		this.Current = new Stats();
+		this.Current.Deserialize(buffer);
+		this.Max = new Stats();
+		this.Max.Deserialize(buffer);
+	}
+}
+
+public class Harvest : INet {
+	public uint EntityID;
+
+	public void Serialize(BinaryWriter buffer) {
+		buffer.Write(this.EntityID);
+	}
+
+	public void Deserialize(BinaryReader buffer) {
+		this.EntityID = buffer.ReadUInt32();
+	}
+}
+
+public class Craft : INet {
+	public uint RecipeID;
+
+	public void Serialize(BinaryWriter buffer) {
+		buffer.Write(this.RecipeID);
+	}
+
+	public void Deserialize(BinaryReader buffer) {
+		this.RecipeID = buffer.ReadUInt32();
+	}
+}
+
+public class CraftResult : INet {
+	public uint RecipeID;
+	public byte State;
+	public uint DoneTick;
+	public uint ItemID;
+
+	public void Serialize(BinaryWriter buffer) {
+		buffer.Write(this.RecipeID);
+		buffer.Write(this.State);
+		buffer.Write(this.DoneTick);
+		buffer.Write(this.ItemID);
+	}
+
+	public void Deserialize(BinaryReader buffer) {
+		this.RecipeID = buffer.ReadUInt32();
+		this.State = buffer.ReadByte();
+		this.DoneTick = buffer.ReadUInt32();
+		this.ItemID = buffer.ReadUInt32();
+	}
+}
+
+public class Consume : INet {
+	public uint ItemID;
+
+	public void Serialize(BinaryWriter buffer) {
+		buffer.Write(this.ItemID);
+	}
+
+	public void Deserialize(BinaryReader buffer) {
+		this.ItemID = buffer.ReadUInt32();
+	}
+}
+
+public class CharacterStatus : INet {
+	public byte Hunger;
+	public byte Thirst;
+	public short Temperature;
+
+	public void Serialize(BinaryWriter buffer) {
+		buffer.Write(this.Hunger);
+		buffer.Write(this.Thirst);
+		buffer.Write(this.Temperature);
+	}
+
+	public void Deserialize(BinaryReader buffer) {
+		this.Hunger = buffer.ReadByte();
+		this.Thirst = buffer.ReadByte();
+		this.Temperature = buffer.ReadInt16();
+	}
+}
+
@@ -14,11 +14,11 @@ func WriteCS(messages []Message, messageMap map[string]Message) {
 	gobuf.WriteString("interface INet {\n\tvoid Serialize(BinaryWriter buffer);\n\tvoid Deserialize(BinaryReader buffer);\n}\n\n")
 
 	// Message type enum
-	gobuf.WriteString("enum MsgType : ushort {Unknown=0,Ack=1,")
+	gobuf.WriteString("enum MsgType : ushort {Unknown=0,")
 	for idx, t := range messages {
 		gobuf.WriteString(t.Name)
 		gobuf.WriteString("=")
-		gobuf.WriteString(strconv.Itoa(idx + 2))
+		gobuf.WriteString(strconv.Itoa(idx + 1))
 		if idx < len(messages)-1 {
 			gobuf.WriteString(",")
 		}
@@ -12,7 +12,7 @@ func WriteGo(messages []Message, messageMap map[string]Message) {
 	// 1. List type values!
 	gobuf.WriteString("type Net interface {\n\tSerialize(*bytes.Buffer)\n\tDeserialize(*bytes.Buffer)\n\tLen() int\n}\n\n")
 	gobuf.WriteString("type MessageType uint16\n\n")
-	gobuf.WriteString("const (\n\tUnknownMsgType MessageType = iota\n\tAckMsgType\n")
+	gobuf.WriteString("const (\n\tUnknownMsgType MessageType = iota\n")
 	for _, t := range messages {
 		gobuf.WriteString("\t")
 		gobuf.WriteString(t.Name)
@@ -251,6 +251,7 @@ func WriteGoDeserial(f MessageField, scopeDepth int, buf *bytes.Buffer, messages
 		buf.WriteString("binary.Read(buffer, binary.LittleEndian, &")
 		buf.WriteString(lname)
 		buf.WriteString(")\n")
+		writeGoLenCheck(lname, scopeDepth, buf)
 		for i := 0; i < scopeDepth; i++ {
 			buf.WriteString("\t")
 		}
@@ -288,6 +289,7 @@ func WriteGoDeserial(f MessageField, scopeDepth int, buf *bytes.Buffer, messages
 			buf.WriteString("binary.Read(buffer, binary.LittleEndian, &")
 			buf.WriteString(lname)
 			buf.WriteString(")\n")
+			writeGoLenCheck(lname, scopeDepth, buf)
 
 			// Create array variable
 			for i := 0; i < scopeDepth; i++ {
@@ -342,3 +344,24 @@ func WriteGoDeserial(f MessageField, scopeDepth int, buf *bytes.Buffer, messages
 	}
 
 }
+
+// writeGoLenCheck makes sure a length read from the wire is not negative and not
+// longer than the bytes left, so a bad message can't cause a huge allocation or panic.
+func writeGoLenCheck(lname string, scopeDepth int, buf *bytes.Buffer) {
+	for i := 0; i < scopeDepth; i++ {
+		buf.WriteString("\t")
+	}
+	buf.WriteString("if ")
+	buf.WriteString(lname)
+	buf.WriteString(" < 0 || int(")
+	buf.WriteString(lname)
+	buf.WriteString(") > buffer.Len() {\n")
+	for i := 0; i < scopeDepth+1; i++ {
+		buf.WriteString("\t")
+	}
+	buf.WriteString("return\n")
+	for i := 0; i < scopeDepth; i++ {
+		buf.WriteString("\t")
+	}
+	buf.WriteString("}\n")
+}
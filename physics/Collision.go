@@ -0,0 +1,81 @@
+package physics
+
+import "math"
+
+// Restitution is how much of the closing speed is kept when two bodies collide.
+// 0 means bodies stop dead against each other instead of bouncing.
+const Restitution = 0.0
+
+// Contact describes two overlapping bodies.
+type Contact struct {
+	A, B        *RigidBody
+	Normal      Vect2 // Unit axis pointing from A to B.
+	Penetration int32 // How far the bodies overlap along Normal.
+}
+
+// FindContact is the narrow phase check between two bodies. Bodies are treated as
+// their bounding boxes, so the contact normal is always along the X or Y axis.
+// Boxes that only touch edges are not in contact.
+func FindContact(a, b *RigidBody) (Contact, bool) {
+	ab, bb := a.Bounds(), b.Bounds()
+	overlapX := min32(ab.MaxX, bb.MaxX) - max32(ab.MinX, bb.MinX)
+	overlapY := min32(ab.MaxY, bb.MaxY) - max32(ab.MinY, bb.MinY)
+	if overlapX <= 0 || overlapY <= 0 {
+		return Contact{}, false
+	}
+	c := Contact{A: a, B: b}
+	if overlapX <= overlapY {
+		c.Penetration = overlapX
+		c.Normal = Vect2{X: 1}
+		if b.Position.X < a.Position.X {
+			c.Normal.X = -1
+		}
+	} else {
+		c.Penetration = overlapY
+		c.Normal = Vect2{Y: 1}
+		if b.Position.Y < a.Position.Y {
+			c.Normal.Y = -1
+		}
+	}
+	return c, true
+}
+
+// Resolve pushes the bodies apart and applies an impulse so they stop moving into each other.
+// Bodies with an InvMass of 0 have infinite mass and are never moved.
+// Contacts are along an axis through both centers so no torque is applied.
+func (c Contact) Resolve() {
+	invA, invB := c.A.InvMass, c.B.InvMass
+	invSum := invA + invB
+	if invSum == 0 {
+		return
+	}
+
+	// Move each body out by its share of the overlap, lighter bodies move more.
+	moveA := int32(math.Round(float64(c.Penetration) * invA / invSum))
+	moveB := c.Penetration - moveA
+	c.A.Position = SubVect2(c.A.Position, MultVect2(c.Normal, moveA))
+	c.B.Position = AddVect2(c.B.Position, MultVect2(c.Normal, moveB))
+
+	rv := SubVect2(c.B.Velocity, c.A.Velocity)
+	closing := float64(rv.X*c.Normal.X + rv.Y*c.Normal.Y)
+	if closing >= 0 {
+		return // Already separating.
+	}
+	j := -(1 + Restitution) * closing / invSum
+	c.A.Velocity = SubVect2(c.A.Velocity, ScaleVect2(c.Normal, j*invA))
+	c.B.Velocity = AddVect2(c.B.Velocity, ScaleVect2(c.Normal, j*invB))
+}
+
+func min32(a, b int32) int32 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max32(a, b int32) int32 {
+	if a > b {
+		return a
+	}
+	return b
+}
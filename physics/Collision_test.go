@@ -0,0 +1,93 @@
+package physics
+
+import "testing"
+
+func TestFindContact(t *testing.T) {
+	a := NewRigidBody(1, 10, 10, Vect2{0, 0}, Vect2{}, 0, 10)
+	b := NewRigidBody(2, 10, 10, Vect2{8, 2}, Vect2{}, 0, 10)
+	c, ok := FindContact(a, b)
+	if !ok || c.Normal != (Vect2{X: 1}) || c.Penetration != 2 {
+		t.Fatalf("Expected contact on X axis with penetration 2, got %v %v", ok, c)
+	}
+	c, ok = FindContact(b, a)
+	if !ok || c.Normal != (Vect2{X: -1}) {
+		t.Fatalf("Expected reversed normal, got %v", c.Normal)
+	}
+
+	b.Position = Vect2{10, 0}
+	if _, ok := FindContact(a, b); ok {
+		t.Fatalf("Touching edges should not be a contact.")
+	}
+}
+
+func TestStopAtFixedBody(t *testing.T) {
+	for _, tree := range []*RigidBody{
+		{ID: 2, Position: Vect2{100, 5}, Height: 50, Width: 50},
+		NewRigidBody(2, 50, 50, Vect2{100, 5}, Vect2{}, 0, 1), // Fixed bodies never move, even when lighter.
+	} {
+		ss := NewSimulatedSpace()
+		player := NewRigidBody(1, 20, 20, Vect2{0, 0}, Vect2{500, 0}, 0, 100)
+		ss.AddEntity(player, false)
+		ss.AddEntity(tree, true)
+
+		collided := false
+		for i := 0; i < 50; i++ {
+			collided = len(ss.Tick(true)) > 0 || collided
+		}
+		if !collided {
+			t.Fatalf("Expected player to collide with the tree.")
+		}
+		if player.Bounds().MaxX > tree.Bounds().MinX {
+			t.Fatalf("Player went into the tree: %v", player.Position)
+		}
+		if player.Velocity.X != 0 {
+			t.Fatalf("Player should have stopped, velocity: %v", player.Velocity)
+		}
+		if tree.Position != (Vect2{100, 5}) || tree.Velocity != (Vect2{}) {
+			t.Fatalf("Fixed body with mass %d was moved by the collision.", tree.Mass)
+		}
+	}
+}
+
+func TestCollisionMomentum(t *testing.T) {
+	ss := NewSimulatedSpace()
+	a := NewRigidBody(1, 20, 20, Vect2{0, 0}, Vect2{400, 0}, 0, 100)
+	b := NewRigidBody(2, 20, 20, Vect2{60, 0}, Vect2{}, 0, 300)
+	ss.AddEntity(a, false)
+	ss.AddEntity(b, false)
+
+	before := a.Mass*a.Velocity.X + b.Mass*b.Velocity.X
+	for i := 0; i < 20; i++ {
+		ss.Tick(false)
+	}
+	after := a.Mass*a.Velocity.X + b.Mass*b.Velocity.X
+	if b.Velocity.X <= 0 {
+		t.Fatalf("Heavier body was not pushed.")
+	}
+	if after != before {
+		t.Fatalf("Momentum changed from %d to %d", before, after)
+	}
+	if _, ok := FindContact(a, b); ok {
+		t.Fatalf("Bodies are still overlapping: %v %v", a.Position, b.Position)
+	}
+}
+
+func TestSensorNotPushed(t *testing.T) {
+	ss := NewSimulatedSpace()
+	sensor := NewRigidBody(1, 20, 20, Vect2{0, 0}, Vect2{500, 0}, 0, 1)
+	sensor.Sensor = true
+	wall := &RigidBody{ID: 2, Position: Vect2{30, 0}, Height: 50, Width: 50}
+	ss.AddEntity(sensor, false)
+	ss.AddEntity(wall, true)
+
+	collided := false
+	for i := 0; i < 10; i++ {
+		collided = len(ss.Tick(true)) > 0 || collided
+	}
+	if !collided {
+		t.Fatalf("Sensor should still report collisions.")
+	}
+	if sensor.Velocity.X != 500 || sensor.Position.X != 100 {
+		t.Fatalf("Sensor was pushed: %v %v", sensor.Position, sensor.Velocity)
+	}
+}
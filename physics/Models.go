@@ -23,6 +23,11 @@ func MultVect2(a Vect2, s int32) Vect2 {
 	return Vect2{a.X * s, a.Y * s}
 }
 
+// ScaleVect2 multiplies a vector by a fractional amount, rounding to the nearest unit.
+func ScaleVect2(a Vect2, s float64) Vect2 {
+	return Vect2{int32(math.Round(float64(a.X) * s)), int32(math.Round(float64(a.Y) * s))}
+}
+
 func AngleVect2(a Vect2, b Vect2) float64 {
 	alpha := float64(a.X*a.X+a.Y*b.Y) / (a.Magnitude() * b.Magnitude())
 	return math.Acos(alpha)
@@ -80,13 +85,18 @@ type RigidBody struct {
 	AngularVelocity float64 // speed of rotation around the Z axis (radians/sec)
 	Torque          float64 // Torque to apply each tick
 
-	Mass       int32 // Mass of the object, (kg)
-	InvMass    int32 // Inverted mass for physics calcs
-	Inertia    int32 // Inertia of the object
-	InvInertia int32 // Inverted Inertia for physics calcs
+	Mass       int32   // Mass of the object, (kg)
+	InvMass    float64 // Inverted mass for physics calcs, 0 means infinite mass (never moved by collisions)
+	Inertia    int32   // Inertia of the object
+	InvInertia float64 // Inverted Inertia for physics calcs
 
 	Height int32
 	Width  int32
+
+	Sensor bool // Sensors report collisions but never push or get pushed by other bodies.
+
+	indexed quadtree.BoundingBox // Bounds this body was last added to the space's tree with.
+	slot    int                  // Index in the space's Entities, kept by the space for bodies that move.
 }
 
 func (rb RigidBody) BoxID() uint32 {
@@ -119,17 +129,25 @@ func (rb RigidBody) Clone() quadtree.BoundingBoxer {
 	return nrb
 }
 
+// NewRigidBody creates a body, a mass of 0 makes a body with infinite mass.
 func NewRigidBody(id uint32, h int32, w int32, pos Vect2, vel Vect2, angle float64, mass int32) *RigidBody {
-	return &RigidBody{
+	rb := &RigidBody{
 		ID:       id,
 		Position: pos,
 		Velocity: vel,
 		Angle:    angle,
 		Mass:     mass,
-		InvMass:  1 / mass,
+		Inertia:  mass * (h*h + w*w) / 12, // Solid box
 		Height:   h,
 		Width:    w,
 	}
+	if rb.Mass > 0 {
+		rb.InvMass = 1 / float64(rb.Mass)
+	}
+	if rb.Inertia > 0 {
+		rb.InvInertia = 1 / float64(rb.Inertia)
+	}
+	return rb
 }
 
 // PhysicsEntityUpdate message linked to an Entity.
@@ -25,79 +25,168 @@ const (
 //  4.
 
 func NewSimulatedSpace() *SimulatedSpace {
+	// The trees grow as bodies are added further out, this is just where they start.
 	world := quadtree.NewBoundingBox(-10000.0, 10000.0, -10000.0, 10000.0)
 	return &SimulatedSpace{
-		tree:     quadtree.NewQuadTree(world),
-		Entities: make([]*RigidBody, 1000),
-		Fixed:    make([]*RigidBody, 1000),
+		tree:      quadtree.NewQuadTree(world),
+		fixedTree: quadtree.NewQuadTree(world),
+		Entities:  make([]*RigidBody, 0, 1000),
+		Fixed:     make([]*RigidBody, 0, 1000),
 	}
 }
 
 type SimulatedSpace struct {
-	tree     quadtree.QuadTree
-	Entities []*RigidBody // Anything that can collide in the playspace
-	Fixed    []*RigidBody // Anything that can collide but is fixed in place.
-	entidx   int
-	fixidx   int
+	tree      quadtree.QuadTree // Bodies in Entities
+	fixedTree quadtree.QuadTree // Bodies in Fixed, kept apart so snapshots can share it.
+	Entities  []*RigidBody      // Anything that can collide in the playspace, only bodies still in the space.
+	Fixed     []*RigidBody      // Anything that can collide but is fixed in place, only bodies still in the space.
+
+	// fixedShared is set once Fixed and fixedTree are shared with a clone, they are
+	// copied before the next change to fixed bodies so the clone doesn't see it.
+	fixedShared bool
 
 	lastUpdate time.Time
 	TickID     uint32
 }
 
+// AddEntity adds the body to the space. Fixed bodies are given infinite mass so
+// collisions never move them, whatever their Mass is. Fixed bodies are shared with
+// clones of the space so they must not be changed once added, to change one remove
+// it and add a changed copy.
 func (ss *SimulatedSpace) AddEntity(body *RigidBody, fixed bool) {
+	body.indexed = body.Bounds()
 	if fixed {
-		ss.Fixed[ss.fixidx] = body
-		ss.fixidx++
+		ss.ownFixed()
+		body.InvMass = 0
+		body.InvInertia = 0
+		ss.Fixed = append(ss.Fixed, body)
+		ss.fixedTree.Add(body)
 		return
 	}
-	ss.Entities[ss.entidx] = body
-	ss.entidx++
+	body.slot = len(ss.Entities)
+	ss.Entities = append(ss.Entities, body)
 	ss.tree.Add(body)
 }
 
+// RemoveEntity takes the body out of the space. The last body is moved into its
+// place so the lists only ever hold bodies that are still in the space.
 func (ss *SimulatedSpace) RemoveEntity(body *RigidBody, fixed bool) {
 	if fixed {
+		ss.ownFixed()
+		// Fixed bodies are shared with clones that may keep them in a different order,
+		// so they can't remember their own index like moving bodies do.
 		for cidx, f := range ss.Fixed {
 			if f.ID == body.ID {
-				ss.Fixed[cidx] = nil
+				last := len(ss.Fixed) - 1
+				ss.Fixed[cidx] = ss.Fixed[last]
+				ss.Fixed[last] = nil
+				ss.Fixed = ss.Fixed[:last]
 				break
 			}
 		}
-	} else {
-		for cidx, f := range ss.Entities {
-			if f.ID == body.ID {
-				ss.Entities[cidx] = nil
-				break
-			}
+		ss.fixedTree.RemoveFrom(body, body.indexed)
+		return
+	}
+	if idx := body.slot; idx < len(ss.Entities) && ss.Entities[idx] == body {
+		last := len(ss.Entities) - 1
+		ss.Entities[idx] = ss.Entities[last]
+		ss.Entities[idx].slot = idx
+		ss.Entities[last] = nil
+		ss.Entities = ss.Entities[:last]
+	}
+	ss.tree.RemoveFrom(body, body.indexed)
+}
+
+// ownFixed copies the fixed bodies list and tree if they are shared with a clone.
+// The bodies themselves are never changed so they stay shared.
+func (ss *SimulatedSpace) ownFixed() {
+	if !ss.fixedShared {
+		return
+	}
+	ss.Fixed = append([]*RigidBody(nil), ss.Fixed...)
+	tree, _ := ss.fixedTree.CloneFunc(func(bb quadtree.BoundingBoxer) quadtree.BoundingBoxer { return bb })
+	ss.fixedTree = *tree
+	ss.fixedShared = false
+}
+
+// UpdateEntity re-indexes a body after its position or size was changed outside of Tick.
+// Tick keeps bodies it moves indexed on its own. Only for bodies that aren't fixed.
+func (ss *SimulatedSpace) UpdateEntity(body *RigidBody) {
+	bounds := body.Bounds()
+	if bounds == body.indexed {
+		return
+	}
+	if ss.tree.Move(body, body.indexed) {
+		body.indexed = bounds
+	}
+}
+
+// Clone creates a copy of the space, including the layout of the tree, so it can be used
+// as a snapshot. Bodies that move are copied, fixed bodies never change so they are shared
+// until either space adds or removes one. The returned map links each copied body in this
+// space to its copy so callers can fix up their own references to bodies.
+func (ss *SimulatedSpace) Clone() (*SimulatedSpace, map[*RigidBody]*RigidBody) {
+	bodies := make(map[*RigidBody]*RigidBody, len(ss.Entities))
+	cloneBody := func(rb *RigidBody) *RigidBody {
+		if rb == nil {
+			return nil
+		}
+		if nrb, ok := bodies[rb]; ok {
+			return nrb
+		}
+		nrb := *rb
+		bodies[rb] = &nrb
+		return &nrb
+	}
+
+	tree, _ := ss.tree.CloneFunc(func(bb quadtree.BoundingBoxer) quadtree.BoundingBoxer {
+		if rb, ok := bb.(*RigidBody); ok {
+			return cloneBody(rb)
 		}
+		return bb.Clone()
+	})
+	ss.fixedShared = true
+	ns := &SimulatedSpace{
+		tree:        *tree,
+		fixedTree:   ss.fixedTree,
+		Entities:    make([]*RigidBody, len(ss.Entities)),
+		Fixed:       ss.Fixed,
+		fixedShared: true,
+		lastUpdate:  ss.lastUpdate,
+		TickID:      ss.TickID,
 	}
-	ss.tree.Remove(body)
+	for idx, rb := range ss.Entities {
+		ns.Entities[idx] = cloneBody(rb)
+	}
+	return ns, bodies
+}
+
+// Restore replaces the contents of this space with a copy of the snapshot. The snapshot is
+// left untouched so it can be restored again. The returned map links each body in the
+// snapshot to the body now in this space.
+func (ss *SimulatedSpace) Restore(snap *SimulatedSpace) map[*RigidBody]*RigidBody {
+	ns, bodies := snap.Clone()
+	*ss = *ns
+	return bodies
 }
 
+// Tick moves every body forward one step, then separates any bodies that overlap.
+// If sendUpdate is set, each pair of bodies that collided is returned.
 func (ss *SimulatedSpace) Tick(sendUpdate bool) []PhysicsEntityUpdate {
 	ss.TickID++
 	ss.lastUpdate = time.Now()
-	var changeList []PhysicsEntityUpdate
-	if sendUpdate {
-		changeList = make([]PhysicsEntityUpdate, len(ss.Entities)*2)
-	}
-	cidx := 0
-	changed := false
 	for _, rigid := range ss.Entities {
 		if rigid == nil {
 			continue
 		}
-		changed = false
-		rigid.Velocity = AddVect2(rigid.Velocity, MultVect2(rigid.Force, rigid.InvMass/SimUpdatesPerSecond))
-		rigid.AngularVelocity += (rigid.Torque * float64(rigid.InvInertia)) / SimUpdatesPerSecond
+		rigid.Velocity = AddVect2(rigid.Velocity, ScaleVect2(rigid.Force, rigid.InvMass/SimUpdatesPerSecond))
+		rigid.AngularVelocity += (rigid.Torque * rigid.InvInertia) / SimUpdatesPerSecond
 
 		if rigid.Velocity.X != 0.0 {
 			rigid.Position.X += rigid.Velocity.X / SimUpdatesPerSecond
-			changed = true
 		}
 		if rigid.Velocity.Y != 0.0 {
 			rigid.Position.Y += rigid.Velocity.Y / SimUpdatesPerSecond
-			changed = true
 		}
 		if rigid.AngularVelocity != 0.0 {
 			rigid.Angle += rigid.AngularVelocity / SimUpdatesPerSecond
@@ -107,43 +196,64 @@ func (ss *SimulatedSpace) Tick(sendUpdate bool) []PhysicsEntityUpdate {
 			for rigid.Angle < -FullCircle {
 				rigid.Angle += FullCircle
 			}
-			changed = true
 		}
+		ss.UpdateEntity(rigid)
+	}
 
-		if !changed {
+	var changeList []PhysicsEntityUpdate
+	resolved := map[[2]uint32]bool{}
+	for _, rigid := range ss.Entities {
+		if rigid == nil {
 			continue
 		}
-		// if changed && sendUpdate {
-		// 	changeList[cidx].UpdateType = UpdatePosition
-		// 	changeList[cidx].Body = *rigid
-		// 	cidx++
-		// 	if cidx == len(changeList) {
-		// 		newlist := make([]PhysicsEntityUpdate, len(changeList)*2)
-		// 		copy(newlist, changeList)
-		// 		changeList = newlist
-		// 	}
-		// }
-
-		collisions := ss.tree.Query(rigid.Bounds())
-
-		for _, collbox := range collisions {
-			other := collbox.(*RigidBody)
+		for _, other := range ss.collisionCandidates(rigid) {
 			if other == nil || other.ID == rigid.ID {
 				continue
 			}
+			pair := [2]uint32{rigid.ID, other.ID}
+			if other.ID < rigid.ID {
+				pair = [2]uint32{other.ID, rigid.ID}
+			}
+			if resolved[pair] {
+				continue
+			}
+			contact, ok := FindContact(rigid, other)
+			if !ok {
+				continue
+			}
+			resolved[pair] = true
+			if !rigid.Sensor && !other.Sensor {
+				contact.Resolve()
+				ss.UpdateEntity(rigid)
+				ss.UpdateEntity(other)
+			}
+			if sendUpdate {
+				changeList = append(changeList, PhysicsEntityUpdate{
+					UpdateType: UpdateCollision,
+					Body:       rigid,
+					Other:      other,
+				})
+			}
+		}
+	}
 
-			changeList[cidx].UpdateType = UpdateCollision
-			changeList[cidx].Body = rigid
-			changeList[cidx].Other = other
+	return changeList
+}
 
-			cidx++
-			if cidx == len(changeList) {
-				newlist := make([]PhysicsEntityUpdate, len(changeList)*2)
-				copy(newlist, changeList)
-				changeList = newlist
+// collisionCandidates is the broad phase, returns every body that might overlap rigid.
+func (ss *SimulatedSpace) collisionCandidates(rigid *RigidBody) []*RigidBody {
+	return ss.Query(rigid.Bounds())
+}
+
+// Query returns every body in the space whose bounds intersect the box.
+func (ss *SimulatedSpace) Query(bbox quadtree.BoundingBox) []*RigidBody {
+	bodies := []*RigidBody{}
+	for _, tree := range []*quadtree.QuadTree{&ss.tree, &ss.fixedTree} {
+		for _, collbox := range tree.Query(bbox) {
+			if rb, ok := collbox.(*RigidBody); ok {
+				bodies = append(bodies, rb)
 			}
 		}
 	}
-
-	return changeList[:cidx]
+	return bodies
 }
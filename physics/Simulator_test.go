@@ -3,12 +3,15 @@ package physics
 import (
 	"fmt"
 	"math/rand"
+	"reflect"
 	"testing"
+
+	"github.com/lologarithm/survival/physics/quadtree"
 )
 
 func TestTick(t *testing.T) {
 	// 1. Create simple scene
-	ss := &SimulatedSpace{Entities: make([]*RigidBody, 2)}
+	ss := &SimulatedSpace{}
 	o1 := &RigidBody{}
 	o1.Velocity = Vect2{1, 1}
 	o1.Position = Vect2{0, 0}
@@ -26,7 +29,7 @@ func TestTick(t *testing.T) {
 }
 
 func BenchmarkTick(b *testing.B) {
-	ss := &SimulatedSpace{Entities: make([]*RigidBody, 10000)}
+	ss := &SimulatedSpace{Entities: make([]*RigidBody, 0, 10000)}
 	for i := uint32(0); i < 10000; i++ {
 		o1 := &RigidBody{}
 		o1.ID = i
@@ -43,3 +46,225 @@ func BenchmarkTick(b *testing.B) {
 		ss.Tick(true)
 	}
 }
+
+func TestSnapshotRestore(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	ss := NewSimulatedSpace()
+	for i := uint32(0); i < 100; i++ {
+		pos := Vect2{int32(rng.Intn(500)), int32(rng.Intn(500))}
+		vel := Vect2{int32(rng.Intn(200) - 100), int32(rng.Intn(200) - 100)}
+		ss.AddEntity(NewRigidBody(i, 20, 20, pos, vel, 0, 100), false)
+	}
+
+	type result struct {
+		positions  []Vect2
+		collisions [][2]uint32
+	}
+	run := func(ss *SimulatedSpace) result {
+		r := result{}
+		for i := 0; i < 30; i++ {
+			for _, u := range ss.Tick(true) {
+				if u.Body != nil {
+					r.collisions = append(r.collisions, [2]uint32{u.Body.ID, u.Other.ID})
+				}
+			}
+		}
+		for _, rb := range ss.Entities[:100] {
+			r.positions = append(r.positions, rb.Position)
+		}
+		return r
+	}
+
+	snap, bodies := ss.Clone()
+	for orig, copied := range bodies {
+		if orig == copied || *orig != *copied {
+			t.Fatalf("Body %d was not copied correctly.", orig.ID)
+		}
+	}
+	want := run(ss)
+	if len(want.collisions) == 0 {
+		t.Fatalf("Expected the test scene to have collisions.")
+	}
+	for i := 0; i < 2; i++ {
+		bodies = ss.Restore(snap)
+		if ss.TickID != snap.TickID {
+			t.Fatalf("Restored tick %d, expected %d", ss.TickID, snap.TickID)
+		}
+		if first := ss.Entities[0]; first == snap.Entities[0] || bodies[snap.Entities[0]] != first {
+			t.Fatalf("Restored space should have its own copy of each snapshot body.")
+		}
+		got := run(ss)
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("Restored space did not simulate the same as the original.")
+		}
+	}
+}
+
+// TestTreeMatchesBruteForce moves bodies around randomly and checks that the tree always
+// finds the same bodies as scanning every body in the space.
+func TestTreeMatchesBruteForce(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	ss := NewSimulatedSpace()
+	bounds := ss.tree.Bounds()
+	randPos := func() Vect2 {
+		// Some bodies start (and wander) outside of the starting tree bounds.
+		return Vect2{int32(rng.Intn(24000) - 12000), int32(rng.Intn(24000) - 12000)}
+	}
+	nextID := uint32(1)
+	addBody := func(fixed bool) {
+		vel := Vect2{}
+		if !fixed {
+			vel = Vect2{int32(rng.Intn(400) - 200), int32(rng.Intn(400) - 200)}
+		}
+		rb := NewRigidBody(nextID, int32(rng.Intn(300)+1), int32(rng.Intn(300)+1), randPos(), vel, 0, int32(rng.Intn(200)))
+		rb.AngularVelocity = float64(rng.Intn(3))
+		nextID++
+		ss.AddEntity(rb, fixed)
+	}
+	for i := 0; i < 150; i++ {
+		addBody(false)
+	}
+	for i := 0; i < 50; i++ {
+		addBody(true)
+	}
+
+	check := func(tick int) {
+		for i := 0; i < 10; i++ {
+			q := NewRigidBody(0, int32(rng.Intn(4000)), int32(rng.Intn(4000)), randPos(), Vect2{}, 0, 0).Bounds()
+			want := map[uint32]bool{}
+			for _, list := range [][]*RigidBody{ss.Entities, ss.Fixed} {
+				for _, rb := range list {
+					if rb != nil && q.Intersects(rb.Bounds()) {
+						want[rb.ID] = true
+					}
+				}
+			}
+			got := map[uint32]bool{}
+			for _, rb := range ss.Query(q) {
+				got[rb.ID] = true
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("Tick %d: tree found %d bodies, brute force found %d (tree bounds %v)", tick, len(got), len(want), bounds)
+			}
+		}
+	}
+
+	for tick := 0; tick < 2000; tick++ {
+		switch rng.Intn(20) {
+		case 0:
+			// Remove a random body.
+			if len(ss.Entities) > 0 {
+				ss.RemoveEntity(ss.Entities[rng.Intn(len(ss.Entities))], false)
+			}
+		case 1:
+			addBody(false)
+		case 2:
+			// Teleport a body outside of Tick.
+			if len(ss.Entities) > 0 {
+				rb := ss.Entities[rng.Intn(len(ss.Entities))]
+				rb.Position = randPos()
+				ss.UpdateEntity(rb)
+			}
+		}
+		ss.Tick(false)
+		check(tick)
+	}
+	if n := len(ss.tree.Contents()) + len(ss.fixedTree.Contents()); n != countBodies(ss) {
+		t.Fatalf("Tree has %d bodies, space has %d", n, countBodies(ss))
+	}
+}
+
+// TestCloneSharesFixed checks that clones share fixed bodies but still don't see
+// fixed bodies added or removed after they were made.
+func TestCloneSharesFixed(t *testing.T) {
+	ss := NewSimulatedSpace()
+	wall := NewRigidBody(1, 100, 100, Vect2{}, Vect2{}, 0, 0)
+	ss.AddEntity(wall, true)
+	ss.AddEntity(NewRigidBody(2, 10, 10, Vect2{X: 200}, Vect2{}, 0, 10), false)
+
+	snap, bodies := ss.Clone()
+	if _, ok := bodies[wall]; ok || snap.Fixed[0] != wall {
+		t.Fatalf("Fixed body should be shared with the clone, not copied.")
+	}
+	if snap.Entities[0] == ss.Entities[0] {
+		t.Fatalf("Moving body should be copied.")
+	}
+
+	ss.RemoveEntity(wall, true)
+	rock := NewRigidBody(3, 10, 10, Vect2{X: 500}, Vect2{}, 0, 0)
+	ss.AddEntity(rock, true)
+	everywhere := quadtree.NewBoundingBox(-1000, 1000, -1000, 1000)
+	ids := func(space *SimulatedSpace) map[uint32]bool {
+		found := map[uint32]bool{}
+		for _, rb := range space.Query(everywhere) {
+			found[rb.ID] = true
+		}
+		return found
+	}
+	if got := ids(snap); !reflect.DeepEqual(got, map[uint32]bool{1: true, 2: true}) || countBodies(snap) != 2 {
+		t.Fatalf("Clone changed when the original's fixed bodies did: %v", got)
+	}
+	if got := ids(ss); !reflect.DeepEqual(got, map[uint32]bool{2: true, 3: true}) || countBodies(ss) != 2 {
+		t.Fatalf("Original has the wrong bodies after changing fixed bodies: %v", got)
+	}
+
+	// Restoring gives back the shared fixed bodies again.
+	ss.Restore(snap)
+	if got := ids(ss); !reflect.DeepEqual(got, map[uint32]bool{1: true, 2: true}) {
+		t.Fatalf("Restore did not bring back the fixed bodies: %v", got)
+	}
+}
+
+func countBodies(ss *SimulatedSpace) int {
+	n := 0
+	for _, list := range [][]*RigidBody{ss.Entities, ss.Fixed} {
+		for _, rb := range list {
+			if rb != nil {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+func TestAddEntityGrows(t *testing.T) {
+	ss := &SimulatedSpace{}
+	for i := uint32(1); i <= 5; i++ {
+		ss.AddEntity(&RigidBody{ID: i}, false)
+		ss.AddEntity(&RigidBody{ID: i + 10}, true)
+	}
+	if countBodies(ss) != 10 {
+		t.Fatalf("Expected 10 bodies in the space, got %d", countBodies(ss))
+	}
+}
+
+// TestRemoveEntityKeepsLiveBodies checks that removed bodies give up their place in the
+// lists so a space with bodies coming and going doesn't keep growing.
+func TestRemoveEntityKeepsLiveBodies(t *testing.T) {
+	ss := NewSimulatedSpace()
+	var live []*RigidBody
+	for i := uint32(1); i <= 5; i++ {
+		rb := &RigidBody{ID: i}
+		ss.AddEntity(rb, false)
+		live = append(live, rb)
+	}
+	for i := uint32(100); i < 10000; i++ {
+		moving, wall := &RigidBody{ID: i}, &RigidBody{ID: i + 100000}
+		ss.AddEntity(moving, false)
+		ss.AddEntity(wall, true)
+		ss.RemoveEntity(moving, false)
+		ss.RemoveEntity(wall, true)
+	}
+	// Take one out of the middle, the rest must still be found and removable.
+	ss.RemoveEntity(live[1], false)
+	live = append(live[:1], live[2:]...)
+	if len(ss.Entities) != len(live) || len(ss.Fixed) != 0 {
+		t.Fatalf("Expected %d moving and 0 fixed bodies, lists hold %d and %d", len(live), len(ss.Entities), len(ss.Fixed))
+	}
+	for _, rb := range live {
+		ss.RemoveEntity(rb, false)
+	}
+	if len(ss.Entities) != 0 || len(ss.tree.Contents()) != 0 {
+		t.Fatalf("Space still holds %d bodies (%d in the tree) after removing all of them", len(ss.Entities), len(ss.tree.Contents()))
+	}
+}
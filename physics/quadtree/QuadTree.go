@@ -6,6 +6,8 @@ Modified 2015 Ben Echols -- changed from float64 to int32 space
 // Package quadtree is a simple 2D implementation of the Quad-Tree data structure.
 package quadtree
 
+import "math"
+
 // MaxEntriesPerTile is the number of entries until a quad is split
 const MaxEntriesPerTile = 16
 
@@ -49,32 +51,101 @@ type qtile struct {
 }
 
 // Add a value to the quad-tree by trickle down from the root node.
+// The tree grows to fit values outside of its bounds.
 func (qb *QuadTree) Add(v BoundingBoxer) {
+	for !qb.root.Contains(v.Bounds()) && qb.grow(v.Bounds()) {
+	}
 	qb.root.add(v)
 }
 
+// grow doubles the size of the tree toward bbox, the old root becomes a quarter of the new one.
+// Returns false if the tree can't grow without overflowing, values still outside are kept at the root.
+func (qb *QuadTree) grow(bbox BoundingBox) bool {
+	old := qb.root
+	w, h := int64(old.SizeX()), int64(old.SizeY())
+	minX, maxX, mx := int64(old.MinX), int64(old.MaxX)+w, old.MaxX
+	if bbox.MinX < old.MinX {
+		minX, maxX, mx = int64(old.MinX)-w, int64(old.MaxX), old.MinX
+	}
+	minY, maxY, my := int64(old.MinY), int64(old.MaxY)+h, old.MaxY
+	if bbox.MinY < old.MinY {
+		minY, maxY, my = int64(old.MinY)-h, int64(old.MaxY), old.MinY
+	}
+	if w == 0 || h == 0 || minX < math.MinInt32 || minY < math.MinInt32 || maxX > math.MaxInt32 || maxY > math.MaxInt32 {
+		return false
+	}
+
+	root := qtile{BoundingBox: NewBoundingBox(int32(minX), int32(maxX), int32(minY), int32(maxY))}
+	root.childs[topRightTile] = &qtile{BoundingBox: NewBoundingBox(mx, root.MaxX, my, root.MaxY), level: 1}
+	root.childs[topLeftTile] = &qtile{BoundingBox: NewBoundingBox(root.MinX, mx, my, root.MaxY), level: 1}
+	root.childs[bottomLeftTile] = &qtile{BoundingBox: NewBoundingBox(root.MinX, mx, root.MinY, my), level: 1}
+	root.childs[bottomRightTile] = &qtile{BoundingBox: NewBoundingBox(mx, root.MaxX, root.MinY, my), level: 1}
+
+	// Values that were outside of the old root stay at the root where they are always searched.
+	inside := old.contents[:0]
+	for _, v := range old.contents {
+		if old.Contains(v.Bounds()) {
+			inside = append(inside, v)
+		} else {
+			root.contents = append(root.contents, v)
+		}
+	}
+	old.contents = inside
+	old.lower()
+	root.childs[root.findChildIndex(old.BoundingBox)] = &old
+	qb.root = root
+	return true
+}
+
 // Remove a value from the quad-tree by trickle down from the root node.
 func (qb *QuadTree) Remove(v BoundingBoxer) bool {
 	return qb.root.remove(v.Bounds(), v.BoxID())
 }
 
-// Move a box to new box
-func (qb *QuadTree) Move(v BoundingBoxer, oldloc BoundingBox) int {
-	return qb.root.move(v.BoxID(), v, oldloc, v.Bounds())
+// RemoveFrom removes a value that was added when its bounds were oldloc.
+// Use this instead of Remove when the value may have changed since it was added.
+func (qb *QuadTree) RemoveFrom(v BoundingBoxer, oldloc BoundingBox) bool {
+	return qb.root.remove(oldloc, v.BoxID())
+}
+
+// Move re-indexes a value that was added when its bounds were oldloc to its current bounds.
+// Returns false, and adds nothing, if the value was not found at oldloc.
+func (qb *QuadTree) Move(v BoundingBoxer, oldloc BoundingBox) bool {
+	if !qb.root.remove(oldloc, v.BoxID()) {
+		return false
+	}
+	qb.Add(v)
+	return true
 }
 
 // Clone will create a full copy of this quadtree.
 func (qb *QuadTree) Clone() (*QuadTree, []BoundingBoxer) {
-	root, children := qb.root.clone()
+	return qb.CloneFunc(BoundingBoxer.Clone)
+}
+
+// CloneFunc copies the tree with the same layout, using clone to copy each value.
+// Useful when the caller needs to control which copy of a value ends up in the new tree.
+func (qb *QuadTree) CloneFunc(clone func(BoundingBoxer) BoundingBoxer) (*QuadTree, []BoundingBoxer) {
+	root, children := qb.root.clone(clone)
 	return &QuadTree{root: root}, children
 }
 
+// Bounds returns the area covered by the tree.
+func (qb *QuadTree) Bounds() BoundingBox {
+	return qb.root.BoundingBox
+}
+
+// Contents returns every object in the tree.
+func (qb *QuadTree) Contents() []BoundingBoxer {
+	return qb.root.all()
+}
+
 // Query will return all objects which intersect the query box
 func (qb *QuadTree) Query(bbox BoundingBox) []BoundingBoxer {
 	return qb.root.query(bbox)
 }
 
-func (tile qtile) clone() (qtile, []BoundingBoxer) {
+func (tile qtile) clone(clone func(BoundingBoxer) BoundingBoxer) (qtile, []BoundingBoxer) {
 	ntile := qtile{
 		BoundingBox: tile.BoundingBox,
 		level:       tile.level,
@@ -82,12 +153,12 @@ func (tile qtile) clone() (qtile, []BoundingBoxer) {
 		childs:      [4]*qtile{},
 	}
 	for idx, bb := range tile.contents {
-		ntile.contents[idx] = bb.Clone()
+		ntile.contents[idx] = clone(bb)
 	}
 	contents := ntile.contents
 	for idx, c := range tile.childs {
 		if c != nil {
-			cClone, ccont := c.clone()
+			cClone, ccont := c.clone(clone)
 			ntile.childs[idx] = &cClone
 			contents = append(contents, ccont...)
 		}
@@ -95,6 +166,16 @@ func (tile qtile) clone() (qtile, []BoundingBoxer) {
 	return ntile, contents
 }
 
+// lower moves the tile and all of its children one level down.
+func (tile *qtile) lower() {
+	tile.level++
+	if tile.childs[topRightTile] != nil {
+		for _, child := range tile.childs {
+			child.lower()
+		}
+	}
+}
+
 func (tile *qtile) add(v BoundingBoxer) {
 	// look for sub-tile directly below this tile to accomodate value.
 	if i := tile.findChildIndex(v.Bounds()); i < 0 {
@@ -155,53 +236,10 @@ func (tile *qtile) split() {
 
 }
 
-// 0 == not found, 1 == found, needs home, 2 == completed
-func (tile *qtile) move(id uint32, bb BoundingBoxer, old, newb BoundingBox) int {
-	// end recursion if this tile does not intersect the query range
-	if !tile.Intersects(old) {
-		return 0
-	}
-
-	for vidx, v := range tile.contents {
-		if id == v.BoxID() {
-			if tile.Intersects(newb) {
-				return 2
-			}
-			// REMOVE IT
-			tile.contents = append(tile.contents[:vidx], tile.contents[vidx+1:]...)
-			return 1
-		}
-	}
-
-	// recurse into childs (if any)
-	if tile.childs[topRightTile] != nil {
-		for _, child := range tile.childs {
-			cr := child.move(id, bb, old, newb)
-			// Not found, continue searching
-			if cr == 0 {
-				continue
-			}
-			// Found&Handled, return now!
-			if cr == 2 {
-				return 2
-			}
-			// Found but not inserted
-			if !tile.Intersects(newb) {
-				// New loc doesn't fit in here, pass it up!
-				return 1
-			}
-			// Add new loc here!
-			tile.add(bb)
-			return 2
-		}
-	}
-
-	return 0
-}
-
 func (tile *qtile) remove(qbox BoundingBox, id uint32) bool {
 	// end recursion if this tile does not intersect the query range
-	if !tile.Intersects(qbox) {
+	// the root also holds anything outside of the tree bounds so it is always searched.
+	if tile.level > 0 && !tile.Intersects(qbox) {
 		return false
 	}
 
@@ -226,10 +264,21 @@ func (tile *qtile) remove(qbox BoundingBox, id uint32) bool {
 	return false
 }
 
+func (tile *qtile) all() []BoundingBoxer {
+	ret := append([]BoundingBoxer{}, tile.contents...)
+	if tile.childs[topRightTile] != nil {
+		for _, child := range tile.childs {
+			ret = append(ret, child.all()...)
+		}
+	}
+	return ret
+}
+
 func (tile *qtile) query(qbox BoundingBox) []BoundingBoxer {
 	ret := []BoundingBoxer{}
 	// end recursion if this tile does not intersect the query range
-	if !tile.Intersects(qbox) {
+	// the root also holds anything outside of the tree bounds so it is always searched.
+	if tile.level > 0 && !tile.Intersects(qbox) {
 		return ret
 	}
 
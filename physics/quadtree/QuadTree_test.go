@@ -130,6 +130,34 @@ func TestQuadRemove(t *testing.T) {
 	}
 }
 
+func TestQuadMove(t *testing.T) {
+	qt := NewQuadTree(world)
+	for i := 0; i < 100; i++ {
+		qt.Add(mockBox{BoundingBox: NewBoundingBox(int32(i*100), int32(i*100+10), 0, 10), ID: uint32(i + 1)})
+	}
+	old := NewBoundingBox(0, 10, 0, 10)
+	moved := mockBox{BoundingBox: NewBoundingBox(-500000, -499990, 500000, 500010), ID: 1}
+	if !qt.Move(moved, old) {
+		t.Fatalf("Box was not found at its old location.")
+	}
+	if len(qt.Query(old)) != 0 {
+		t.Fatalf("Box is still at its old location.")
+	}
+	if r := qt.Query(moved.BoundingBox); len(r) != 1 || r[0].BoxID() != 1 {
+		t.Fatalf("Box was not found at its new location: %v", r)
+	}
+
+	// Anything outside of the tree is still found.
+	outside := mockBox{BoundingBox: NewBoundingBox(world.MaxX+10, world.MaxX+20, 0, 10), ID: 1000}
+	qt.Add(outside)
+	if r := qt.Query(outside.BoundingBox); len(r) != 1 {
+		t.Fatalf("Box outside of the tree was not found.")
+	}
+	if !qt.Remove(outside) {
+		t.Fatalf("Box outside of the tree was not removed.")
+	}
+}
+
 // Compary correctness of quad-tree results vs simple look-up on set of random points
 func TestQuadTreePoints(t *testing.T) {
 	var points []BoundingBox = randomBoundingBoxes(100*1000, world, 0)
@@ -252,3 +280,49 @@ func BenchmarkPointsLinear(b *testing.B) {
 		queryLinear(points10M, q)
 	}
 }
+
+// TestQuadGrow checks that the tree grows to fit values outside of it instead of piling them into the root.
+func TestQuadGrow(t *testing.T) {
+	start := NewBoundingBox(-100, 100, -100, 100)
+	qt := NewQuadTree(start)
+	boxes := []mockBox{}
+	for i := 0; i < 200; i++ {
+		x, y := int32(rand.Intn(2000000)-1000000), int32(rand.Intn(2000000)-1000000)
+		boxes = append(boxes, mockBox{BoundingBox: NewBoundingBox(x, x+10, y, y+10), ID: uint32(i + 1)})
+		qt.Add(boxes[i])
+	}
+	for _, b := range boxes {
+		if !qt.Bounds().Contains(b.BoundingBox) {
+			t.Fatalf("Tree bounds %v don't contain %v", qt.Bounds(), b.BoundingBox)
+		}
+		if r := qt.Query(b.BoundingBox); len(r) != 1 || r[0].BoxID() != b.ID {
+			t.Fatalf("Box %d was not found after the tree grew: %v", b.ID, r)
+		}
+	}
+	if n := len(qt.root.contents); n > MaxEntriesPerTile {
+		t.Fatalf("Expected boxes to be spread through the tree, root has %d.", n)
+	}
+	if r := qt.Query(start); len(r) != len(queryLinear(boundingBoxes(boxes), start)) {
+		t.Fatalf("Query of the starting area found %d boxes.", len(r))
+	}
+	for _, b := range boxes {
+		if !qt.Remove(b) {
+			t.Fatalf("Box %d was not removed.", b.ID)
+		}
+	}
+
+	// Can't grow past the edge of int32 space, those are kept at the root.
+	edge := mockBox{BoundingBox: NewBoundingBox(math.MaxInt32-10, math.MaxInt32, 0, 10), ID: 1000}
+	qt.Add(edge)
+	if r := qt.Query(edge.BoundingBox); len(r) != 1 {
+		t.Fatalf("Box at the edge of space was not found.")
+	}
+}
+
+func boundingBoxes(boxes []mockBox) []BoundingBox {
+	bbs := make([]BoundingBox, len(boxes))
+	for i, b := range boxes {
+		bbs[i] = b.BoundingBox
+	}
+	return bbs
+}
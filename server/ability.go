@@ -0,0 +1,152 @@
+package server
+
+import (
+	"github.com/lologarithm/survival/physics"
+	"github.com/lologarithm/survival/server/messages"
+)
+
+// Ability IDs
+const (
+	PunchAbility uint32 = iota + 1
+	ThrowRockAbility
+	FireboltAbility
+	BiteAbility
+)
+
+// AbilityResult states
+const (
+	AbilityHit        byte = iota + 1 // Target was hit, Damage is how much.
+	AbilityFired                      // A projectile was fired at the target.
+	AbilityOnCooldown                 // Used again before the cooldown was over.
+	AbilityNoResource                 // Not enough stamina or concentration.
+	AbilityOutOfRange                 // Target was too far away at the tick it was used.
+	AbilityInvalid                    // Unknown ability or target.
+)
+
+// Ability describes what an ability costs and does.
+type Ability struct {
+	ID                uint32
+	Name              string
+	StaminaCost       int16
+	ConcentrationCost int16
+	Cooldown          uint32 // Ticks before it can be used again.
+	Range             int32  // Furthest the target can be, for projectiles how far the projectile flies.
+
+	Damage        int32   // Base damage
+	PhysicalScale float64 // Extra damage per point of PhysicalStrength
+	MagicScale    float64 // Extra damage per point of MagicStrength
+
+	ProjectileSpeed int32 // If set the ability fires a projectile at the target instead of hitting it directly.
+}
+
+// damage is how much the ability does when used with the given stats.
+func (a *Ability) damage(stats Stats) int32 {
+	return a.Damage + int32(a.PhysicalScale*float64(stats.PhysicalStrength)+a.MagicScale*float64(stats.MagicStrength))
+}
+
+// Abilities is every ability a character can use, by ID.
+var Abilities = map[uint32]*Ability{
+	PunchAbility: {
+		ID:            PunchAbility,
+		Name:          "Punch",
+		StaminaCost:   5,
+		Cooldown:      15,
+		Range:         80,
+		Damage:        5,
+		PhysicalScale: 1,
+	},
+	ThrowRockAbility: {
+		ID:              ThrowRockAbility,
+		Name:            "Throw Rock",
+		StaminaCost:     10,
+		Cooldown:        30,
+		Range:           2000,
+		Damage:          8,
+		PhysicalScale:   0.5,
+		ProjectileSpeed: 1500,
+	},
+	FireboltAbility: {
+		ID:                FireboltAbility,
+		Name:              "Firebolt",
+		ConcentrationCost: 15,
+		Cooldown:          45,
+		Range:             3000,
+		Damage:            12,
+		MagicScale:        1.5,
+		ProjectileSpeed:   2500,
+	},
+	BiteAbility: {
+		ID:            BiteAbility,
+		Name:          "Bite",
+		Cooldown:      30,
+		Range:         70,
+		Damage:        6,
+		PhysicalScale: 0.5,
+	},
+}
+
+// UseAbility validates an ability use against the world at the tick the player used it,
+// then applies it and tells everyone who can see the caster or target how it went.
+// Failures are only sent back to the caster.
+func (g *GameSession) UseAbility(c *Client, tmsg *messages.UseAbility) {
+	user := g.Clients[c.ID]
+	if user == nil {
+		return
+	}
+	char := user.Accounts[0].Character
+	result := &messages.AbilityResult{
+		EntityID:  char.ID,
+		AbilityID: tmsg.AbilityID,
+		Target:    &messages.Entity{ID: tmsg.Target},
+	}
+	// The client's tick only picks which world to check hits against, cooldowns run on the
+	// server's clock so a client can't reuse an ability by claiming an older tick.
+	now := g.World.Space.TickID
+	tick := g.clampTick(tmsg.TickID)
+	world := g.worldAt(tick)
+	ability := Abilities[tmsg.AbilityID]
+	caster, target := world.Entities[char.ID], world.Entities[tmsg.Target]
+
+	switch {
+	case ability == nil || caster == nil || target == nil || target == caster:
+		result.State = AbilityInvalid
+	case now < user.cooldowns[ability.ID]:
+		result.State = AbilityOnCooldown
+	case char.CurrentStats.Stamina < ability.StaminaCost || char.CurrentStats.Concentration < ability.ConcentrationCost:
+		result.State = AbilityNoResource
+	case ability.ProjectileSpeed == 0 && physics.SubVect2(target.Body.Position, caster.Body.Position).Magnitude() > float64(ability.Range):
+		result.State = AbilityOutOfRange
+	}
+	if result.State != 0 {
+		g.ToNetwork <- NewReliableMsg(c, messages.AbilityResultMsgType, result)
+		return
+	}
+
+	if user.cooldowns == nil {
+		user.cooldowns = map[uint32]uint32{}
+	}
+	user.cooldowns[ability.ID] = now + ability.Cooldown
+	char.CurrentStats.Stamina -= ability.StaminaCost
+	char.CurrentStats.Concentration -= ability.ConcentrationCost
+	damage := ability.damage(char.CurrentStats)
+	result.Target = g.entityMsg(target)
+	if ability.ProjectileSpeed > 0 {
+		dir := physics.SubVect2(target.Body.Position, caster.Body.Position)
+		g.SpawnProjectile(tick, char.ID, dir, ability.ProjectileSpeed, damage, ability.Range)
+		result.State = AbilityFired
+	} else {
+		g.damageCharacter(target.ID, char.ID, damage)
+		result.State = AbilityHit
+		result.Damage = damage
+	}
+	g.sendAbilityResult(result, user)
+}
+
+// sendAbilityResult tells the caster's user, if there is one, and everyone who can see the caster or target how an ability went.
+func (g *GameSession) sendAbilityResult(result *messages.AbilityResult, caster *User) {
+	for _, u := range g.Clients {
+		if u == caster || u.visible[result.EntityID] || u.visible[result.Target.ID] {
+			g.ToNetwork <- NewReliableMsg(u.Client, messages.AbilityResultMsgType, result)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/lologarithm/survival/physics"
+	"github.com/lologarithm/survival/server/messages"
+)
+
+func TestUseAbility(t *testing.T) {
+	out := make(chan OutgoingMessage, 1000)
+	a, b := &Client{ID: 1}, &Client{ID: 2}
+	g, chars := newTestGame(out, a, b)
+	attacker, victim := chars[0], chars[1]
+	attacker.CurrentStats = Stats{Stamina: 12, PhysicalStrength: 4}
+	victim.CurrentStats = Stats{HP: 100}
+	target := g.World.Entities[victim.ID].Body
+	target.Position = physics.Vect2{X: 5050, Y: 5000}
+	g.World.Space.UpdateEntity(target)
+	g.simulate()
+	g.UpdateInterest()
+	drainFor(out, a)
+	drainFor(out, b)
+
+	use := func(ability uint32) {
+		g.UseAbility(a, &messages.UseAbility{EntityID: attacker.ID, AbilityID: ability, TickID: g.World.Space.TickID, Target: victim.ID})
+	}
+
+	use(PunchAbility)
+	punch := Abilities[PunchAbility]
+	want := punch.Damage + 4
+	if victim.CurrentStats.HP != 100-want {
+		t.Fatalf("Expected punch to do %d damage, HP is %d", want, victim.CurrentStats.HP)
+	}
+	if attacker.CurrentStats.Stamina != 12-punch.StaminaCost {
+		t.Fatalf("Punch did not cost stamina.")
+	}
+	res := drainType(out, b, messages.AbilityResultMsgType)
+	if len(res) != 1 {
+		t.Fatalf("Victim should be told about the hit: %v", res)
+	}
+	if r := res[0].(*messages.AbilityResult); r.State != AbilityHit || r.Damage != want || r.EntityID != attacker.ID || r.Target.ID != victim.ID {
+		t.Fatalf("Victim was told the wrong thing about the hit: %v", r)
+	}
+	drainFor(out, a)
+
+	expectFail := func(ability uint32, state byte) {
+		hp := victim.CurrentStats.HP
+		use(ability)
+		res := drainType(out, a, messages.AbilityResultMsgType)
+		if len(res) != 1 || res[0].(*messages.AbilityResult).State != state {
+			t.Fatalf("Expected ability %d to fail with %d, got %v", ability, state, res)
+		}
+		if len(drainType(out, b, messages.AbilityResultMsgType)) != 0 || victim.CurrentStats.HP != hp {
+			t.Fatalf("Failed ability should not affect the target.")
+		}
+	}
+	expectFail(PunchAbility, AbilityOnCooldown)
+	expectFail(ThrowRockAbility, AbilityNoResource)
+	expectFail(99, AbilityInvalid)
+
+	for i := uint32(0); i < punch.Cooldown; i++ {
+		g.simulate()
+	}
+	attacker.CurrentStats.Stamina = 50
+	target.Position = physics.Vect2{X: 6000, Y: 5000}
+	g.World.Space.UpdateEntity(target)
+	g.simulate()
+	expectFail(PunchAbility, AbilityOutOfRange)
+
+	use(ThrowRockAbility)
+	res = drainType(out, a, messages.AbilityResultMsgType)
+	if len(res) != 1 || res[0].(*messages.AbilityResult).State != AbilityFired {
+		t.Fatalf("Expected a rock to be thrown, got %v", res)
+	}
+	hits := []projectileHit{}
+	for i := 0; i < 60 && len(hits) == 0; i++ {
+		hits = g.simulate()
+	}
+	g.applyHits(hits)
+	if len(hits) != 1 || victim.CurrentStats.HP != 100-want-Abilities[ThrowRockAbility].damage(attacker.CurrentStats) {
+		t.Fatalf("Thrown rock should hit the target, hits: %v HP: %d", hits, victim.CurrentStats.HP)
+	}
+}
+
+// TestAbilityCooldownServerTick checks that claiming an old tick doesn't shorten a cooldown.
+func TestAbilityCooldownServerTick(t *testing.T) {
+	out := make(chan OutgoingMessage, 1000)
+	a, b := &Client{ID: 1}, &Client{ID: 2}
+	g, chars := newTestGame(out, a, b)
+	attacker, victim := chars[0], chars[1]
+	attacker.CurrentStats = Stats{Stamina: 100}
+	victim.CurrentStats = Stats{HP: 100}
+	target := g.World.Entities[victim.ID].Body
+	target.Position = physics.Vect2{X: 5050, Y: 5000}
+	g.World.Space.UpdateEntity(target)
+	const lag = 10
+	for i := 0; i < lag; i++ {
+		g.simulate()
+	}
+	g.UpdateInterest()
+	drainFor(out, a)
+
+	punch := func(tick uint32) byte {
+		g.UseAbility(a, &messages.UseAbility{EntityID: attacker.ID, AbilityID: PunchAbility, TickID: tick, Target: victim.ID})
+		res := drainType(out, a, messages.AbilityResultMsgType)
+		if len(res) != 1 {
+			t.Fatalf("Expected one ability result, got %v", res)
+		}
+		return res[0].(*messages.AbilityResult).State
+	}
+	if state := punch(g.World.Space.TickID - lag); state != AbilityHit {
+		t.Fatalf("Expected a lagged punch to hit, got %d", state)
+	}
+	for i := uint32(0); i < Abilities[PunchAbility].Cooldown-lag; i++ {
+		g.simulate()
+	}
+	if state := punch(g.World.Space.TickID); state != AbilityOnCooldown {
+		t.Fatalf("Cooldown should run from the server tick the punch was used, got %d", state)
+	}
+}
@@ -1,12 +1,12 @@
 package main
 
 import (
-	"bytes"
 	"fmt"
 	"log"
 	"net"
 	"os"
 	"os/signal"
+	"sync/atomic"
 	"time"
 
 	"github.com/lologarithm/survival/server"
@@ -22,7 +22,7 @@ func main() {
 		alive:           true,
 		incoming:        make(chan messages.Packet, 100),
 		outgoing:        make(chan messages.Packet, 100),
-		partialMessages: map[uint32][]*messages.Multipart{},
+		partialMessages: messages.NewReassembler(),
 	}
 	connected := Connect(mu)
 	if connected {
@@ -45,7 +45,20 @@ type MockUser struct {
 	conn            *net.UDPConn
 	incoming        chan messages.Packet
 	outgoing        chan messages.Packet
-	partialMessages map[uint32][]*messages.Multipart
+	partialMessages *messages.Reassembler
+	remoteSeq       uint16                                 // Latest reliable seq received.
+	received        uint32                                 // Bit n set means remoteSeq-(n+1) was received.
+	hasRemote       bool                                   // Set once the first reliable seq arrives.
+	session         atomic.Value                           // *messages.Session once the handshake is done
+	handshake       *messages.Packet                       // Last handshake message, sent again until the server answers.
+	tick            uint32                                 // Latest server tick seen in a master frame
+	states          map[uint32]map[uint32]*messages.Entity // World state by tick, to apply deltas to.
+}
+
+// secureSession returns the encrypted session or nil before the handshake.
+func (mu *MockUser) secureSession() *messages.Session {
+	s, _ := mu.session.Load().(*messages.Session)
+	return s
 }
 
 func ReadMessages(mu *MockUser) {
@@ -66,11 +79,56 @@ func ReadMessages(mu *MockUser) {
 			}
 			copy(buf, buf[pack.Len():])
 			widx -= pack.Len()
+			if session := mu.secureSession(); session != nil && pack.Frame.MsgType == messages.SecureMsgType {
+				inner, fresh, ok := session.Open(pack)
+				if !ok {
+					fmt.Printf("Failed to open secure message.\n")
+					continue
+				}
+				if !fresh && inner.Frame.Seq == 0 {
+					continue
+				}
+				pack = inner
+			}
+			if pack.Frame.Seq != 0 {
+				// Reliable message, ack it and skip if we already have it.
+				sendmsg(mu, messages.NewPacket(messages.AckMsgType, &messages.Ack{Seq: pack.Frame.Seq}))
+				if !mu.receive(pack.Frame.Seq) {
+					continue
+				}
+			}
 			mu.incoming <- pack
 		}
 	}
 }
 
+// ackWindow is how many seqs older than the latest are remembered, the same as the server.
+const ackWindow = 32
+
+// receive records a reliable seq the same way the server's reliable channel does.
+// Returns false if the seq was already seen (or is too old to tell).
+func (mu *MockUser) receive(seq uint16) bool {
+	if !mu.hasRemote {
+		mu.remoteSeq, mu.received, mu.hasRemote = seq, 0, true
+		return true
+	}
+	if newer := seq - mu.remoteSeq; newer != 0 && newer <= 32768 {
+		if newer > ackWindow {
+			mu.received = 0
+		} else {
+			mu.received = (mu.received << newer) | (1 << (newer - 1))
+		}
+		mu.remoteSeq = seq
+		return true
+	}
+	older := mu.remoteSeq - seq
+	if older == 0 || older > ackWindow || mu.received&(1<<(older-1)) != 0 {
+		return false
+	}
+	mu.received |= 1 << (older - 1)
+	return true
+}
+
 func Connect(mu *MockUser) bool {
 	ra, err := net.ResolveUDPAddr("udp", "localhost:24816")
 	if err != nil {
@@ -87,30 +145,30 @@ func Connect(mu *MockUser) bool {
 }
 
 func RunUser(mu *MockUser, exit chan int) {
-	packet := messages.NewPacket(messages.CreateAcctMsgType, &messages.CreateAcct{
-		Name:     "testuser",
-		Password: "testpass",
-		CharName: "mahuser",
-	})
-	_, err := mu.conn.Write(packet.Pack())
-	if err != nil {
-		fmt.Printf("Failed to write to connection.")
-		fmt.Println(err)
-	}
+	// Set up an encrypted session before sending the account password.
+	mu.handshake = messages.NewPacket(messages.KeyRequestMsgType, &messages.KeyRequest{})
+	writemsg(mu, mu.handshake)
 
 	go func() {
 		<-exit
 		mu.alive = false
 	}()
 
+	resend := time.NewTicker(time.Second)
+	defer resend.Stop()
 	for mu.alive {
 		select {
 		case msg := <-mu.incoming:
 			ProcessMessage(mu, msg)
+		case <-resend.C:
+			// Handshake messages aren't reliable, if one was lost send it again.
+			if mu.handshake != nil {
+				writemsg(mu, mu.handshake)
+			}
 		}
 	}
 
-	log.Printf("shutting down user.")
+	log.Printf("shutting down user. multipart groups dropped: %d, expired: %d", mu.partialMessages.Dropped, mu.partialMessages.Expired)
 	disconn := messages.NewPacket(messages.DisconnectedMsgType, &messages.Disconnected{})
 	disb := disconn.Pack()
 	mu.conn.Write(disb)
@@ -118,21 +176,68 @@ func RunUser(mu *MockUser, exit chan int) {
 
 func ProcessMessage(mu *MockUser, msg messages.Packet) {
 	switch msg.Frame.MsgType {
+	case messages.ServerKeyMsgType:
+		if mu.secureSession() != nil {
+			return
+		}
+		key, err := messages.NewSessionKey()
+		if err != nil {
+			fmt.Printf("Failed to create session key: %s\n", err)
+			return
+		}
+		encrypted, err := messages.EncryptSessionKey(msg.NetMsg.(*messages.ServerKey).Key, key)
+		if err != nil {
+			fmt.Printf("Failed to encrypt session key: %s\n", err)
+			return
+		}
+		session, _ := messages.NewSession(key, false)
+		// Keep the session before sending the key so the encrypted SessionReady can be opened,
+		// the key itself goes out unencrypted.
+		mu.session.Store(session)
+		mu.handshake = messages.NewPacket(messages.SessionKeyMsgType, &messages.SessionKey{Key: encrypted})
+		writemsg(mu, mu.handshake)
+	case messages.SessionReadyMsgType:
+		if mu.handshake == nil {
+			return
+		}
+		mu.handshake = nil
+		sendmsg(mu, messages.NewPacket(messages.CreateAcctMsgType, &messages.CreateAcct{
+			Name:     "testuser",
+			Password: "testpass",
+			CharName: "mahuser",
+		}))
 	case messages.CreateAcctRespMsgType:
 		sendmsg(mu, messages.NewPacket(messages.CreateGameMsgType, &messages.CreateGame{
 			Name: "newgame",
 		}))
 	case messages.GameMasterFrameMsgType:
 		tmsg := msg.NetMsg.(*messages.GameMasterFrame)
+		// Big states are split over several master frames with the same tick.
+		state := mu.states[tmsg.TickID]
+		if state == nil {
+			state = map[uint32]*messages.Entity{}
+		}
 		for _, e := range tmsg.Entities {
-			if e.EType == server.CreatureEType {
-				fmt.Printf("Ent: %d @ (%d,%d)\n", e.ID, e.X, e.Y)
-			}
+			state[e.ID] = e
+		}
+		gotState(mu, tmsg.ID, tmsg.TickID, state)
+	case messages.GameDeltaFrameMsgType:
+		tmsg := msg.NetMsg.(*messages.GameDeltaFrame)
+		base, ok := mu.states[tmsg.BaseTick]
+		if !ok {
+			fmt.Printf("Missing base state %d for delta.\n", tmsg.BaseTick)
+			return
 		}
+		state, ok := messages.ApplyEntityDeltas(base, tmsg.Deltas)
+		if !ok {
+			fmt.Printf("Bad delta frame for tick %d.\n", tmsg.TickID)
+			return
+		}
+		gotState(mu, tmsg.ID, tmsg.TickID, state)
 	case messages.CreateGameRespMsgType:
 		sendmsg(mu, messages.NewPacket(messages.MovePlayerMsgType, &messages.MovePlayer{
 			EntityID: 0,
-			TickID:   0,
+			TickID:   mu.tick,
 			X:        1,
 			Y:        0,
 		}))
@@ -142,7 +247,40 @@ func ProcessMessage(mu *MockUser, msg messages.Packet) {
 
 }
 
+const keepStateTicks = 500
+
+// gotState keeps a state from the server and acknowledges it so the next update can be a delta from it.
+func gotState(mu *MockUser, gameID uint32, tick uint32, state map[uint32]*messages.Entity) {
+	if mu.states == nil {
+		mu.states = map[uint32]map[uint32]*messages.Entity{}
+	}
+	mu.states[tick] = state
+	// Keep states well past how long the server will use one as a delta base.
+	for t := range mu.states {
+		if t+keepStateTicks < tick {
+			delete(mu.states, t)
+		}
+	}
+	if tick > mu.tick {
+		mu.tick = tick
+	}
+	for _, e := range state {
+		if e.EType == server.CreatureEType {
+			fmt.Printf("Ent: %d @ (%d,%d)\n", e.ID, e.X, e.Y)
+		}
+	}
+	sendmsg(mu, messages.NewPacket(messages.FrameAckMsgType, &messages.FrameAck{GameID: gameID, TickID: tick}))
+}
+
 func sendmsg(mu *MockUser, msg *messages.Packet) {
+	if session := mu.secureSession(); session != nil {
+		msg = session.Seal(msg)
+	}
+	writemsg(mu, msg)
+}
+
+// writemsg writes the packet as is, without encrypting it.
+func writemsg(mu *MockUser, msg *messages.Packet) {
 	_, err := mu.conn.Write(msg.Pack())
 	if err != nil {
 		fmt.Printf("Failed to write to connection.")
@@ -152,29 +290,7 @@ func sendmsg(mu *MockUser, msg *messages.Packet) {
 
 func handleMultipart(mu *MockUser, packet messages.Packet) {
 	netmsg := packet.NetMsg.(*messages.Multipart)
-	// 1. Check if this group already exists
-	if _, ok := mu.partialMessages[netmsg.GroupID]; !ok {
-		mu.partialMessages[netmsg.GroupID] = make([]*messages.Multipart, netmsg.NumParts)
-	}
-	// 2. Insert into group
-	mu.partialMessages[netmsg.GroupID][netmsg.ID] = netmsg
-	// 3. See if group is ready to process
-	isReady := true
-	for _, p := range mu.partialMessages[netmsg.GroupID] {
-		if p == nil {
-			isReady = false
-			break
-		}
-	}
-	if isReady {
-		buf := &bytes.Buffer{}
-		for _, p := range mu.partialMessages[netmsg.GroupID] {
-			buf.Write(p.Content)
-		}
-		packet, ok := messages.NextPacket(buf.Bytes())
-		if !ok {
-			fmt.Printf("lol, failed multipart.... %v", packet)
-		}
+	if packet, ok := mu.partialMessages.Add(netmsg, time.Now()); ok {
 		mu.incoming <- packet
 	}
 }
@@ -48,11 +48,11 @@ func sendMessages(exit chan int) {
 		return
 	}
 
-	packet := messages.NewPacket(messages.LoginMsgType, &messages.Login{
-		Name:     "testuser",
-		Password: "testpass",
-	})
-	msgbytes := packet.Pack()
+	session, err := handshake(conn)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
 
 	alive := true
 	go func() {
@@ -83,7 +83,12 @@ func sendMessages(exit chan int) {
 	}()
 
 	for alive {
-		_, err = conn.Write(msgbytes)
+		// Every sealed packet can only be used once, so seal a new login each time.
+		packet := session.Seal(messages.NewPacket(messages.LoginMsgType, &messages.Login{
+			Name:     "testuser",
+			Password: "testpass",
+		}))
+		_, err = conn.Write(packet.Pack())
 		if err != nil {
 			fmt.Printf("Failed to write to connection.")
 			fmt.Println(err)
@@ -94,3 +99,47 @@ func sendMessages(exit chan int) {
 	disb := disconn.Pack()
 	conn.Write(disb)
 }
+
+// handshake sets up an encrypted session with the server, logins are dropped without one.
+// The key request and session key are sent again until the server answers.
+func handshake(conn *net.UDPConn) (*messages.Session, error) {
+	key, err := messages.NewSessionKey()
+	if err != nil {
+		return nil, err
+	}
+	session, _ := messages.NewSession(key, false)
+	next := messages.NewPacket(messages.KeyRequestMsgType, &messages.KeyRequest{}).Pack()
+	buf := make([]byte, 1024)
+	defer conn.SetReadDeadline(time.Time{})
+	for tries := 0; tries < 10; tries++ {
+		conn.Write(next)
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		for {
+			n, err := conn.Read(buf)
+			if err != nil {
+				break // Timed out, send the last message again.
+			}
+			pack, ok := messages.NextPacket(buf[:n])
+			if !ok {
+				continue
+			}
+			switch pack.Frame.MsgType {
+			case messages.ServerKeyMsgType:
+				conn.Write(messages.NewPacket(messages.AckMsgType, &messages.Ack{Seq: pack.Frame.Seq}).Pack())
+				encrypted, err := messages.EncryptSessionKey(pack.NetMsg.(*messages.ServerKey).Key, key)
+				if err != nil {
+					return nil, err
+				}
+				next = messages.NewPacket(messages.SessionKeyMsgType, &messages.SessionKey{Key: encrypted}).Pack()
+				conn.Write(next)
+			case messages.SecureMsgType:
+				inner, _, ok := session.Open(pack)
+				if ok && inner.Frame.MsgType == messages.SessionReadyMsgType {
+					conn.Write(session.Seal(messages.NewPacket(messages.AckMsgType, &messages.Ack{Seq: inner.Frame.Seq})).Pack())
+					return session, nil
+				}
+			}
+		}
+	}
+	return nil, fmt.Errorf("no answer from the server to the handshake")
+}
@@ -0,0 +1,197 @@
+package server
+
+import (
+	"github.com/lologarithm/survival/physics"
+	"github.com/lologarithm/survival/server/messages"
+)
+
+// Kits a new character can start with, picked by CreateAcct.DefaultKit.
+const (
+	FighterKit byte = iota
+	MageKit
+	ScoutKit
+)
+
+// Kits are the starting stats of each kit.
+var Kits = map[byte]Stats{
+	FighterKit: {HP: 120, Stamina: 100, Concentration: 30, Speed: 50, MagicStrength: 2, PhysicalStrength: 10},
+	MageKit:    {HP: 80, Stamina: 50, Concentration: 100, Speed: 50, MagicStrength: 10, PhysicalStrength: 2},
+	ScoutKit:   {HP: 100, Stamina: 80, Concentration: 50, Speed: 70, MagicStrength: 4, PhysicalStrength: 6},
+}
+
+// NewCharacter makes a character with the given base stats at full health with nothing equipped.
+func NewCharacter(name string, stats Stats) *Character {
+	return &Character{
+		Name:           name,
+		Stats:          stats,
+		CurrentStats:   stats,
+		Needs:          FullNeeds,
+		EquippedItems:  make([]*Item, NumSlots),
+		InventoryItems: []*Item{},
+	}
+}
+
+// clone copies the character and its items so a game can change it without touching the account's copy.
+func (c *Character) clone() *Character {
+	nc := *c
+	nc.EquippedItems = make([]*Item, len(c.EquippedItems))
+	for i, it := range c.EquippedItems {
+		if it != nil {
+			nit := *it
+			nc.EquippedItems[i] = &nit
+		}
+	}
+	nc.InventoryItems = make([]*Item, len(c.InventoryItems))
+	for i, it := range c.InventoryItems {
+		nit := *it
+		nc.InventoryItems[i] = &nit
+	}
+	return &nc
+}
+
+// KitStats returns the starting stats for a kit, unknown kits start as a fighter.
+func KitStats(kit byte) Stats {
+	if stats, ok := Kits[kit]; ok {
+		return stats
+	}
+	return Kits[FighterKit]
+}
+
+// Stamina and Concentration come back by this much every regenInterval ticks, up to the character's MaxStats.
+const (
+	regenInterval            = 15
+	staminaRegen       int16 = 2
+	concentrationRegen int16 = 1
+)
+
+// RespawnTicks is how long a dead character waits before it comes back at the spawn point.
+const RespawnTicks = 150
+
+// SpawnPoint is where characters enter the world and come back after dying.
+var SpawnPoint = physics.Vect2{X: 5000, Y: 5000}
+
+// HealthPercent is how much of its HP the character has left, from 0 to 100.
+func (c *Character) HealthPercent() byte {
+	max := c.MaxStats().HP
+	if max <= 0 || c.CurrentStats.HP <= 0 {
+		return 0
+	}
+	if c.CurrentStats.HP >= max {
+		return 100
+	}
+	return byte(c.CurrentStats.HP * 100 / max)
+}
+
+// regen gives back some Stamina and Concentration without going over the character's MaxStats.
+func (c *Character) regen() {
+	max := c.MaxStats()
+	if c.CurrentStats.Stamina < max.Stamina {
+		c.CurrentStats.Stamina = min16(c.CurrentStats.Stamina+staminaRegen, max.Stamina)
+	}
+	if c.CurrentStats.Concentration < max.Concentration {
+		c.CurrentStats.Concentration = min16(c.CurrentStats.Concentration+concentrationRegen, max.Concentration)
+	}
+}
+
+// spawnCharacter returns a command that puts a character's entity in the world at the spawn point.
+func spawnCharacter(id uint32, name string, seed uint64) func(*GameWorld) {
+	return func(w *GameWorld) {
+		if _, ok := w.Entities[id]; ok {
+			return
+		}
+		player := &Entity{
+			ID:    id,
+			Name:  name,
+			EType: CreatureEType,
+			Seed:  seed,
+			Body:  physics.NewRigidBody(id, 22, 46, SpawnPoint, physics.Vect2{}, 0, 100),
+		}
+		w.Space.AddEntity(player.Body, false)
+		w.Entities[id] = player
+	}
+}
+
+// UpdateCharacters regenerates the living characters and respawns dead ones whose wait is over.
+func (g *GameSession) UpdateCharacters() {
+	now := g.World.Space.TickID
+	for _, user := range g.Clients {
+		char := user.Accounts[0].Character
+		if user.respawnAt != 0 {
+			if now >= user.respawnAt {
+				char.CurrentStats = char.MaxStats()
+				char.Needs = FullNeeds
+				user.respawnAt = 0
+				g.applyNow(user.respawn)
+				user.respawn = nil
+			}
+			continue
+		}
+		if now%regenInterval == 0 {
+			char.regen()
+		}
+	}
+}
+
+// damageCharacter takes HP from the character or creature of the entity, if it is one.
+// A character that reaches 0 HP dies, source is the entity that did the damage.
+func (g *GameSession) damageCharacter(id, source uint32, damage int32) {
+	if cr := g.creatures[id]; cr != nil {
+		g.damageCreature(cr, source, damage)
+		return
+	}
+	user := g.userFor(id)
+	if user == nil || user.respawnAt != 0 {
+		return
+	}
+	stats := &user.Accounts[0].Character.CurrentStats
+	stats.HP -= damage
+	if stats.HP <= 0 {
+		stats.HP = 0
+		g.killCharacter(user, source)
+	}
+}
+
+// killCharacter tells everyone who can see the character that it died, takes it
+// out of the world and schedules it to come back after RespawnTicks.
+func (g *GameSession) killCharacter(user *User, killer uint32) {
+	id := user.Accounts[0].Character.ID
+	ent := g.World.Entities[id]
+	if ent == nil {
+		return
+	}
+	user.respawnAt = g.World.Space.TickID + RespawnTicks
+	user.respawn = spawnCharacter(id, ent.Name, ent.Seed)
+	died := &messages.CharacterDied{
+		EntityID:    id,
+		KillerID:    killer,
+		RespawnTick: user.respawnAt,
+	}
+	for _, u := range g.Clients {
+		if u == user || u.visible[id] {
+			g.ToNetwork <- NewReliableMsg(u.Client, messages.CharacterDiedMsgType, died)
+		}
+	}
+	g.applyNow(func(w *GameWorld) {
+		if e := w.Entities[id]; e != nil {
+			w.removeEntity(e)
+		}
+	})
+}
+
+// entityMsg converts an entity to a network message with the health of its character or creature filled in.
+func (g *GameSession) entityMsg(e *Entity) *messages.Entity {
+	msg := e.toMsg()
+	if cr := g.creatures[e.ID]; cr != nil {
+		msg.HealthPercent = cr.Character.HealthPercent()
+	} else if user := g.userFor(e.ID); user != nil {
+		msg.HealthPercent = user.Accounts[0].Character.HealthPercent()
+	}
+	return msg
+}
+
+func min16(a, b int16) int16 {
+	if a < b {
+		return a
+	}
+	return b
+}
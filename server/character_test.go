@@ -0,0 +1,98 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/lologarithm/survival/physics"
+	"github.com/lologarithm/survival/server/messages"
+)
+
+func TestKitStats(t *testing.T) {
+	gm := NewGameManager(make(chan int, 1), nil, make(chan OutgoingMessage, 100))
+	c := &Client{ID: 1, FromGameManager: make(chan InternalMessage, 10)}
+	gm.ProcessNetMsg(GameMessage{client: c, net: &messages.Connected{}, mtype: messages.ConnectedMsgType})
+	gm.ProcessNetMsg(GameMessage{client: c, net: &messages.CreateAcct{Name: "m", CharName: "m", DefaultKit: MageKit}, mtype: messages.CreateAcctMsgType})
+	char := gm.AcctByName["m"].Character
+	if char.Stats != Kits[MageKit] || char.CurrentStats != char.Stats {
+		t.Fatalf("Expected mage stats, got %v / %v", char.Stats, char.CurrentStats)
+	}
+	if KitStats(200) != Kits[FighterKit] {
+		t.Fatalf("Unknown kit should start as a fighter.")
+	}
+}
+
+func TestRegen(t *testing.T) {
+	g, chars := newTestGame(nil, &Client{ID: 1})
+	char := chars[0]
+	char.CurrentStats.Stamina = 0
+	char.CurrentStats.Concentration = char.Stats.Concentration
+	for i := 0; i < regenInterval*100; i++ {
+		g.simulate()
+		g.UpdateCharacters()
+		if char.CurrentStats.Stamina > char.Stats.Stamina || char.CurrentStats.Concentration > char.Stats.Concentration {
+			t.Fatalf("Regenerated past max: %v", char.CurrentStats)
+		}
+	}
+	if char.CurrentStats.Stamina != char.Stats.Stamina {
+		t.Fatalf("Stamina did not regenerate: %d", char.CurrentStats.Stamina)
+	}
+}
+
+func TestDeathAndRespawn(t *testing.T) {
+	out := make(chan OutgoingMessage, 1000)
+	g := NewGame("A", nil, nil, out)
+	a, b := &Client{ID: 1}, &Client{ID: 2}
+	g.addPlayer(AddPlayer{Entity: &Entity{Name: "a"}, Character: NewCharacter("a", Kits[FighterKit]), Client: a})
+	g.addPlayer(AddPlayer{Entity: &Entity{Name: "b"}, Character: NewCharacter("b", Kits[MageKit]), Client: b})
+	killer, victim := g.Clients[1].Accounts[0].Character, g.Clients[2].Accounts[0].Character
+	body := g.World.Entities[victim.ID].Body
+	body.Position = physics.Vect2{X: 6000, Y: 5000}
+	g.World.Space.UpdateEntity(body)
+	g.UpdateInterest()
+	drainFor(out, a)
+
+	g.damageCharacter(victim.ID, killer.ID, 20)
+	if hp := g.entityMsg(g.World.Entities[victim.ID]).HealthPercent; hp != 75 {
+		t.Fatalf("Expected 75%% health, got %d", hp)
+	}
+	g.damageCharacter(victim.ID, killer.ID, 100)
+	if victim.CurrentStats.HP != 0 {
+		t.Fatalf("HP should stop at 0, is %d", victim.CurrentStats.HP)
+	}
+	if _, ok := g.World.Entities[victim.ID]; ok {
+		t.Fatalf("Dead character should be removed from the world.")
+	}
+	sent := map[*Client][]*messages.CharacterDied{}
+	for len(out) > 0 {
+		msg := <-out
+		sent[msg.dest] = append(sent[msg.dest], msg.msg.NetMsg.(*messages.CharacterDied))
+	}
+	for _, c := range []*Client{a, b} {
+		if len(sent[c]) != 1 {
+			t.Fatalf("Expected a death message, got %v", sent[c])
+		}
+		died := sent[c][0]
+		if died.EntityID != victim.ID || died.KillerID != killer.ID || died.RespawnTick != g.World.Space.TickID+RespawnTicks {
+			t.Fatalf("Wrong death message: %v", died)
+		}
+	}
+	g.damageCharacter(victim.ID, killer.ID, 10)
+	if len(drainFor(out, b)) != 0 {
+		t.Fatalf("Dead characters can't die again.")
+	}
+
+	for i := 0; i < RespawnTicks; i++ {
+		g.simulate()
+		g.UpdateCharacters()
+		if i < RespawnTicks-1 && g.World.Entities[victim.ID] != nil {
+			t.Fatalf("Respawned early at tick %d", i)
+		}
+	}
+	ent := g.World.Entities[victim.ID]
+	if ent == nil || ent.Body.Position != SpawnPoint {
+		t.Fatalf("Expected character to respawn at the spawn point.")
+	}
+	if victim.CurrentStats != victim.Stats || g.entityMsg(ent).HealthPercent != 100 {
+		t.Fatalf("Expected full stats after respawn, got %v", victim.CurrentStats)
+	}
+}
@@ -1,7 +1,7 @@
 package server
 
 import (
-	"bytes"
+	"crypto/rsa"
 	"log"
 	"net"
 	"sync/atomic"
@@ -29,8 +29,13 @@ type Client struct {
 	toGameManager chan<- GameMessage // Messages to the main game manager.
 	activeGame    *clientGame
 
-	Seq     uint16
-	GroupID uint32
+	reliable *reliableChannel // Sequence and ack tracking for reliable packets.
+
+	serverKey       *rsa.PrivateKey   // Used to decrypt the session key sent by the client.
+	serverPublicKey []byte            // Sent to the client when it asks for a key.
+	session         *messages.Session // Set once the handshake is done, only access atomically.
+
+	GroupID uint32 // Last multipart group ID sent, only access atomically.
 	Alive   bool
 }
 
@@ -48,13 +53,19 @@ func (client *Client) ProcessBytes(disconClient chan Client) {
 		net:    &messages.Connected{},
 		mtype:  messages.ConnectedMsgType,
 	}
+	if client.reliable == nil {
+		client.reliable = newReliableChannel()
+	}
 	client.Alive = true
 	client.lastMsg = time.Now().UTC().Unix()
-	// Used to cache parts of a message.
-	// TODO: When should this be cleaned out?
-	partialMessages := map[uint32][]*messages.Multipart{}
+	// Used to rebuild messages that were split into parts.
+	partialMessages := messages.NewReassembler()
 
 	go func() {
+		heartbeat := time.NewTicker(time.Second * 10)
+		resend := time.NewTicker(reliableTick)
+		defer heartbeat.Stop()
+		defer resend.Stop()
 		for {
 			select {
 			case msg := <-client.FromGameManager:
@@ -67,7 +78,18 @@ func (client *Client) ProcessBytes(disconClient chan Client) {
 					atomic.StorePointer((*unsafe.Pointer)(unsafe.Pointer(&client.activeGame)), unsafe.Pointer(activeGame))
 					log.Printf("Got connected, hooked up toGame channel!")
 				}
-			case <-time.After(time.Second * 10):
+			case now := <-resend.C:
+				if !client.Alive {
+					continue
+				}
+				packets, dropped := client.reliable.due(now)
+				for _, p := range packets {
+					client.ToNetwork <- OutgoingMessage{dest: client, raw: p}
+				}
+				if dropped > 0 {
+					log.Printf("Client %d: dropped %d reliable packets that were never acked.", client.ID, dropped)
+				}
+			case <-heartbeat.C:
 				if !client.Alive {
 					log.Printf("Client %d: no longer alive.", client.ID)
 					return
@@ -98,29 +120,12 @@ func (client *Client) ProcessBytes(disconClient chan Client) {
 		if packet.Frame.MsgType == messages.DisconnectedMsgType {
 			client.Alive = false
 			break
-		} else if ok && packet.Frame.MsgType == messages.MultipartMsgType {
-			netmsg := packet.NetMsg.(*messages.Multipart)
-			// 1. Check if this group already exists
-			if _, ok := partialMessages[netmsg.GroupID]; !ok {
-				partialMessages[netmsg.GroupID] = make([]*messages.Multipart, netmsg.NumParts)
-			}
-			// 2. Insert into group
-			partialMessages[netmsg.GroupID][netmsg.ID] = netmsg
-			// 3. See if group is ready to process
-			isReady := true
-			for _, p := range partialMessages[netmsg.GroupID] {
-				if p == nil {
-					isReady = false
-					break
-				}
-			}
-			if isReady {
-				buf := &bytes.Buffer{}
-				for _, p := range partialMessages[netmsg.GroupID] {
-					buf.Write(p.Content)
-				}
-				packet, ok = messages.NextPacket(buf.Bytes())
-			}
+		} else if !ok && client.wIdx >= messages.FrameLen && packet.Len() <= client.wIdx {
+			// Have the whole message but it couldn't be parsed, throw it away.
+			log.Printf("Client %d: dropping invalid message (%v)", client.ID, packet.Frame)
+			copy(client.buffer, client.buffer[packet.Len():])
+			client.wIdx -= packet.Len()
+			continue
 		} else if !ok || packet.Len() > client.wIdx {
 			// This means we need more data still.
 			n := client.FromNetwork.Read(client.buffer[client.wIdx:])
@@ -133,25 +138,83 @@ func (client *Client) ProcessBytes(disconClient chan Client) {
 			client.wIdx += n
 			continue
 		}
-		// Only try to parse if we have collected enough bytes.
-		if ok {
-			switch packet.Frame.MsgType {
-			case messages.CreateAcctMsgType, messages.LoginMsgType, messages.ListGamesMsgType, messages.JoinGameMsgType, messages.CreateGameMsgType:
-				client.toGameManager <- GameMessage{net: packet.NetMsg, client: client, mtype: packet.Frame.MsgType}
-			default:
-				if client.activeGame == nil {
-					log.Printf("Client sent message (%d:%v) before in a game!", packet.Frame.MsgType, packet.NetMsg)
-					break
+
+		// Remove the used bytes from the buffer.
+		copy(client.buffer, client.buffer[packet.Len():])
+		client.wIdx -= packet.Len()
+
+		// Once a session is set up everything from the client must be encrypted.
+		if session := client.secureSession(); session != nil {
+			if packet.Frame.MsgType != messages.SecureMsgType {
+				log.Printf("Client %d: dropping unencrypted message (%d) on a secure session.", client.ID, packet.Frame.MsgType)
+				continue
+			}
+			inner, fresh, valid := session.Open(packet)
+			if !valid {
+				log.Printf("Client %d: dropping message that failed authentication.", client.ID)
+				continue
+			}
+			if !fresh {
+				// Replayed (or resent) packet, at most it needs to be acked again.
+				if inner.Frame.Seq != 0 {
+					client.ToNetwork <- NewOutgoingMsg(client, messages.AckMsgType, client.reliable.ack())
 				}
-				client.activeGame.toGame <- GameMessage{net: packet.NetMsg, client: client, mtype: packet.Frame.MsgType}
+				continue
 			}
+			packet = inner
+		}
 
-			// Remove the used bytes from the buffer.
-			copy(client.buffer, client.buffer[packet.Len():])
-			client.wIdx -= packet.Len()
+		// Reliable packets are always acked, even duplicates, in case our last ack was lost.
+		if packet.Frame.Seq != 0 {
+			isNew := client.reliable.receive(packet.Frame.Seq)
+			client.ToNetwork <- NewOutgoingMsg(client, messages.AckMsgType, client.reliable.ack())
+			if !isNew {
+				continue
+			}
+		}
+
+		switch packet.Frame.MsgType {
+		case messages.AckMsgType:
+			client.reliable.acked(packet.NetMsg.(*messages.Ack))
+			continue
+		case messages.KeyRequestMsgType:
+			client.ToNetwork <- NewReliableMsg(client, messages.ServerKeyMsgType, &messages.ServerKey{Key: client.serverPublicKey})
+			continue
+		case messages.SessionKeyMsgType:
+			client.startSession(packet.NetMsg.(*messages.SessionKey))
+			continue
+		case messages.SecureMsgType:
+			log.Printf("Client %d: got encrypted message without a session.", client.ID)
+			continue
+		case messages.MultipartMsgType:
+			packet, ok = partialMessages.Add(packet.NetMsg.(*messages.Multipart), time.Now())
+			if !ok {
+				continue
+			}
+		}
+
+		switch packet.Frame.MsgType {
+		case messages.CreateAcctMsgType, messages.LoginMsgType:
+			// Passwords are only accepted over an encrypted session.
+			if client.secureSession() == nil {
+				log.Printf("Client %d: dropping account message (%d) sent before the handshake.", client.ID, packet.Frame.MsgType)
+				break
+			}
+			client.toGameManager <- GameMessage{net: packet.NetMsg, client: client, mtype: packet.Frame.MsgType}
+		case messages.ListGamesMsgType, messages.JoinGameMsgType, messages.CreateGameMsgType:
+			client.toGameManager <- GameMessage{net: packet.NetMsg, client: client, mtype: packet.Frame.MsgType}
+		default:
+			if client.activeGame == nil {
+				log.Printf("Client sent message (%d:%v) before in a game!", packet.Frame.MsgType, packet.NetMsg)
+				break
+			}
+			client.activeGame.toGame <- GameMessage{net: packet.NetMsg, client: client, mtype: packet.Frame.MsgType}
 		}
 	}
 	log.Printf("  shutdown client msg parser: %d\n", client.ID)
+	if partialMessages.Dropped > 0 || partialMessages.Expired > 0 {
+		log.Printf("  client %d multipart groups dropped: %d, expired: %d", client.ID, partialMessages.Dropped, partialMessages.Expired)
+	}
 	client.toGameManager <- GameMessage{
 		client: client,
 		net:    &messages.Disconnected{},
@@ -160,3 +223,28 @@ func (client *Client) ProcessBytes(disconClient chan Client) {
 	disconClient <- *client
 	close(client.FromGameManager)
 }
+
+// startSession decrypts the client's session key and switches the client to encrypted messages.
+// A session can only be started once per connection.
+func (client *Client) startSession(msg *messages.SessionKey) {
+	if client.secureSession() != nil || client.serverKey == nil {
+		return
+	}
+	key, err := messages.DecryptSessionKey(client.serverKey, msg.Key)
+	if err != nil {
+		log.Printf("Client %d: failed to decrypt session key: %s", client.ID, err)
+		return
+	}
+	session, err := messages.NewSession(key, true)
+	if err != nil {
+		log.Printf("Client %d: failed to create session: %s", client.ID, err)
+		return
+	}
+	atomic.StorePointer((*unsafe.Pointer)(unsafe.Pointer(&client.session)), unsafe.Pointer(session))
+	client.ToNetwork <- NewReliableMsg(client, messages.SessionReadyMsgType, &messages.SessionReady{})
+}
+
+// secureSession returns the encrypted session or nil if the handshake hasn't happened.
+func (client *Client) secureSession() *messages.Session {
+	return (*messages.Session)(atomic.LoadPointer((*unsafe.Pointer)(unsafe.Pointer(&client.session))))
+}
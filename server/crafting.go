@@ -0,0 +1,252 @@
+package server
+
+import (
+	"github.com/lologarithm/survival/physics"
+	"github.com/lologarithm/survival/physics/quadtree"
+	"github.com/lologarithm/survival/server/messages"
+)
+
+// Recipe IDs
+const (
+	ClubRecipe uint32 = iota + 1
+	StoneAxeRecipe
+	ShieldRecipe
+	CharmRecipe
+	WorkbenchRecipe
+	CampfireRecipe
+	TeaRecipe
+)
+
+// CraftResult states
+const (
+	CraftStarted      byte = iota + 1 // Inputs were taken, DoneTick is when it will be ready.
+	CraftDone                         // ItemID is the new item, or the built station's entity ID.
+	CraftBusy                         // Already crafting something.
+	CraftMissingItems                 // Inventory doesn't have all the inputs.
+	CraftNoStation                    // Required station isn't within CraftRange.
+	CraftInvalid                      // Unknown recipe or the character is dead.
+)
+
+// CraftRange is how close a character has to be to a station to use it.
+const CraftRange = 150
+
+// StationSize is the height and width of a built station.
+const StationSize = 60
+
+// itemStack is a number of items of one kind.
+type itemStack struct {
+	Kind  uint16
+	Count uint16
+}
+
+// Recipe turns items into a new item or builds a station.
+type Recipe struct {
+	ID      uint32
+	Name    string
+	Inputs  []itemStack // Taken from the inventory when crafting starts.
+	Output  Item        // Item given when crafting is done, ID is filled in then.
+	Builds  uint16      // If set the recipe builds an entity of this type next to the character instead of giving Output.
+	Ticks   uint32      // How long crafting takes.
+	Station uint16      // EType that has to be within CraftRange to craft this, 0 for none.
+}
+
+// Recipes is everything a character can craft, by ID.
+var Recipes = map[uint32]*Recipe{
+	ClubRecipe: {
+		ID:     ClubRecipe,
+		Name:   "Club",
+		Inputs: []itemStack{{Kind: WoodItem, Count: 5}},
+		Output: Item{Kind: ClubItem, Count: 1, Slot: MainHandSlot, Stats: Stats{PhysicalStrength: 3}},
+		Ticks:  30,
+	},
+	StoneAxeRecipe: {
+		ID:      StoneAxeRecipe,
+		Name:    "Stone Axe",
+		Inputs:  []itemStack{{Kind: WoodItem, Count: 3}, {Kind: StoneItem, Count: 4}},
+		Output:  Item{Kind: StoneAxeItem, Count: 1, Slot: MainHandSlot, Stats: Stats{PhysicalStrength: 6}},
+		Ticks:   60,
+		Station: WorkbenchEType,
+	},
+	ShieldRecipe: {
+		ID:      ShieldRecipe,
+		Name:    "Shield",
+		Inputs:  []itemStack{{Kind: WoodItem, Count: 8}},
+		Output:  Item{Kind: ShieldItem, Count: 1, Slot: OffHandSlot, Stats: Stats{HP: 20}},
+		Ticks:   60,
+		Station: WorkbenchEType,
+	},
+	CharmRecipe: {
+		ID:      CharmRecipe,
+		Name:    "Charm",
+		Inputs:  []itemStack{{Kind: StoneItem, Count: 6}, {Kind: BerryItem, Count: 4}},
+		Output:  Item{Kind: CharmItem, Count: 1, Slot: SpiritSlot, Stats: Stats{Concentration: 20, MagicStrength: 4}},
+		Ticks:   90,
+		Station: WorkbenchEType,
+	},
+	WorkbenchRecipe: {
+		ID:     WorkbenchRecipe,
+		Name:   "Workbench",
+		Inputs: []itemStack{{Kind: WoodItem, Count: 10}},
+		Builds: WorkbenchEType,
+		Ticks:  90,
+	},
+	CampfireRecipe: {
+		ID:     CampfireRecipe,
+		Name:   "Campfire",
+		Inputs: []itemStack{{Kind: WoodItem, Count: 5}, {Kind: StoneItem, Count: 3}},
+		Builds: CampfireEType,
+		Ticks:  60,
+	},
+	TeaRecipe: {
+		ID:      TeaRecipe,
+		Name:    "Berry Tea",
+		Inputs:  []itemStack{{Kind: BerryItem, Count: 3}},
+		Output:  Item{Kind: TeaItem, Count: 1},
+		Ticks:   30,
+		Station: CampfireEType,
+	},
+}
+
+// crafting is a recipe a character started, its inputs are already taken.
+type crafting struct {
+	Recipe *Recipe
+	Done   uint32 // Tick it is ready at.
+}
+
+// countItems is how many of a kind the character has in its backpack.
+func (c *Character) countItems(kind uint16) int {
+	n := 0
+	for _, item := range c.InventoryItems {
+		if item.Kind == kind {
+			n += int(item.Count)
+		}
+	}
+	return n
+}
+
+// removeItems takes count of a kind out of the backpack, emptied stacks are removed.
+// The caller has to check there are enough first.
+func (c *Character) removeItems(kind uint16, count uint16) {
+	kept := c.InventoryItems[:0]
+	for _, item := range c.InventoryItems {
+		if item.Kind == kind && count > 0 {
+			n := item.Count
+			if n > count {
+				n = count
+			}
+			item.Count -= n
+			count -= n
+			if item.Count == 0 {
+				continue
+			}
+		}
+		kept = append(kept, item)
+	}
+	for i := len(kept); i < len(c.InventoryItems); i++ {
+		c.InventoryItems[i] = nil
+	}
+	c.InventoryItems = kept
+}
+
+// nearStation is true if an entity of the station type is within CraftRange of the position.
+func (gw *GameWorld) nearStation(pos physics.Vect2, station uint16) bool {
+	area := quadtree.NewBoundingBox(pos.X-CraftRange, pos.X+CraftRange, pos.Y-CraftRange, pos.Y+CraftRange)
+	for _, body := range gw.Space.Query(area) {
+		if e := gw.Entities[body.ID]; e != nil && e.EType == station {
+			return true
+		}
+	}
+	return false
+}
+
+// Craft starts crafting a recipe if the player's character has the inputs and is near the station.
+// The inputs are taken right away and the output is given by UpdateCrafting once the recipe's time is up.
+func (g *GameSession) Craft(c *Client, tmsg *messages.Craft) {
+	user := g.Clients[c.ID]
+	if user == nil {
+		return
+	}
+	char := user.Accounts[0].Character
+	recipe := Recipes[tmsg.RecipeID]
+	ent := g.World.Entities[char.ID]
+	result := &messages.CraftResult{RecipeID: tmsg.RecipeID}
+	switch {
+	case recipe == nil || ent == nil:
+		result.State = CraftInvalid
+	case user.crafting != nil:
+		result.State = CraftBusy
+	case recipe.Station != 0 && !g.World.nearStation(ent.Body.Position, recipe.Station):
+		result.State = CraftNoStation
+	default:
+		for _, in := range recipe.Inputs {
+			if char.countItems(in.Kind) < int(in.Count) {
+				result.State = CraftMissingItems
+			}
+		}
+	}
+	if result.State != 0 {
+		g.ToNetwork <- NewReliableMsg(c, messages.CraftResultMsgType, result)
+		return
+	}
+
+	for _, in := range recipe.Inputs {
+		char.removeItems(in.Kind, in.Count)
+	}
+	user.crafting = &crafting{Recipe: recipe, Done: g.World.Space.TickID + recipe.Ticks}
+	result.State = CraftStarted
+	result.DoneTick = user.crafting.Done
+	g.ToNetwork <- NewReliableMsg(c, messages.CraftResultMsgType, result)
+	g.sendInventory(user)
+}
+
+// UpdateCrafting finishes every craft whose time is up. Items go in the backpack,
+// or on the ground if there is no room, and stations are built next to the character.
+func (g *GameSession) UpdateCrafting() {
+	now := g.World.Space.TickID
+	for _, user := range g.Clients {
+		if user.crafting == nil || now < user.crafting.Done {
+			continue
+		}
+		recipe := user.crafting.Recipe
+		user.crafting = nil
+		char := user.Accounts[0].Character
+		pos := SpawnPoint
+		if ent := g.World.Entities[char.ID]; ent != nil {
+			pos = ent.Body.Position
+		}
+		result := &messages.CraftResult{RecipeID: recipe.ID, State: CraftDone, DoneTick: now}
+		if recipe.Builds != 0 {
+			result.ItemID = g.build(recipe.Builds, physics.Vect2{X: pos.X + StationSize, Y: pos.Y})
+		} else {
+			item := recipe.Output
+			item.ID = g.nextID()
+			if len(char.InventoryItems) < MaxInventory {
+				char.InventoryItems = append(char.InventoryItems, &item)
+			} else {
+				g.dropItem(&item, pos)
+			}
+			result.ItemID = item.ID
+		}
+		g.ToNetwork <- NewReliableMsg(user.Client, messages.CraftResultMsgType, result)
+		g.sendInventory(user)
+	}
+}
+
+// build adds a station to the world as a fixed body, returns its entity ID.
+func (g *GameSession) build(etype uint16, pos physics.Vect2) uint32 {
+	id := g.nextID()
+	g.invalidateNav(quadtree.NewBoundingBox(pos.X-StationSize/2, pos.X+StationSize/2, pos.Y-StationSize/2, pos.Y+StationSize/2))
+	g.applyNow(func(w *GameWorld) {
+		if _, ok := w.Entities[id]; ok {
+			return
+		}
+		e := &Entity{
+			ID:    id,
+			EType: etype,
+			Body:  &physics.RigidBody{ID: id, Position: pos, Height: StationSize, Width: StationSize},
+		}
+		w.Entities[id] = e
+		w.Space.AddEntity(e.Body, true)
+	})
+	return id
+}
@@ -0,0 +1,67 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/lologarithm/survival/server/messages"
+)
+
+func TestCrafting(t *testing.T) {
+	out := make(chan OutgoingMessage, 100)
+	c := &Client{ID: 1}
+	g, chars := newTestGame(out, c)
+	char := chars[0]
+	char.giveItems(WoodItem, 13, g.nextID)
+	char.giveItems(StoneItem, 3, g.nextID)
+
+	craft := func(id uint32, state byte) *messages.CraftResult {
+		g.Craft(c, &messages.Craft{RecipeID: id})
+		res := drainType(out, c, messages.CraftResultMsgType)
+		if len(res) != 1 || res[0].(*messages.CraftResult).State != state {
+			t.Fatalf("Expected recipe %d to give state %d, got %v", id, state, res)
+		}
+		return res[0].(*messages.CraftResult)
+	}
+	finish := func(done uint32) *messages.CraftResult {
+		for g.World.Space.TickID < done {
+			g.simulate()
+			g.UpdateCrafting()
+			if res := drainType(out, c, messages.CraftResultMsgType); len(res) > 0 {
+				r := res[0].(*messages.CraftResult)
+				if g.World.Space.TickID != done || r.State != CraftDone {
+					t.Fatalf("Craft finished at %d instead of %d: %v", g.World.Space.TickID, done, r)
+				}
+				return r
+			}
+		}
+		t.Fatalf("Craft never finished.")
+		return nil
+	}
+
+	craft(StoneAxeRecipe, CraftNoStation)
+	craft(99, CraftInvalid)
+	started := craft(WorkbenchRecipe, CraftStarted)
+	if char.countItems(WoodItem) != 3 {
+		t.Fatalf("Inputs were not taken, %d wood left.", char.countItems(WoodItem))
+	}
+	craft(ClubRecipe, CraftBusy)
+	bench := finish(started.DoneTick)
+	if e := g.World.Entities[bench.ItemID]; e == nil || e.EType != WorkbenchEType {
+		t.Fatalf("Workbench was not built.")
+	}
+
+	craft(StoneAxeRecipe, CraftMissingItems)
+	char.giveItems(StoneItem, 1, g.nextID)
+	started = craft(StoneAxeRecipe, CraftStarted)
+	if len(char.InventoryItems) != 0 {
+		t.Fatalf("Used up stacks should be removed: %v", char.InventoryItems)
+	}
+	axe := finish(started.DoneTick)
+	if len(char.InventoryItems) != 1 || char.InventoryItems[0].ID != axe.ItemID || char.InventoryItems[0].Kind != StoneAxeItem {
+		t.Fatalf("Expected the axe in the inventory: %v", char.InventoryItems)
+	}
+	g.EquipItem(c, &messages.EquipItem{ItemID: axe.ItemID, Slot: byte(MainHandSlot)})
+	if char.CurrentStats.PhysicalStrength != Kits[FighterKit].PhysicalStrength+6 {
+		t.Fatalf("Axe stats not added: %v", char.CurrentStats)
+	}
+}
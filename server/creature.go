@@ -0,0 +1,273 @@
+package server
+
+import (
+	"encoding/binary"
+	"math"
+	"sort"
+
+	xxhash "github.com/OneOfOne/xxhash/native"
+	"github.com/lologarithm/survival/physics"
+	"github.com/lologarithm/survival/physics/quadtree"
+	"github.com/lologarithm/survival/server/messages"
+)
+
+// Creature behaviors
+const (
+	IdleBehavior   byte = iota // Standing still.
+	WanderBehavior             // Walking somewhere near home.
+	FleeBehavior               // Running away from its target.
+	ChaseBehavior              // Running at its target.
+	AttackBehavior             // Close enough to its target to attack it.
+)
+
+// creatureSalt is mixed into the chunk hash to place creatures, after the chunk objects' salts.
+const creatureSalt = 20
+
+// maxCreatures is the most creatures a chunk can spawn.
+const maxCreatures = 3
+
+// thinkInterval is how many ticks go by between each creature deciding what to do.
+const thinkInterval = 10
+
+// wanderTicks is how long a creature keeps doing the same idle or wander step.
+const wanderTicks = 90
+
+// CreatureKind describes how a kind of creature looks and behaves.
+type CreatureKind struct {
+	EType   uint16
+	Name    string
+	Stats   Stats // Speed is how fast it runs in units/sec.
+	Size    int32
+	Sight   int32  // How far away it notices players.
+	Leash   int32  // How far from where it spawned it wanders before heading back.
+	Hostile bool   // Chases and attacks players it sees, otherwise it runs from them.
+	Attack  uint32 // Ability ID it attacks with.
+}
+
+// Kinds of creatures
+var (
+	RabbitKind = &CreatureKind{EType: RabbitEType, Name: "Rabbit", Stats: Stats{HP: 20, Speed: 80}, Size: 16, Sight: 400, Leash: 800}
+	DeerKind   = &CreatureKind{EType: DeerEType, Name: "Deer", Stats: Stats{HP: 60, Speed: 70}, Size: 40, Sight: 600, Leash: 1500}
+	WolfKind   = &CreatureKind{EType: WolfEType, Name: "Wolf", Stats: Stats{HP: 80, Speed: 45, PhysicalStrength: 4}, Size: 30, Sight: 700, Leash: 2000, Hostile: true, Attack: BiteAbility}
+)
+
+// CreatureKinds are the creatures that spawn in the world, one is picked from each creature's seed.
+var CreatureKinds = []*CreatureKind{RabbitKind, DeerKind, WolfKind}
+
+// Creature is a creature the server controls, kept by the game alongside its entity.
+type Creature struct {
+	ID        uint32
+	Kind      *CreatureKind
+	Character Character // Stats and HP of the creature.
+	Seed      uint64
+	Chunk     ChunkCoord    // Chunk it spawned in, it is removed when that chunk unloads.
+	Home      physics.Vect2 // Where it spawned.
+
+	Behavior   byte
+	Target     uint32 // Entity it is chasing or running from, set when it is attacked.
+	nextAttack uint32 // Tick it can attack again.
+}
+
+// spawnCreatures places the creatures for a chunk. The same seed always places the same
+// kinds of creatures in the same places, only the IDs are new each time.
+func (g *GameSession) spawnCreatures(x, y int32) {
+	c := ChunkCoord{X: x, Y: y}
+	count := g.objectSeed(x, y, creatureSalt, 0) % (maxCreatures + 1)
+	for i := uint32(1); i <= uint32(count); i++ {
+		seed := g.objectSeed(x, y, creatureSalt, i)
+		kind := CreatureKinds[seed%uint64(len(CreatureKinds))]
+		ox, oy := chunkOffset(seed)
+		pos := physics.Vect2{X: x*ChunkSize + ox, Y: y*ChunkSize + oy}
+		g.addCreature(kind, seed, pos, c)
+	}
+}
+
+// addCreature puts a new creature in the world, returns its entity ID.
+func (g *GameSession) addCreature(kind *CreatureKind, seed uint64, pos physics.Vect2, chunk ChunkCoord) uint32 {
+	id := g.nextID()
+	g.creatures[id] = &Creature{
+		ID:        id,
+		Kind:      kind,
+		Character: Character{ID: id, Name: kind.Name, Stats: kind.Stats, CurrentStats: kind.Stats},
+		Seed:      seed,
+		Chunk:     chunk,
+		Home:      pos,
+	}
+	g.applyNow(func(w *GameWorld) {
+		if _, ok := w.Entities[id]; ok {
+			return
+		}
+		e := &Entity{
+			ID:    id,
+			Name:  kind.Name,
+			EType: kind.EType,
+			Seed:  seed,
+			Body:  physics.NewRigidBody(id, kind.Size, kind.Size, pos, physics.Vect2{}, 0, 50),
+		}
+		w.Entities[id] = e
+		w.Space.AddEntity(e.Body, false)
+	})
+	return id
+}
+
+// removeCreatures takes every creature that spawned in the chunk out of the world.
+func (g *GameSession) removeCreatures(c ChunkCoord) {
+	ids := []uint32{}
+	for id, cr := range g.creatures {
+		if cr.Chunk == c {
+			ids = append(ids, id)
+		}
+	}
+	// Remove in a fixed order so replays remove entities the same way.
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	for _, id := range ids {
+		g.removeCreature(id)
+	}
+}
+
+// removeCreature forgets the creature and takes its entity out of the world.
+func (g *GameSession) removeCreature(id uint32) {
+	delete(g.creatures, id)
+	g.applyNow(func(w *GameWorld) {
+		if e := w.Entities[id]; e != nil {
+			w.removeEntity(e)
+		}
+	})
+}
+
+// UpdateCreatures lets each creature decide what to do. Creatures think every thinkInterval
+// ticks, spread out by ID so they don't all think on the same tick.
+func (g *GameSession) UpdateCreatures() {
+	now := g.World.Space.TickID
+	ids := make([]uint32, 0, len(g.creatures))
+	for id := range g.creatures {
+		if (now+id)%thinkInterval == 0 {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	for _, id := range ids {
+		g.think(g.creatures[id])
+	}
+}
+
+// think picks the creature's behavior from what it can see and starts it moving.
+func (g *GameSession) think(cr *Creature) {
+	ent := g.World.Entities[cr.ID]
+	if ent == nil {
+		return
+	}
+	now := g.World.Space.TickID
+	pos := ent.Body.Position
+	target := g.perceive(cr, pos)
+	speed := int32(cr.Character.CurrentStats.Speed)
+	var dir physics.Vect2
+
+	switch {
+	case target != nil && cr.Kind.Hostile:
+		cr.Target = target.ID
+		toward := physics.SubVect2(target.Body.Position, pos)
+		bite := Abilities[cr.Kind.Attack]
+		if toward.Magnitude() > float64(bite.Range) {
+			cr.Behavior = ChaseBehavior
+			dir = g.routeDir(pos, target.Body.Position)
+			break
+		}
+		cr.Behavior = AttackBehavior
+		if now >= cr.nextAttack {
+			cr.nextAttack = now + bite.Cooldown
+			g.creatureAttack(cr, bite, target)
+		}
+	case target != nil:
+		cr.Target = target.ID
+		cr.Behavior = FleeBehavior
+		dir = physics.SubVect2(pos, target.Body.Position)
+	default:
+		cr.Target = 0
+		home := physics.SubVect2(cr.Home, pos)
+		if home.Magnitude() > float64(cr.Kind.Leash) {
+			cr.Behavior = WanderBehavior
+			dir = g.routeDir(pos, cr.Home)
+			break
+		}
+		roll := creatureRoll(cr.Seed, now/wanderTicks)
+		if roll%3 == 0 {
+			cr.Behavior = IdleBehavior
+			break
+		}
+		cr.Behavior = WanderBehavior
+		angle := float64((roll>>8)%360) * math.Pi / 180
+		dir = physics.Vect2{X: int32(math.Cos(angle) * 100), Y: int32(math.Sin(angle) * 100)}
+		speed /= 2
+	}
+	g.moveEntity(now, cr.ID, dir, speed)
+}
+
+// perceive finds what the creature should react to: whatever attacked it while that is within
+// twice its sight, otherwise the closest living player character it can see.
+func (g *GameSession) perceive(cr *Creature, pos physics.Vect2) *Entity {
+	if cr.Target != 0 {
+		if e := g.World.Entities[cr.Target]; e != nil && physics.SubVect2(e.Body.Position, pos).Magnitude() <= float64(2*cr.Kind.Sight) {
+			return e
+		}
+	}
+	sight := cr.Kind.Sight
+	var closest *Entity
+	closestDist := math.Inf(1)
+	for _, body := range g.World.Space.Query(quadtree.NewBoundingBox(pos.X-sight, pos.X+sight, pos.Y-sight, pos.Y+sight)) {
+		user := g.userFor(body.ID)
+		if user == nil || user.respawnAt != 0 {
+			continue
+		}
+		dist := physics.SubVect2(body.Position, pos).Magnitude()
+		if dist <= float64(sight) && (dist < closestDist || (dist == closestDist && body.ID < closest.ID)) {
+			closest, closestDist = g.World.Entities[body.ID], dist
+		}
+	}
+	return closest
+}
+
+// creatureAttack hits the target with the creature's attack and tells everyone who can see it.
+func (g *GameSession) creatureAttack(cr *Creature, ability *Ability, target *Entity) {
+	damage := ability.damage(cr.Character.CurrentStats)
+	result := &messages.AbilityResult{
+		EntityID:  cr.ID,
+		AbilityID: ability.ID,
+		Target:    g.entityMsg(target),
+		Damage:    damage,
+		State:     AbilityHit,
+	}
+	g.damageCharacter(target.ID, cr.ID, damage)
+	g.sendAbilityResult(result, nil)
+}
+
+// damageCreature takes HP from a creature and makes it react to whatever hurt it.
+// A creature that reaches 0 HP dies and is gone until its chunk is generated again.
+func (g *GameSession) damageCreature(cr *Creature, source uint32, damage int32) {
+	cr.Character.CurrentStats.HP -= damage
+	if source != 0 {
+		cr.Target = source
+	}
+	if cr.Character.CurrentStats.HP > 0 {
+		return
+	}
+	cr.Character.CurrentStats.HP = 0
+	died := &messages.CharacterDied{EntityID: cr.ID, KillerID: source}
+	for _, u := range g.Clients {
+		if u.visible[cr.ID] {
+			g.ToNetwork <- NewReliableMsg(u.Client, messages.CharacterDiedMsgType, died)
+		}
+	}
+	g.removeCreature(cr.ID)
+}
+
+// creatureRoll is a random number for a creature at a point in time, the same every time it is asked for.
+func creatureRoll(seed uint64, step uint32) uint64 {
+	tb := make([]byte, 8)
+	h := xxhash.New64()
+	binary.LittleEndian.PutUint64(tb, seed)
+	h.Write(tb)
+	binary.LittleEndian.PutUint32(tb[:4], step)
+	h.Write(tb[:4])
+	return h.Sum64()
+}
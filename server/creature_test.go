@@ -0,0 +1,106 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/lologarithm/survival/physics"
+	"github.com/lologarithm/survival/server/messages"
+)
+
+func TestCreatureSpawning(t *testing.T) {
+	spawned := func(g *GameSession) map[uint64]*Creature {
+		bySeed := map[uint64]*Creature{}
+		for _, cr := range g.creatures {
+			bySeed[cr.Seed] = cr
+		}
+		return bySeed
+	}
+	a, b := NewGame("A", nil, nil, nil), NewGame("B", nil, nil, nil)
+	a.Seed, b.Seed = 10, 10
+	for x := int32(-2); x < 2; x++ {
+		for y := int32(-2); y < 2; y++ {
+			a.SpawnChunk(x, y)
+			b.SpawnChunk(x, y)
+		}
+	}
+	ca, cb := spawned(a), spawned(b)
+	if len(ca) == 0 || len(ca) != len(cb) {
+		t.Fatalf("Expected the same creatures in both games, got %d and %d", len(ca), len(cb))
+	}
+	for seed, cr := range ca {
+		other := cb[seed]
+		if other == nil || other.Kind != cr.Kind || other.Home != cr.Home || ChunkAt(cr.Home) != cr.Chunk {
+			t.Fatalf("Creature spawned differently with the same seed.")
+		}
+		if e := a.World.Entities[cr.ID]; e == nil || e.EType != cr.Kind.EType || e.Body.Position != cr.Home {
+			t.Fatalf("Creature has no entity at its home.")
+		}
+	}
+
+	for x := int32(-2); x < 2; x++ {
+		for y := int32(-2); y < 2; y++ {
+			a.UnloadChunk(x, y)
+		}
+	}
+	if len(a.creatures) != 0 || len(a.World.Entities) != 0 {
+		t.Fatalf("Creatures were left after their chunks unloaded: %d", len(a.creatures))
+	}
+}
+
+func TestCreatureBehavior(t *testing.T) {
+	out := make(chan OutgoingMessage, 100)
+	c := &Client{ID: 1}
+	g, chars := newTestGame(out, c)
+	char := chars[0]
+	player := g.World.Entities[char.ID]
+
+	step := func(cr *Creature) *physics.RigidBody {
+		g.think(cr)
+		g.simulate()
+		return g.World.Entities[cr.ID].Body
+	}
+
+	rabbit := g.creatures[g.addCreature(RabbitKind, 1, physics.Vect2{X: 5200, Y: 5000}, ChunkCoord{})]
+	if body := step(rabbit); rabbit.Behavior != FleeBehavior || body.Velocity.X <= 0 {
+		t.Fatalf("Rabbit should run from the player: %d %v", rabbit.Behavior, body.Velocity)
+	}
+	g.removeCreature(rabbit.ID)
+
+	wolf := g.creatures[g.addCreature(WolfKind, 2, physics.Vect2{X: 5400, Y: 5000}, ChunkCoord{})]
+	if body := step(wolf); wolf.Behavior != ChaseBehavior || body.Velocity.X >= 0 {
+		t.Fatalf("Wolf should chase the player: %d %v", wolf.Behavior, body.Velocity)
+	}
+	body := g.World.Entities[wolf.ID].Body
+	body.Position = physics.Vect2{X: 5050, Y: 5000}
+	g.World.Space.UpdateEntity(body)
+	g.UpdateInterest()
+	drainFor(out, c)
+	step(wolf)
+	bite := Abilities[BiteAbility]
+	if wolf.Behavior != AttackBehavior || char.CurrentStats.HP != Kits[FighterKit].HP-bite.damage(WolfKind.Stats) {
+		t.Fatalf("Wolf should bite the player: %d HP %d", wolf.Behavior, char.CurrentStats.HP)
+	}
+	msgs := drainFor(out, c)
+	if len(msgs) != 1 || msgs[0].NetMsg.(*messages.AbilityResult).EntityID != wolf.ID {
+		t.Fatalf("Expected the player to see the bite: %v", msgs)
+	}
+	step(wolf)
+	if char.CurrentStats.HP != Kits[FighterKit].HP-bite.damage(WolfKind.Stats) {
+		t.Fatalf("Wolf bit again before its cooldown.")
+	}
+
+	// Far from any player it idles or wanders around home.
+	player.Body.Position = physics.Vect2{X: -5000, Y: -5000}
+	g.World.Space.UpdateEntity(player.Body)
+	if step(wolf); wolf.Behavior != IdleBehavior && wolf.Behavior != WanderBehavior {
+		t.Fatalf("Wolf without a target should idle or wander, is %d", wolf.Behavior)
+	}
+
+	g.damageCharacter(wolf.ID, char.ID, WolfKind.Stats.HP)
+	if g.creatures[wolf.ID] != nil || g.World.Entities[wolf.ID] != nil {
+		t.Fatalf("Dead wolf should be removed.")
+	}
+	if msgs := drainFor(out, c); len(msgs) != 1 || msgs[0].NetMsg.(*messages.CharacterDied).KillerID != char.ID {
+		t.Fatalf("Expected the player to see the wolf die: %v", msgs)
+	}
+}
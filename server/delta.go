@@ -0,0 +1,110 @@
+package server
+
+import (
+	"math"
+
+	"github.com/lologarithm/survival/server/messages"
+)
+
+// stateInterval is how many ticks apart state updates are sent to players.
+const stateInterval = 5
+
+// masterInterval is how many ticks apart full master frames are sent to players that
+// have no acknowledged state to build a delta from.
+const masterInterval = 20
+
+// maxSentStates is how many unacknowledged states are kept per player. If a player doesn't
+// acknowledge any of them they get a full master frame again.
+const maxSentStates = 32
+
+// stateHistory keeps the states sent to a player so new states can be sent as deltas
+// from the newest state the player has acknowledged.
+type stateHistory struct {
+	sent  map[uint32]map[uint32]*messages.Entity // Entity state sent at each tick
+	parts map[uint32]int                         // Acks still needed for each state sent over several frames.
+	acked uint32                                 // Newest acknowledged tick, 0 if none.
+}
+
+// base returns the acknowledged state to build a delta from.
+func (sh *stateHistory) base() (uint32, map[uint32]*messages.Entity, bool) {
+	state, ok := sh.sent[sh.acked]
+	return sh.acked, state, ok && sh.acked != 0
+}
+
+// record saves a state that was sent to the player in the given number of frames,
+// forgetting the oldest if there are too many.
+func (sh *stateHistory) record(tick uint32, state map[uint32]*messages.Entity, frames int) {
+	if sh.sent == nil {
+		sh.sent = map[uint32]map[uint32]*messages.Entity{}
+		sh.parts = map[uint32]int{}
+	}
+	sh.sent[tick] = state
+	sh.parts[tick] = frames
+	for len(sh.sent) > maxSentStates {
+		oldest := tick
+		for t := range sh.sent {
+			if t < oldest {
+				oldest = t
+			}
+		}
+		delete(sh.sent, oldest)
+		delete(sh.parts, oldest)
+	}
+}
+
+// ack marks a frame of a state as received by the player. Once every frame of the state is
+// acknowledged older states can't be a base anymore so they are dropped.
+func (sh *stateHistory) ack(tick uint32) {
+	if _, ok := sh.sent[tick]; !ok || tick <= sh.acked {
+		return
+	}
+	if sh.parts[tick]--; sh.parts[tick] > 0 {
+		return
+	}
+	sh.acked = tick
+	for t := range sh.sent {
+		if t < tick {
+			delete(sh.sent, t)
+			delete(sh.parts, t)
+		}
+	}
+}
+
+// SendState sends each player what changed since the last state they acknowledged.
+// Players that haven't acknowledged a state that is still kept get a full master frame
+// every masterInterval ticks instead.
+func (g *GameSession) SendState() {
+	tick := g.World.Space.TickID
+	for _, user := range g.Clients {
+		entities := g.visibleEntities(user)
+		state := make(map[uint32]*messages.Entity, len(entities))
+		for _, e := range entities {
+			state[e.ID] = e
+		}
+		baseTick, base, ok := user.states.base()
+		if ok {
+			df := &messages.GameDeltaFrame{
+				ID:       g.ID,
+				TickID:   tick,
+				BaseTick: baseTick,
+				Deltas:   messages.EntityDeltas(base, state),
+			}
+			if df.Len() <= math.MaxUint16 {
+				g.ToNetwork <- NewOutgoingMsg(user.Client, messages.GameDeltaFrameMsgType, df)
+				user.states.record(tick, state, 1)
+				continue
+			}
+		} else if tick%masterInterval != 0 {
+			continue
+		}
+		// A state split over many frames is only a base once the player acknowledges every frame.
+		user.states.record(tick, state, g.sendMasterFrame(user, entities))
+	}
+}
+
+// AckState is called when a player acknowledges a state update.
+func (g *GameSession) AckState(c *Client, tmsg *messages.FrameAck) {
+	if user := g.Clients[c.ID]; user != nil {
+		user.states.ack(tmsg.TickID)
+	}
+}
@@ -0,0 +1,136 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/lologarithm/survival/physics"
+	"github.com/lologarithm/survival/server/messages"
+)
+
+func TestDeltaState(t *testing.T) {
+	out := make(chan OutgoingMessage, 1000)
+	g := NewGame("A", nil, nil, out)
+	g.Seed = 10
+	c := &Client{ID: 1}
+	g.addPlayer(AddPlayer{Entity: &Entity{Name: "a"}, Character: NewCharacter("a", Stats{}), Client: c})
+	g.StreamChunks()
+	g.UpdateInterest()
+	drainFor(out, c)
+	player := g.World.Entities[g.Clients[1].Accounts[0].Character.ID]
+
+	sendStates := func() []messages.Packet {
+		for i := 0; i < stateInterval; i++ {
+			g.simulate()
+		}
+		g.SendState()
+		return drainFor(out, c)
+	}
+	nextState := func() messages.Packet {
+		msgs := sendStates()
+		if len(msgs) != 1 {
+			t.Fatalf("Expected one state message, got %d", len(msgs))
+		}
+		return msgs[0]
+	}
+	// masterState waits for the next full frame.
+	masterState := func() messages.Packet {
+		for (g.World.Space.TickID+stateInterval)%masterInterval != 0 {
+			g.simulate()
+		}
+		return nextState()
+	}
+
+	// Nothing acknowledged yet, so a full frame.
+	full := masterState()
+	mf, ok := full.NetMsg.(*messages.GameMasterFrame)
+	if !ok {
+		t.Fatalf("Expected first state to be a master frame, got %T", full.NetMsg)
+	}
+	clientState := map[uint32]*messages.Entity{}
+	for _, e := range mf.Entities {
+		clientState[e.ID] = e
+	}
+	// Players without a base only get a full frame every masterInterval ticks.
+	if msgs := sendStates(); len(msgs) != 0 {
+		t.Fatalf("Expected no state before the next full frame, got %d", len(msgs))
+	}
+	g.AckState(c, &messages.FrameAck{GameID: g.ID, TickID: mf.TickID})
+
+	player.Body.Velocity.X = 500
+	delta := nextState()
+	df, ok := delta.NetMsg.(*messages.GameDeltaFrame)
+	if !ok {
+		t.Fatalf("Expected a delta after an ack, got %T", delta.NetMsg)
+	}
+	if df.BaseTick != mf.TickID || df.Len() >= mf.Len() {
+		t.Fatalf("Delta from tick %d is %d bytes, full frame was %d bytes", df.BaseTick, df.Len(), mf.Len())
+	}
+	next, ok := messages.ApplyEntityDeltas(clientState, df.Deltas)
+	if !ok || next[player.ID].X != player.Body.Position.X {
+		t.Fatalf("Delta did not move the player to %v", player.Body.Position)
+	}
+	for id, e := range next {
+		if *e != *g.entityMsg(g.World.Entities[id]) {
+			t.Fatalf("Entity %d doesn't match the server after the delta.", id)
+		}
+	}
+
+	// Without another ack deltas stay based on the acknowledged state.
+	if df := nextState().NetMsg.(*messages.GameDeltaFrame); df.BaseTick != mf.TickID {
+		t.Fatalf("Expected delta from the acked tick %d, got %d", mf.TickID, df.BaseTick)
+	}
+	// Once the acked state is too old to keep the client gets a full frame again.
+	for i := 0; i < maxSentStates; i++ {
+		sendStates()
+	}
+	if _, ok := masterState().NetMsg.(*messages.GameMasterFrame); !ok {
+		t.Fatalf("Expected a full frame after the acked state expired.")
+	}
+}
+
+// TestDeltaStateMultipleFrames checks that a state sent over several master frames is only
+// used as a delta base once every frame is acknowledged.
+func TestDeltaStateMultipleFrames(t *testing.T) {
+	out := make(chan OutgoingMessage, 1000)
+	g := NewGame("A", nil, nil, out)
+	c := &Client{ID: 1}
+	g.addPlayer(AddPlayer{Entity: &Entity{Name: "a"}, Character: NewCharacter("a", Stats{}), Client: c})
+	player := g.World.Entities[g.Clients[1].Accounts[0].Character.ID]
+	// Enough entities in view that the state doesn't fit in one frame.
+	g.applyNow(func(w *GameWorld) {
+		for i := 0; i < 6000; i++ {
+			id := g.nextID()
+			e := &Entity{ID: id, EType: RockEType, Body: physics.NewRigidBody(id, 10, 10, player.Body.Position, physics.Vect2{}, 0, 0)}
+			w.Entities[id] = e
+			w.Space.AddEntity(e.Body, true)
+		}
+	})
+	for g.World.Space.TickID%masterInterval != masterInterval-1 {
+		g.simulate()
+	}
+	g.simulate()
+	g.UpdateInterest()
+	drainFor(out, c)
+	g.SendState()
+	frames := drainFor(out, c)
+	if len(frames) < 2 {
+		t.Fatalf("Expected the state to be split over several frames, got %d", len(frames))
+	}
+	tick := frames[0].NetMsg.(*messages.GameMasterFrame).TickID
+	for i := range frames {
+		if _, _, ok := g.Clients[1].states.base(); ok {
+			t.Fatalf("State was used as a base after %d of %d frames were acknowledged.", i, len(frames))
+		}
+		g.AckState(c, &messages.FrameAck{GameID: g.ID, TickID: tick})
+	}
+	if baseTick, _, ok := g.Clients[1].states.base(); !ok || baseTick != tick {
+		t.Fatalf("Expected the state at %d to be a base once every frame was acknowledged.", tick)
+	}
+	for i := 0; i < stateInterval; i++ {
+		g.simulate()
+	}
+	g.SendState()
+	if msgs := drainFor(out, c); len(msgs) != 1 || msgs[0].NetMsg.(*messages.GameDeltaFrame).BaseTick != tick {
+		t.Fatalf("Expected a single delta from the multi frame state, got %d messages", len(msgs))
+	}
+}
@@ -0,0 +1,91 @@
+package directedPath
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+// BSPGenerator makes rooms joined by corridors. The map is split in two again and again
+// until the parts are too small to split, each part gets a room and the two halves of
+// every split are joined by a corridor.
+type BSPGenerator struct {
+	MinRoom int // Smallest width and height of a room, 4 if 0.
+}
+
+// Generate makes a map of rooms and corridors.
+func (b BSPGenerator) Generate(seed int64, h, w int) (*Map, error) {
+	minRoom := b.MinRoom
+	if minRoom == 0 {
+		minRoom = 4
+	}
+	if minRoom < 1 {
+		return nil, fmt.Errorf("rooms must be at least 1 tile, not %d", minRoom)
+	}
+	// A part needs a tile on each side of its room, and the map needs a tile around every part.
+	leaf := minRoom + 2
+	if err := checkSize(h, w, leaf+2, leaf+2); err != nil {
+		return nil, err
+	}
+	rng := rand.New(rand.NewSource(seed))
+	m := newMap(h, w)
+	m.split(rng, rect{X: 1, Y: 1, W: w - 2, H: h - 2}, leaf, minRoom)
+	m.wallIn()
+	return m, nil
+}
+
+// split fills the area with rooms and corridors, returns a tile in one of its rooms to join it up with.
+func (m *Map) split(rng *rand.Rand, r rect, leaf, minRoom int) (int, int) {
+	// Always split across the longer side so parts stay roughly square.
+	across := r.H > r.W
+	size := r.W
+	if across {
+		size = r.H
+	}
+	if size < 2*leaf {
+		room := rect{W: minRoom + rng.Intn(r.W-leaf+1), H: minRoom + rng.Intn(r.H-leaf+1)}
+		room.X = r.X + 1 + rng.Intn(r.W-room.W-1)
+		room.Y = r.Y + 1 + rng.Intn(r.H-room.H-1)
+		m.fill(Flat, room)
+		return room.X + room.W/2, room.Y + room.H/2
+	}
+
+	at := leaf + rng.Intn(size-2*leaf+1)
+	a, b := r, r
+	if across {
+		a.H = at
+		b.Y += at
+		b.H -= at
+	} else {
+		a.W = at
+		b.X += at
+		b.W -= at
+	}
+	ax, ay := m.split(rng, a, leaf, minRoom)
+	bx, by := m.split(rng, b, leaf, minRoom)
+	m.corridor(rng, ax, ay, bx, by)
+	if rng.Intn(2) == 0 {
+		return ax, ay
+	}
+	return bx, by
+}
+
+// corridor digs an L shaped corridor between two tiles, going either way around the corner.
+func (m *Map) corridor(rng *rand.Rand, ax, ay, bx, by int) {
+	cx, cy := bx, ay
+	if rng.Intn(2) == 0 {
+		cx, cy = ax, by
+	}
+	m.fill(Flat, span(ax, ay, cx, cy))
+	m.fill(Flat, span(cx, cy, bx, by))
+}
+
+// span is the rectangle covering two tiles and everything between them.
+func span(ax, ay, bx, by int) rect {
+	if ax > bx {
+		ax, bx = bx, ax
+	}
+	if ay > by {
+		ay, by = by, ay
+	}
+	return rect{X: ax, Y: ay, W: bx - ax + 1, H: by - ay + 1}
+}
@@ -0,0 +1,119 @@
+package directedPath
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+// CaveGenerator makes caves with a cellular automaton. Tiles start out rock or open at
+// random, then each pass turns a tile to rock when most of its neighbors are rock and
+// opens it when most are open. Only the biggest cave is kept.
+type CaveGenerator struct {
+	Fill  int // Percent of tiles that start as rock, 45 if 0.
+	Steps int // Smoothing passes, 5 if 0.
+}
+
+// Generate makes a map of one cave.
+func (c CaveGenerator) Generate(seed int64, h, w int) (*Map, error) {
+	fill, steps := c.Fill, c.Steps
+	if fill == 0 {
+		fill = 45
+	}
+	if steps == 0 {
+		steps = 5
+	}
+	if fill < 0 || fill >= 100 || steps < 0 {
+		return nil, fmt.Errorf("fill must be 0 to 99 percent and steps at least 0, not %d and %d", fill, steps)
+	}
+	if err := checkSize(h, w, 3, 3); err != nil {
+		return nil, err
+	}
+	rng := rand.New(rand.NewSource(seed))
+
+	// The edge of the map is always rock so the cave is closed in.
+	rock := make([][]bool, h)
+	for y := range rock {
+		rock[y] = make([]bool, w)
+		for x := range rock[y] {
+			rock[y][x] = x == 0 || y == 0 || x == w-1 || y == h-1 || rng.Intn(100) < fill
+		}
+	}
+	for i := 0; i < steps; i++ {
+		next := make([][]bool, h)
+		for y := range next {
+			next[y] = make([]bool, w)
+			for x := range next[y] {
+				if x == 0 || y == 0 || x == w-1 || y == h-1 {
+					next[y][x] = true
+					continue
+				}
+				switch n := rockAround(rock, x, y); {
+				case n > 4:
+					next[y][x] = true
+				case n < 4:
+					next[y][x] = false
+				default:
+					next[y][x] = rock[y][x]
+				}
+			}
+		}
+		rock = next
+	}
+
+	m := newMap(h, w)
+	cave := biggestCave(rock)
+	if len(cave) == 0 {
+		// Everything filled in, leave a single open tile in the middle.
+		cave = [][2]int{{w / 2, h / 2}}
+	}
+	for _, p := range cave {
+		m.Tiles[p[1]][p[0]] = Flat
+	}
+	m.wallIn()
+	return m, nil
+}
+
+// rockAround counts the rock tiles around x,y.
+func rockAround(rock [][]bool, x, y int) int {
+	n := 0
+	for dy := -1; dy <= 1; dy++ {
+		for dx := -1; dx <= 1; dx++ {
+			if (dx != 0 || dy != 0) && rock[y+dy][x+dx] {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// biggestCave returns the x,y of every tile in the largest area of open tiles joined up and down or side to side.
+func biggestCave(rock [][]bool) [][2]int {
+	seen := make([][]bool, len(rock))
+	for y := range seen {
+		seen[y] = make([]bool, len(rock[y]))
+	}
+	var biggest [][2]int
+	for y := range rock {
+		for x := range rock[y] {
+			if rock[y][x] || seen[y][x] {
+				continue
+			}
+			cave := [][2]int{{x, y}}
+			seen[y][x] = true
+			for i := 0; i < len(cave); i++ {
+				cx, cy := cave[i][0], cave[i][1]
+				for _, d := range [4][2]int{{1, 0}, {-1, 0}, {0, 1}, {0, -1}} {
+					nx, ny := cx+d[0], cy+d[1]
+					if !rock[ny][nx] && !seen[ny][nx] {
+						seen[ny][nx] = true
+						cave = append(cave, [2]int{nx, ny})
+					}
+				}
+			}
+			if len(cave) > len(biggest) {
+				biggest = cave
+			}
+		}
+	}
+	return biggest
+}
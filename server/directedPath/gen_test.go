@@ -1,11 +1,81 @@
 package directedPath
 
 import (
+	"flag"
 	"fmt"
+	"io/ioutil"
+	"path/filepath"
 	"testing"
 )
 
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
 func TestGen(t *testing.T) {
-	m := Generate(150, 75)
-	fmt.Print(m.String())
+	for _, seed := range []int64{1, 2, 42} {
+		m, err := Generate(seed, 150, 75)
+		if err != nil {
+			t.Fatal(err)
+		}
+		golden := filepath.Join("testdata", fmt.Sprintf("seed%d_150x75.golden", seed))
+		if *update {
+			if err := ioutil.WriteFile(golden, []byte(m.String()), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		want, err := ioutil.ReadFile(golden)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m.String() != string(want) {
+			t.Fatalf("Map for seed %d doesn't match %s, got:\n%s", seed, golden, m.String())
+		}
+	}
+}
+
+func TestGenSeeds(t *testing.T) {
+	a, _ := Generate(7, 150, 75)
+	b, _ := Generate(7, 150, 75)
+	if a.String() != b.String() {
+		t.Fatalf("Same seed made different maps:\n%s\n%s", a, b)
+	}
+	if c, _ := Generate(8, 150, 75); a.String() == c.String() {
+		t.Fatalf("Different seeds made the same map.")
+	}
+}
+
+func TestGenSizes(t *testing.T) {
+	for _, size := range [][2]int{{0, 0}, {-5, 75}, {MinHeight - 1, 75}, {150, MinWidth - 1}, {MaxHeight + 1, 75}, {150, MaxWidth + 1}} {
+		if m, err := Generate(1, size[0], size[1]); err == nil || m != nil {
+			t.Fatalf("Expected an error for height %d width %d", size[0], size[1])
+		}
+	}
+	for _, size := range [][2]int{{MinHeight, MinWidth}, {MinHeight, 300}, {300, MinWidth}, {64, 64}} {
+		for seed := int64(0); seed < 50; seed++ {
+			m, err := Generate(seed, size[0], size[1])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(m.Tiles) != size[0] || len(m.Tiles[0]) != size[1] {
+				t.Fatalf("Expected a %dx%d map, got %dx%d", size[1], size[0], len(m.Tiles[0]), len(m.Tiles))
+			}
+		}
+	}
+}
+
+func FuzzGenerators(f *testing.F) {
+	f.Add(int64(1), 150, 75)
+	f.Add(int64(2), MinHeight, MinWidth)
+	f.Add(int64(3), 0, 0)
+	f.Fuzz(func(t *testing.T, seed int64, h, w int) {
+		// Keep the sizes small enough to generate quickly, past the limits only the error is checked.
+		if h > 400 && h <= MaxHeight || w > 400 && w <= MaxWidth {
+			return
+		}
+		for _, name := range generatorNames() {
+			m, err := Generators[name].Generate(seed, h, w)
+			if (err == nil) != (m != nil) {
+				t.Fatalf("Expected a map or an error from %s, got %v and %v", name, m, err)
+			}
+		}
+	})
 }
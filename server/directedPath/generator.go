@@ -3,20 +3,28 @@ package directedPath
 import (
 	"math"
 	"math/rand"
-	"time"
 )
 
-// Generate creates a new map.
-func Generate(h, w int) *Map {
-	m := NewMap()
-	m.Tiles = make([][]Tile, h) // 150 tall
-	for idx := range m.Tiles {
-		m.Tiles[idx] = make([]Tile, w) // 75 wide
+// Sizes of maps Generate can make. Paths step at least 10 tiles sideways and 3 up
+// with a tile of wall on every side, anything smaller has no room for them.
+const (
+	MinWidth  = 23
+	MinHeight = 40
+	MaxWidth  = 2000
+	MaxHeight = 2000
+)
+
+// Generate creates a new map. The same seed, height and width always create the same map.
+// Returns an error if the size is outside of MinWidth/MinHeight to MaxWidth/MaxHeight.
+func Generate(seed int64, h, w int) (*Map, error) {
+	if err := checkSize(h, w, MinHeight, MinWidth); err != nil {
+		return nil, err
 	}
-	rand.Seed(time.Now().UnixNano())
-	// Setup starting room
-	startX := rand.Intn(65) + 5
-	startY := rand.Intn(25) + 5
+	rng := rand.New(rand.NewSource(seed))
+	m := newMap(h, w)
+	// Setup starting room, 11x11 somewhere in the bottom of the map.
+	startX := rng.Intn(w-10) + 5
+	startY := rng.Intn(h/6) + 5
 
 	for x := startX - 5; x <= startX+5; x++ {
 		m.Tiles[startY-5][x] = Wall
@@ -41,7 +49,7 @@ func Generate(h, w int) *Map {
 			break
 		}
 		// 1. pick random direction & length
-		dir := rand.Intn(w-20) + 10
+		dir := rng.Intn(w-20) + 10
 		if newX+dir < w-1 {
 			newX += dir
 		} else if newX-dir > 1 {
@@ -51,7 +59,7 @@ func Generate(h, w int) *Map {
 			continue
 		}
 
-		newY += rand.Intn((h/10)-3) + 3
+		newY += rng.Intn((h/10)-3) + 3
 		// Now we make a 'path' from startX/Y to newX/Y
 		angle := math.Atan2(float64(newY-startY), float64(newX-startX))
 		flen := math.Sqrt(math.Pow(float64(dir), 2) + math.Pow(float64(newY-startY), 2))
@@ -85,7 +93,7 @@ func Generate(h, w int) *Map {
 		startY = newY
 	}
 
-	return m
+	return m, nil
 }
 
 func (m *Map) setIfEmpty(t Tile, x, y int) {
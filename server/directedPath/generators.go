@@ -0,0 +1,74 @@
+package directedPath
+
+import "fmt"
+
+// Generator makes maps. Every generator makes the same map from the same seed, height and width,
+// and returns an error instead of a map for sizes it can't fill.
+type Generator interface {
+	Generate(seed int64, h, w int) (*Map, error)
+}
+
+// Generators by name, with their default settings.
+var Generators = map[string]Generator{
+	"path": PathGenerator{},
+	"bsp":  BSPGenerator{},
+	"cave": CaveGenerator{},
+	"walk": WalkGenerator{},
+}
+
+// PathGenerator makes the zig-zag path of Generate, starting in a room at the bottom of the map.
+type PathGenerator struct{}
+
+// Generate calls Generate.
+func (PathGenerator) Generate(seed int64, h, w int) (*Map, error) {
+	return Generate(seed, h, w)
+}
+
+// checkSize returns an error if the size is smaller than the minimum or bigger than MaxWidth/MaxHeight.
+func checkSize(h, w, minH, minW int) error {
+	if w < minW || w > MaxWidth || h < minH || h > MaxHeight {
+		return fmt.Errorf("can't generate a %dx%d map, width must be %d to %d and height %d to %d", w, h, minW, MaxWidth, minH, MaxHeight)
+	}
+	return nil
+}
+
+// newMap makes an h tall, w wide map of empty tiles.
+func newMap(h, w int) *Map {
+	m := NewMap()
+	m.Tiles = make([][]Tile, h)
+	for idx := range m.Tiles {
+		m.Tiles[idx] = make([]Tile, w)
+	}
+	return m
+}
+
+// fill sets every tile in the rectangle.
+func (m *Map) fill(t Tile, r rect) {
+	for y := r.Y; y < r.Y+r.H; y++ {
+		for x := r.X; x < r.X+r.W; x++ {
+			m.Tiles[y][x] = t
+		}
+	}
+}
+
+// wallIn puts a wall on every empty tile next to a flat one, including diagonally.
+// Flat tiles are never on the edge of the map so every wall fits.
+func (m *Map) wallIn() {
+	for y := 1; y < len(m.Tiles)-1; y++ {
+		for x := 1; x < len(m.Tiles[y])-1; x++ {
+			if !m.Tiles[y][x].Walkable() {
+				continue
+			}
+			for dy := -1; dy <= 1; dy++ {
+				for dx := -1; dx <= 1; dx++ {
+					m.setIfEmpty(Wall, x+dx, y+dy)
+				}
+			}
+		}
+	}
+}
+
+// rect is an area of a map, X and Y are its bottom left tile.
+type rect struct {
+	X, Y, W, H int
+}
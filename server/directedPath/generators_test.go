@@ -0,0 +1,113 @@
+package directedPath
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+// generatorNames are the names in Generators in a fixed order.
+func generatorNames() []string {
+	names := make([]string, 0, len(Generators))
+	for name := range Generators {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// checkMap fails if the map has flat tiles on its edge or next to empty tiles.
+func checkMap(t *testing.T, name string, m *Map) {
+	for y := range m.Tiles {
+		for x, tile := range m.Tiles[y] {
+			if !tile.Walkable() {
+				continue
+			}
+			if x == 0 || y == 0 || y == len(m.Tiles)-1 || x == len(m.Tiles[y])-1 {
+				t.Fatalf("%s put a flat tile on the edge at %d,%d:\n%s", name, x, y, m)
+			}
+			for dy := -1; dy <= 1; dy++ {
+				for dx := -1; dx <= 1; dx++ {
+					if m.Tiles[y+dy][x+dx] == Empty {
+						t.Fatalf("%s left %d,%d open to the outside:\n%s", name, x, y, m)
+					}
+				}
+			}
+		}
+	}
+}
+
+// connected is true if every flat tile can be reached from every other going up, down or side to side.
+func connected(m *Map) bool {
+	rock := make([][]bool, len(m.Tiles))
+	flat := 0
+	for y := range m.Tiles {
+		rock[y] = make([]bool, len(m.Tiles[y]))
+		for x, tile := range m.Tiles[y] {
+			rock[y][x] = !tile.Walkable()
+			if !rock[y][x] {
+				flat++
+			}
+		}
+	}
+	return flat > 0 && len(biggestCave(rock)) == flat
+}
+
+func TestGenerators(t *testing.T) {
+	for _, name := range generatorNames() {
+		gen := Generators[name]
+		m, err := gen.Generate(1, 40, 60)
+		if err != nil {
+			t.Fatal(err)
+		}
+		golden := filepath.Join("testdata", fmt.Sprintf("%s_seed1_60x40.golden", name))
+		if *update {
+			if err := ioutil.WriteFile(golden, []byte(m.String()), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		want, err := ioutil.ReadFile(golden)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m.String() != string(want) {
+			t.Fatalf("%s map doesn't match %s, got:\n%s", name, golden, m)
+		}
+
+		if _, err := gen.Generate(1, 0, 60); err == nil {
+			t.Fatalf("%s should fail to make a map with no height", name)
+		}
+		if name == "path" {
+			continue // Its paths can meet only at the corners.
+		}
+		for seed := int64(0); seed < 20; seed++ {
+			for _, size := range [][2]int{{8, 8}, {20, 70}, {70, 20}, {100, 100}} {
+				m, err := gen.Generate(seed, size[0], size[1])
+				if err != nil {
+					t.Fatal(err)
+				}
+				checkMap(t, name, m)
+				if !connected(m) {
+					t.Fatalf("%s made a map that isn't all joined up:\n%s", name, m)
+				}
+			}
+		}
+	}
+}
+
+func TestGeneratorSettings(t *testing.T) {
+	for _, gen := range []Generator{BSPGenerator{MinRoom: -1}, CaveGenerator{Fill: 100}, CaveGenerator{Steps: -1}, WalkGenerator{Cover: 95}} {
+		if _, err := gen.Generate(1, 50, 50); err == nil {
+			t.Fatalf("Expected an error from %#v", gen)
+		}
+	}
+	if _, err := (BSPGenerator{MinRoom: 10}).Generate(1, 13, 50); err == nil {
+		t.Fatalf("Map should be too small for a room of 10")
+	}
+	m, err := CaveGenerator{Fill: 99}.Generate(1, 20, 20)
+	if err != nil || !connected(m) {
+		t.Fatalf("A cave that fills in should still have an open tile: %v\n%s", err, m)
+	}
+}
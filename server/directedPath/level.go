@@ -26,6 +26,11 @@ func (t Tile) String() string {
 	return "?"
 }
 
+// Walkable is true for tiles something can stand on.
+func (t Tile) Walkable() bool {
+	return t == Flat || t == Flat2 || t == Flat3
+}
+
 type Map struct {
 	Tiles [][]Tile
 }
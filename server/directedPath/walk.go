@@ -0,0 +1,63 @@
+package directedPath
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+// WalkGenerator digs a map with a drunkard's walk. Starting in the middle it steps
+// in a random direction and digs out every tile it steps on until enough are dug.
+type WalkGenerator struct {
+	Cover int // Percent of the inside of the map to dig out, 40 if 0.
+}
+
+// Generate makes a map of winding tunnels and caverns.
+func (d WalkGenerator) Generate(seed int64, h, w int) (*Map, error) {
+	cover := d.Cover
+	if cover == 0 {
+		cover = 40
+	}
+	// Digging the last few tiles of a map takes a very long walk.
+	if cover < 0 || cover > 90 {
+		return nil, fmt.Errorf("cover must be 0 to 90 percent, not %d", cover)
+	}
+	if err := checkSize(h, w, 3, 3); err != nil {
+		return nil, err
+	}
+	rng := rand.New(rand.NewSource(seed))
+	m := newMap(h, w)
+
+	// Walk inside of the edge so there is room for walls.
+	want := (w - 2) * (h - 2) * cover / 100
+	x, y := w/2, h/2
+	m.Tiles[y][x] = Flat
+	for dug := 1; dug < want; {
+		switch rng.Intn(4) {
+		case 0:
+			x++
+		case 1:
+			x--
+		case 2:
+			y++
+		default:
+			y--
+		}
+		x, y = clamp(x, 1, w-2), clamp(y, 1, h-2)
+		if m.Tiles[y][x] != Flat {
+			m.Tiles[y][x] = Flat
+			dug++
+		}
+	}
+	m.wallIn()
+	return m, nil
+}
+
+func clamp(v, min, max int) int {
+	if v < min {
+		return min
+	}
+	if v > max {
+		return max
+	}
+	return v
+}
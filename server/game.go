@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"math"
 	"math/rand"
+	"sort"
+	"sync/atomic"
 	"time"
 
 	xxhash "github.com/OneOfOne/xxhash/native"
@@ -15,6 +17,36 @@ import (
 // ChunkSize is how big in 'units' each chunk is.
 const ChunkSize int32 = 10000
 
+// Chunks within ChunkLoadRadius chunks of a player are generated, and chunks further
+// than ChunkUnloadRadius from every player are unloaded. The gap keeps chunks from
+// loading and unloading over and over when a player walks back and forth over a border.
+const (
+	ChunkLoadRadius   int32 = 1
+	ChunkUnloadRadius int32 = 2
+)
+
+// ChunkCoord is the position of a chunk, chunk (0,0) covers 0 to ChunkSize on both axes.
+type ChunkCoord struct {
+	X, Y int32
+}
+
+// ChunkAt returns the chunk a position is in.
+func ChunkAt(p physics.Vect2) ChunkCoord {
+	return ChunkCoord{X: floorDiv(p.X, ChunkSize), Y: floorDiv(p.Y, ChunkSize)}
+}
+
+// floorDiv divides rounding down instead of toward zero so negative positions land in negative chunks.
+func floorDiv(a, b int32) int32 {
+	q := a / b
+	if (a%b != 0) && ((a < 0) != (b < 0)) {
+		q--
+	}
+	return q
+}
+
+// MaxPlayers is how many users can be in a single game.
+const MaxPlayers = 16
+
 // Entity type constants
 const (
 	UnknownEType uint16 = iota
@@ -23,6 +55,12 @@ const (
 	TreeEType
 	CreatureEType
 	ProjectileEType
+	ItemEType
+	WorkbenchEType
+	CampfireEType
+	RabbitEType
+	DeerEType
+	WolfEType
 )
 
 // GameSession represents a single game
@@ -34,98 +72,138 @@ type GameSession struct {
 	// map character ID to client
 	Clients map[uint32]*User
 
-	IntoGameManager chan<- GameMessage     // Game can only write to this channel, not read.
+	IntoGameManager chan<- InternalMessage // Game can only write to this channel, not read.
 	FromGameManager chan InternalMessage   // Messages from the game Manager.
 	FromNetwork     <-chan GameMessage     // FromNetwork is read only here, messages from players.
+	toGame          chan<- GameMessage     // Write side of FromNetwork, handed to clients in this game.
 	ToNetwork       chan<- OutgoingMessage // Messages to players!
 
-	Exit   chan int
-	Status GameStatus
+	Exit       chan int
+	status     uint32 // GameStatus, only access atomically since the manager reads it while the game runs.
+	ViewRadius int32  // How far from their character players can see entities.
 
 	// Private
-	World          *GameWorld    // Current world state
-	prevWorlds     []*GameWorld  // Last X seconds of game states
-	commandHistory []interface{} // Last X seconds of commands
+	World          *GameWorld               // Current world state
+	prevWorlds     []*GameWorld             // Ring buffer of world states at the start of each of the last historyTicks ticks
+	commandHistory []tickCommand            // Commands for the ticks still in prevWorlds
+	rewindTo       uint32                   // Oldest tick that got a new command since the last simulate
+	lastID         uint32                   // Last entity ID handed out by nextID
+	appliedHits    map[uint32]uint32        // Projectile ID to the tick of hits already applied
+	depleted       map[uint64]*depletion    // Seed of harvested nodes to how much has been taken
+	creatures      map[uint32]*Creature     // Creatures in the world by entity ID
+	nav            map[ChunkCoord]*chunkNav // Navigation grids of chunks creatures have found paths in
 }
 
 // GameWorld represents all the data in the world.
 // Physical entities and the physics simulation.
 type GameWorld struct {
 	Entities map[uint32]*Entity
-	Chunks   map[uint32]map[uint32]bool // list of chunks that have been already created.
+	Chunks   map[ChunkCoord][]uint32 // Chunks that are loaded, with the IDs of the entities generated in each.
 	Space    *physics.SimulatedSpace
 }
 
-// Clone returns a deep copy of the game world at this time.
+// Clone returns a deep copy of the game world at this time, use it to take a snapshot.
+// Entities in the copy share bodies with the copied space, same as the original.
 func (gw *GameWorld) Clone() *GameWorld {
-	// TODO: make this work.
-	return &GameWorld{}
+	space, bodies := gw.Space.Clone()
+	ngw := &GameWorld{
+		Entities: make(map[uint32]*Entity, len(gw.Entities)),
+		Chunks:   make(map[ChunkCoord][]uint32, len(gw.Chunks)),
+		Space:    space,
+	}
+	for id, e := range gw.Entities {
+		body, ok := bodies[e.Body]
+		if !ok {
+			// Fixed bodies are shared between worlds and never changed, so their entities can be too.
+			ngw.Entities[id] = e
+			continue
+		}
+		ne := *e
+		ne.Body = body
+		ngw.Entities[id] = &ne
+	}
+	for c, ids := range gw.Chunks {
+		ngw.Chunks[c] = ids // Never modified once the chunk is loaded so safe to share.
+	}
+	return ngw
+}
+
+// Restore replaces this world with a copy of the snapshot.
+// The snapshot is left untouched so it can be restored again.
+func (gw *GameWorld) Restore(snap *GameWorld) {
+	*gw = *snap.Clone()
 }
 
 // EntitiesMsg converts all entities in the world to a network message.
 func (gw *GameWorld) EntitiesMsg() []*messages.Entity {
-	es := make([]*messages.Entity, len(gw.Entities))
-	for idx, e := range gw.Entities {
-		es[idx] = e.toMsg()
+	es := make([]*messages.Entity, 0, len(gw.Entities))
+	for _, e := range gw.Entities {
+		es = append(es, e.toMsg())
 	}
+	sort.Slice(es, func(i, j int) bool { return es[i].ID < es[j].ID })
 
 	return es
 }
 
+// Status is whether the game is running. Safe to call while the game runs.
+func (g *GameSession) Status() GameStatus {
+	return GameStatus(atomic.LoadUint32(&g.status))
+}
+
+// setStatus is set by the manager when the game starts and by the game itself
+// before its loop stops, so nobody joins a game that isn't running anymore.
+func (g *GameSession) setStatus(s GameStatus) {
+	atomic.StoreUint32(&g.status, uint32(s))
+}
+
 // Run starts the game!
 func (g *GameSession) Run() {
-	waiting := true
 	for {
-		timeout := time.Millisecond * 33
-		waiting = true
+		timeout := time.After(time.Millisecond * 33)
+		waiting := true
 		for waiting {
 			select {
-			case <-time.After(timeout):
+			case <-timeout:
 				waiting = false
 				break
 			case msg := <-g.FromNetwork:
 				switch msg.mtype {
 				case messages.MovePlayerMsgType:
 					g.MoveEntity(msg.client, msg.net.(*messages.MovePlayer))
+				case messages.UseAbilityMsgType:
+					g.UseAbility(msg.client, msg.net.(*messages.UseAbility))
+				case messages.FrameAckMsgType:
+					g.AckState(msg.client, msg.net.(*messages.FrameAck))
+				case messages.PickupItemMsgType:
+					g.PickupItem(msg.client, msg.net.(*messages.PickupItem))
+				case messages.DropItemMsgType:
+					g.DropItem(msg.client, msg.net.(*messages.DropItem))
+				case messages.EquipItemMsgType:
+					g.EquipItem(msg.client, msg.net.(*messages.EquipItem))
+				case messages.UnequipItemMsgType:
+					g.UnequipItem(msg.client, msg.net.(*messages.UnequipItem))
+				case messages.HarvestMsgType:
+					g.Harvest(msg.client, msg.net.(*messages.Harvest))
+				case messages.CraftMsgType:
+					g.Craft(msg.client, msg.net.(*messages.Craft))
+				case messages.ConsumeMsgType:
+					g.Consume(msg.client, msg.net.(*messages.Consume))
 				default:
 					fmt.Printf("game.go:Run(): UNKNOWN MESSAGE TYPE: %T\n", msg)
 				}
 			case imsg := <-g.FromGameManager:
 				switch timsg := imsg.(type) {
 				case AddPlayer:
-					newid := uint32(len(g.World.Entities))
-					player := &Entity{
-						ID:    newid,
-						Name:  timsg.Entity.Name,
-						EType: CreatureEType,
-						Seed:  timsg.Entity.Seed,
-						Body:  physics.NewRigidBody(newid, 22, 46, physics.Vect2{X: 5000, Y: 5000}, physics.Vect2{}, 0, 100),
-					}
-					g.World.Space.AddEntity(player.Body, false)
-					g.World.Entities[newid] = player
-					g.Clients[timsg.Client.ID] = &User{
-						Client: timsg.Client,
-						Accounts: []*Account{
-							{
-								Character: &Character{
-									ID: newid,
-								},
-							},
-						},
-					}
+					g.addPlayer(timsg)
 				case RemovePlayer:
-					id := g.Clients[timsg.Client.ID].Accounts[0].Character.ID
-					ent := g.World.Entities[id]
-					if ent == nil {
-						return
+					if !g.removePlayer(timsg) {
+						break
 					}
-					// TODO: remove player from game after timeout?
-					g.World.Space.RemoveEntity(ent.Body, false)
-					delete(g.Clients, timsg.Client.ID)
 					if len(g.Clients) == 0 {
 						fmt.Printf("All clients disconnected, closing game %d.", g.ID)
+						g.setStatus(EndedStatus)
 						g.IntoGameManager <- GameMessage{
-							net:   &messages.EndGame{},
+							net:   &messages.EndGame{GameID: g.ID},
 							mtype: messages.EndGameMsgType,
 						}
 						return
@@ -133,196 +211,346 @@ func (g *GameSession) Run() {
 				}
 			case <-g.Exit:
 				fmt.Print("EXITING: Run in Game.go\n")
+				g.setStatus(EndedStatus)
 				return
 			}
 		}
-		collisions := g.World.Space.Tick(true)
-		for _, col := range collisions {
-			var ent *Entity
-			for _, e := range g.World.Entities {
-				if e.ID == col.Body.ID {
-					ent = e
-					break
-				}
-			}
-			if ent.EType == ProjectileEType {
-				// TODO: remove the projectile
-				// TODO: resolve hit to target.
-			}
+		g.StreamChunks()
+		g.applyHits(g.simulate())
+		g.UpdateCharacters()
+		g.UpdateNeeds()
+		g.UpdateCreatures()
+		g.RegrowResources()
+		g.UpdateCrafting()
+		g.UpdateInterest()
+		if g.World.Space.TickID%stateInterval == 0 {
+			g.SendState()
 		}
-		if g.World.Space.TickID%20 == 0 {
-			g.SendMasterFrame()
+	}
+}
+
+func (g *GameSession) addPlayer(timsg AddPlayer) {
+	newid := g.nextID()
+	name := timsg.Entity.Name
+	seed := timsg.Entity.Seed
+	g.applyNow(spawnCharacter(newid, name, seed))
+	char := timsg.Character
+	char.ID = newid
+	if char.CurrentStats.HP <= 0 {
+		// Left while dead, comes back like after a respawn.
+		char.CurrentStats = char.MaxStats()
+		char.Needs = FullNeeds
+	}
+	// Item IDs are only unique within a game, the ones brought in get new ones.
+	for _, it := range char.EquippedItems {
+		if it != nil {
+			it.ID = g.nextID()
 		}
 	}
+	for _, it := range char.InventoryItems {
+		it.ID = g.nextID()
+	}
+	user := &User{
+		Client:   timsg.Client,
+		Accounts: []*Account{{ID: timsg.AccountID, Character: char}},
+	}
+	g.Clients[timsg.Client.ID] = user
+	if timsg.Join {
+		// GameConnected has everything in view so there is nothing to announce in the next interest update.
+		user.visible = g.visibleTo(user)
+		g.ToNetwork <- NewReliableMsg(timsg.Client, messages.GameConnectedMsgType, &messages.GameConnected{
+			ID:       g.ID,
+			Seed:     g.Seed,
+			Entities: g.visibleEntities(user),
+		})
+		g.sendInventory(user)
+	}
 }
 
-// SendMasterFrame will create a 'master' state of all things and send to each client.
-func (g *GameSession) SendMasterFrame() {
-	mf := &messages.GameMasterFrame{
-		ID:       g.ID,
-		Entities: g.World.EntitiesMsg(),
+// removePlayer takes the player's character out of the world and sends it back to the
+// manager to be saved. Returns false if the client wasn't in the game.
+func (g *GameSession) removePlayer(timsg RemovePlayer) bool {
+	user := g.Clients[timsg.Client.ID]
+	if user == nil {
+		return false
 	}
+	// TODO: remove player from game after timeout?
+	acct := user.Accounts[0]
+	id := acct.Character.ID
+	g.applyNow(func(w *GameWorld) {
+		if ent := w.Entities[id]; ent != nil {
+			w.Space.RemoveEntity(ent.Body, false)
+			delete(w.Entities, id)
+		}
+	})
+	delete(g.Clients, timsg.Client.ID)
+	g.IntoGameManager <- SaveCharacter{AccountID: acct.ID, Character: acct.Character}
+	return true
+}
 
-	frame := messages.Frame{
-		MsgType:       messages.GameMasterFrameMsgType,
-		Seq:           1,
-		ContentLength: uint16(mf.Len()),
+// SendMasterFrame will create a 'master' state of everything each client can see and send it to them.
+func (g *GameSession) SendMasterFrame() {
+	for _, user := range g.Clients {
+		g.sendMasterFrame(user, g.visibleEntities(user))
 	}
-	msg := OutgoingMessage{
-		msg: messages.Packet{
-			Frame:  frame,
-			NetMsg: mf,
-		},
+}
+
+// sendMasterFrame sends the entities to the user, returns how many frames it took.
+func (g *GameSession) sendMasterFrame(user *User, entities []*messages.Entity) int {
+	frames := masterFrames(g.ID, g.World.Space.TickID, entities)
+	for _, mf := range frames {
+		g.ToNetwork <- NewOutgoingMsg(user.Client, messages.GameMasterFrameMsgType, mf)
 	}
+	return len(frames)
+}
 
-	for _, c := range g.Clients {
-		msg.dest = c.Client
-		g.ToNetwork <- msg
+// masterFrames splits entities across as many master frames as needed so that
+// each frame's content length still fits in the frame header.
+func masterFrames(id uint32, tick uint32, entities []*messages.Entity) []*messages.GameMasterFrame {
+	frames := []*messages.GameMasterFrame{}
+	for _, part := range splitEntities(entities, (&messages.GameMasterFrame{}).Len()) {
+		frames = append(frames, &messages.GameMasterFrame{ID: id, TickID: tick, Entities: part})
 	}
+	return frames
 }
 
 // MoveEntity is used to move players from a movement message.
+// The move is applied at the tick the client sent it at.
 func (g *GameSession) MoveEntity(c *Client, tmsg *messages.MovePlayer) {
-	// TODO: go back in time and apply at tick!
-	id := g.Clients[c.ID].Accounts[0].Character.ID
-	ent := g.World.Entities[id]
-	if ent == nil {
+	user := g.Clients[c.ID]
+	if user == nil {
 		return
 	}
+	id := user.Accounts[0].Character.ID
 	dirVect := physics.Vect2{
 		X: int32(tmsg.X),
 		Y: int32(tmsg.Y),
 	}
-	ent.Body.Angle = physics.AngleVect2(dirVect, physics.Vect2{X: 0, Y: 1})
-	// TODO: Replace hardcoded 50 with 'speed' setting of the character.
-	ent.Body.Velocity = physics.NormalizeVect2(dirVect, 50)
+	g.moveEntity(tmsg.TickID, id, dirVect, int32(user.Accounts[0].Character.CurrentStats.Speed))
+}
+
+// moveEntity sets the entity moving toward dir at speed units/sec from the given tick, a zero dir stops it.
+func (g *GameSession) moveEntity(tick uint32, id uint32, dir physics.Vect2, speed int32) {
+	g.queueCommand(tick, func(w *GameWorld) {
+		ent := w.Entities[id]
+		if ent == nil {
+			return
+		}
+		if dir.X == 0 && dir.Y == 0 {
+			ent.Body.Velocity = physics.Vect2{}
+			return
+		}
+		ent.Body.Angle = physics.AngleVect2(dir, physics.Vect2{X: 0, Y: 1})
+		ent.Body.Velocity = physics.NormalizeVect2(dir, speed)
+	})
 }
 
+// chunkObject is a kind of obstacle that is scattered around each chunk.
+type chunkObject struct {
+	EType  uint16
+	Salt   uint32 // Mixed into each object's hash so each kind lands in different spots.
+	Size   int32
+	Growth int32 // How much an object grows when another of the same kind lands on it.
+}
+
+var (
+	rockObject = chunkObject{EType: RockEType, Salt: 10, Size: 10, Growth: 7}
+	bushObject = chunkObject{EType: BushEType, Salt: 11, Size: 40, Growth: 25}
+	treeObject = chunkObject{EType: TreeEType, Salt: 12, Size: 100, Growth: 75}
+)
+
 // SpawnChunk creates all the entities for a chunk at the given x/y
-func (g *GameSession) SpawnChunk(x, y uint32) {
+// and adds them to the world as fixed bodies, then spawns the chunk's creatures.
+// Spawning a chunk that already exists does nothing.
+func (g *GameSession) SpawnChunk(x, y int32) {
+	c := ChunkCoord{X: x, Y: y}
+	if _, ok := g.World.Chunks[c]; ok {
+		return
+	}
+	entities := g.generateChunk(x, y)
+	ids := make([]uint32, len(entities))
+	for i, e := range entities {
+		ids[i] = e.ID
+	}
+	// IDs of used up nodes are kept in the chunk so they can grow back with them.
+	entities = g.applyDepletion(entities)
+	g.applyNow(func(w *GameWorld) {
+		if _, ok := w.Chunks[c]; ok {
+			return
+		}
+		for _, e := range entities {
+			ne := *e
+			body := *e.Body
+			ne.Body = &body
+			w.Entities[ne.ID] = &ne
+			w.Space.AddEntity(ne.Body, true)
+		}
+		w.Chunks[c] = ids
+	})
+	g.invalidateChunkNav(c)
+	g.spawnCreatures(x, y)
+}
+
+// UnloadChunk removes everything that was generated for a chunk from the world, including its creatures.
+// The chunk is generated again the same way if it is spawned later.
+func (g *GameSession) UnloadChunk(x, y int32) {
+	c := ChunkCoord{X: x, Y: y}
+	if _, ok := g.World.Chunks[c]; !ok {
+		return
+	}
+	g.removeCreatures(c)
+	g.applyNow(func(w *GameWorld) {
+		for _, id := range w.Chunks[c] {
+			if e := w.Entities[id]; e != nil {
+				w.Space.RemoveEntity(e.Body, true)
+				delete(w.Entities, id)
+			}
+		}
+		delete(w.Chunks, c)
+	})
+	delete(g.nav, c)
+	g.invalidateChunkNav(c)
+}
+
+// StreamChunks spawns every chunk within ChunkLoadRadius of a player and unloads
+// chunks that are further than ChunkUnloadRadius from all players.
+func (g *GameSession) StreamChunks() {
+	near := map[ChunkCoord]bool{}
+	for _, user := range g.Clients {
+		ent := g.World.Entities[user.Accounts[0].Character.ID]
+		if ent == nil {
+			continue
+		}
+		center := ChunkAt(ent.Body.Position)
+		for x := center.X - ChunkUnloadRadius; x <= center.X+ChunkUnloadRadius; x++ {
+			for y := center.Y - ChunkUnloadRadius; y <= center.Y+ChunkUnloadRadius; y++ {
+				near[ChunkCoord{X: x, Y: y}] = true
+			}
+		}
+		for x := center.X - ChunkLoadRadius; x <= center.X+ChunkLoadRadius; x++ {
+			for y := center.Y - ChunkLoadRadius; y <= center.Y+ChunkLoadRadius; y++ {
+				g.SpawnChunk(x, y)
+			}
+		}
+	}
+	if len(near) == 0 {
+		return // Nobody to stream for, keep the world as is.
+	}
+	far := []ChunkCoord{}
+	for c := range g.World.Chunks {
+		if !near[c] {
+			far = append(far, c)
+		}
+	}
+	// Unload in a fixed order so replays remove entities the same way.
+	sort.Slice(far, func(i, j int) bool {
+		return far[i].X < far[j].X || (far[i].X == far[j].X && far[i].Y < far[j].Y)
+	})
+	for _, c := range far {
+		g.UnloadChunk(c.X, c.Y)
+	}
+}
+
+// generateChunk creates the entities for a chunk from the game seed. The same seed always
+// creates the same objects in the same places, only the IDs are new each time.
+func (g *GameSession) generateChunk(x, y int32) []*Entity {
 	h := xxhash.New64()
 	tb := make([]byte, 8)
 
 	binary.LittleEndian.PutUint64(tb[:8], g.Seed)
 	h.Write(tb[:8])
-	binary.LittleEndian.PutUint32(tb[:4], x)
+	binary.LittleEndian.PutUint32(tb[:4], uint32(x))
 	h.Write(tb[:4])
-	binary.LittleEndian.PutUint32(tb[:4], y)
+	binary.LittleEndian.PutUint32(tb[:4], uint32(y))
 	h.Write(tb[:4])
 	binary.LittleEndian.PutUint32(tb[:4], 1)
 	h.Write(tb[:4])
 
 	chunkSeed := h.Sum64()
 	numRocks := chunkSeed >> 60
-	// numBush := (chunkSeed << 4) >> 60
+	numBush := (chunkSeed << 4) >> 60
 	numTrees := (chunkSeed << 8) >> 56
 
-	for i := 0; i < int(numRocks); i++ {
-		oh := xxhash.New64() // (worldseed, chunkX, chunkY, 10, rock#)
-		binary.LittleEndian.PutUint64(tb[:8], g.Seed)
-		oh.Write(tb[:8])
-
-		binary.LittleEndian.PutUint32(tb[:4], x)
-		oh.Write(tb[:4])
-		binary.LittleEndian.PutUint32(tb[:4], y)
-		oh.Write(tb[:4])
-		binary.LittleEndian.PutUint32(tb[:4], 10)
-		oh.Write(tb[:4])
-		binary.LittleEndian.PutUint32(tb[:4], uint32(i))
-		oh.Write(tb[:4])
+	entities := []*Entity{}
+	entities = g.scatter(entities, x, y, rockObject, int(numRocks))
+	entities = g.scatter(entities, x, y, bushObject, int(numBush))
+	entities = g.scatter(entities, x, y, treeObject, int(numTrees))
+	return entities
+}
 
-		oSeed := oh.Sum64()
-		ox := int32(oSeed>>48) / (math.MaxUint16/ChunkSize + 1)
-		oy := int32((oSeed<<16)>>48) / (math.MaxUint16/ChunkSize + 1)
+// scatter places count objects of one kind in the chunk.
+// Objects that land on an existing object are not added; if it is the same kind the old one grows instead.
+func (g *GameSession) scatter(entities []*Entity, x, y int32, kind chunkObject, count int) []*Entity {
+	for i := 0; i < count; i++ {
+		oSeed := g.objectSeed(x, y, kind.Salt, uint32(i))
+		ox, oy := chunkOffset(oSeed)
 
 		te := &Entity{
 			Body: &physics.RigidBody{
 				Position: physics.Vect2{
-					X: ox,
-					Y: oy,
+					X: x*ChunkSize + ox,
+					Y: y*ChunkSize + oy,
 				},
-				Height: 10,
-				Width:  10,
+				Height: kind.Size,
+				Width:  kind.Size,
 			},
 			Seed:  oSeed,
-			EType: RockEType,
-			ID:    uint32(len(g.World.Entities)),
+			EType: kind.EType,
 		}
-		// Check if existing rock overlaps this rock, if so, make old rock bigger!
+		// Check if existing object overlaps this one, if so, make old one bigger!
 		intersected := false
-		for _, t := range g.World.Entities {
+		for _, t := range entities {
 			if t.Intersects(te) {
 				if t.EType == te.EType {
-					t.Body.Height += 7
-					t.Body.Width += 7
+					t.Body.Height += kind.Growth
+					t.Body.Width += kind.Growth
 				}
 				intersected = true
 				break
 			}
 		}
 		if !intersected {
-			g.World.Entities[te.ID] = te
+			te.ID = g.nextID()
+			te.Body.ID = te.ID
+			entities = append(entities, te)
 		}
 	}
+	return entities
+}
 
-	for i := 0; i < int(numTrees); i++ {
-		oh := xxhash.New64() // (worldseed, chunkX, chunkY, 12, tree#)
-		binary.LittleEndian.PutUint64(tb[:8], g.Seed)
-		oh.Write(tb[:8])
-		binary.LittleEndian.PutUint32(tb[:4], x)
-		oh.Write(tb[:4])
-		binary.LittleEndian.PutUint32(tb[:4], y)
-		oh.Write(tb[:4])
-		binary.LittleEndian.PutUint32(tb[:4], 12)
-		oh.Write(tb[:4])
-		binary.LittleEndian.PutUint32(tb[:4], uint32(i))
-		oh.Write(tb[:4])
-
-		oSeed := oh.Sum64()
-		ox := int32(oSeed>>48) / (math.MaxUint16/ChunkSize + 1)
-		oy := int32((oSeed<<16)>>48) / (math.MaxUint16/ChunkSize + 1)
-		te := &Entity{
-			Body: &physics.RigidBody{
-				Position: physics.Vect2{
-					X: ox,
-					Y: oy,
-				},
-				Height: 100,
-				Width:  100,
-			},
-			Seed:  oSeed,
-			EType: TreeEType,
-			ID:    uint32(len(g.World.Entities)),
-		}
+// objectSeed hashes (worldseed, chunkX, chunkY, salt, object#) into the seed of one generated object.
+func (g *GameSession) objectSeed(x, y int32, salt uint32, i uint32) uint64 {
+	tb := make([]byte, 8)
+	oh := xxhash.New64()
+	binary.LittleEndian.PutUint64(tb[:8], g.Seed)
+	oh.Write(tb[:8])
+	binary.LittleEndian.PutUint32(tb[:4], uint32(x))
+	oh.Write(tb[:4])
+	binary.LittleEndian.PutUint32(tb[:4], uint32(y))
+	oh.Write(tb[:4])
+	binary.LittleEndian.PutUint32(tb[:4], salt)
+	oh.Write(tb[:4])
+	binary.LittleEndian.PutUint32(tb[:4], i)
+	oh.Write(tb[:4])
+	return oh.Sum64()
+}
 
-		// Check if existing tree overlaps this tree, if so, make old tree bigger!
-		intersected := false
-		for _, t := range g.World.Entities {
-			if t.Intersects(te) {
-				if t.EType == te.EType {
-					t.Body.Height += 75
-					t.Body.Width += 75
-				}
-				intersected = true
-				break
-			}
-		}
-		if !intersected {
-			g.World.Entities[te.ID] = te
-		}
-	}
+// chunkOffset picks a position within a chunk from an object's seed.
+func chunkOffset(seed uint64) (int32, int32) {
+	ox := int32(seed>>48) / (math.MaxUint16/ChunkSize + 1)
+	oy := int32((seed<<16)>>48) / (math.MaxUint16/ChunkSize + 1)
+	return ox, oy
+}
 
-	if g.World.Chunks == nil {
-		g.World.Chunks = map[uint32]map[uint32]bool{}
-	}
-	if g.World.Chunks[x] == nil {
-		g.World.Chunks[x] = map[uint32]bool{}
-	}
-	g.World.Chunks[x][y] = true
+// nextID returns a new entity ID. All entities in a game get their ID from here so they never collide.
+// IDs are not reused, even if the world is rewound.
+func (g *GameSession) nextID() uint32 {
+	g.lastID++
+	return g.lastID
 }
 
 // NewGame constructs a new game and starts it.
-func NewGame(name string, toGameManager chan<- GameMessage, fromNetwork <-chan GameMessage, toNetwork chan<- OutgoingMessage) *GameSession {
+func NewGame(name string, toGameManager chan<- InternalMessage, fromNetwork <-chan GameMessage, toNetwork chan<- OutgoingMessage) *GameSession {
 	seed := uint64(rand.Uint32())
 	seed = seed << 32
 	seed += uint64(rand.Uint32())
@@ -336,10 +564,16 @@ func NewGame(name string, toGameManager chan<- GameMessage, fromNetwork <-chan G
 		World: &GameWorld{
 			Space:    physics.NewSimulatedSpace(),
 			Entities: map[uint32]*Entity{},
-			Chunks:   map[uint32]map[uint32]bool{}, // list of chunks that have been already created.
+			Chunks:   map[ChunkCoord][]uint32{},
 		},
-		Exit:    make(chan int, 1),
-		Clients: make(map[uint32]*User, 16),
+		Exit:        make(chan int, 1),
+		Clients:     make(map[uint32]*User, 16),
+		ViewRadius:  DefaultViewRadius,
+		prevWorlds:  make([]*GameWorld, historyTicks),
+		appliedHits: map[uint32]uint32{},
+		depleted:    map[uint64]*depletion{},
+		creatures:   map[uint32]*Creature{},
+		nav:         map[ChunkCoord]*chunkNav{},
 	}
 	return g
 }
@@ -351,6 +585,9 @@ type Entity struct {
 	EType uint16
 	Seed  uint64
 	Body  *physics.RigidBody
+
+	Projectile *Projectile // Set if this entity is a projectile.
+	Item       *Item       // Set if this entity is an item lying on the ground.
 }
 
 func (e *Entity) toMsg() *messages.Entity {
@@ -400,6 +637,16 @@ type RemovePlayer struct {
 
 // AddPlayer is sent to add a player to a game.
 type AddPlayer struct {
-	Entity *Entity
-	Client *Client
+	Entity    *Entity
+	AccountID uint32     // Account the character is saved back to when the player leaves.
+	Character *Character // The game's own copy of the account's character.
+	Client    *Client
+	Join      bool // Send GameConnected to the client once the player is added.
+}
+
+// SaveCharacter is sent from a game to the manager when a player leaves so their
+// items, health and needs carry over to the next game.
+type SaveCharacter struct {
+	AccountID uint32
+	Character *Character
 }
@@ -2,7 +2,13 @@ package server
 
 import (
 	"fmt"
+	"math"
+	"reflect"
 	"testing"
+
+	"github.com/lologarithm/survival/physics"
+	"github.com/lologarithm/survival/physics/quadtree"
+	"github.com/lologarithm/survival/server/messages"
 )
 
 func TestGameSpawning(t *testing.T) {
@@ -19,3 +25,270 @@ func TestGameSpawning(t *testing.T) {
 		fmt.Printf("Entity %s %3d: Size: %2d X:%3d Y:%3d\n", name, i, t.Body.Height, t.Body.Position.X, t.Body.Position.Y)
 	}
 }
+
+func TestMasterFrameSplit(t *testing.T) {
+	entities := make([]*messages.Entity, 5000)
+	for i := range entities {
+		entities[i] = &messages.Entity{ID: uint32(i)}
+	}
+	frames := masterFrames(1, 0, entities)
+	if len(frames) < 2 {
+		t.Fatalf("Expected 5000 entities to need more than one frame.")
+	}
+	total := 0
+	for _, mf := range frames {
+		if mf.Len() > math.MaxUint16 {
+			t.Fatalf("Frame content too long for header: %d", mf.Len())
+		}
+		total += len(mf.Entities)
+	}
+	if total != len(entities) {
+		t.Fatalf("Expected %d entities across frames, got %d", len(entities), total)
+	}
+}
+
+func TestGameWorldClone(t *testing.T) {
+	g := NewGame("A", nil, nil, nil)
+	g.Seed = 10
+	g.SpawnChunk(0, 0)
+
+	g.addPlayer(AddPlayer{Entity: &Entity{Name: "player"}, Character: NewCharacter("player", Stats{}), Client: &Client{ID: 1}})
+
+	bodies := func(ss *physics.SimulatedSpace) map[*physics.RigidBody]bool {
+		set := map[*physics.RigidBody]bool{}
+		for _, b := range ss.Fixed {
+			set[b] = true
+		}
+		for _, b := range ss.Entities {
+			set[b] = true
+		}
+		return set
+	}
+	clone := g.World.Clone()
+	origSpace, cloneSpace := bodies(g.World.Space), bodies(clone.Space)
+	fixed := map[*physics.RigidBody]bool{}
+	for _, b := range g.World.Space.Fixed {
+		fixed[b] = true
+	}
+	for id, e := range clone.Entities {
+		orig := g.World.Entities[id]
+		// Fixed entities never change so they are shared, everything else is copied.
+		if fixed[orig.Body] != (e == orig) || fixed[orig.Body] != (e.Body == orig.Body) {
+			t.Fatalf("Entity %d was not copied or shared correctly.", id)
+		}
+		if origSpace[orig.Body] != cloneSpace[e.Body] {
+			t.Fatalf("Entity %d body is not the body in the cloned space.", id)
+		}
+		if *e.Body != *orig.Body {
+			t.Fatalf("Entity %d body doesn't match the original.", id)
+		}
+	}
+}
+
+// TestLateCommandRewind checks that a move that arrives late ends up the same as if it arrived on time.
+func TestLateCommandRewind(t *testing.T) {
+	newPlayerGame := func() (*GameSession, *Client) {
+		g := NewGame("A", nil, nil, nil)
+		c := &Client{ID: 1}
+		g.addPlayer(AddPlayer{Entity: &Entity{Name: "player"}, Character: NewCharacter("player", Kits[ScoutKit]), Client: c})
+		return g, c
+	}
+	move := &messages.MovePlayer{TickID: 3, X: 1, Y: 0}
+
+	onTime, c := newPlayerGame()
+	for onTime.World.Space.TickID < 10 {
+		if onTime.World.Space.TickID == move.TickID {
+			onTime.MoveEntity(c, move)
+		}
+		onTime.simulate()
+	}
+
+	late, c := newPlayerGame()
+	for late.World.Space.TickID < 9 {
+		late.simulate()
+	}
+	late.MoveEntity(c, move)
+	late.simulate()
+
+	id := late.Clients[1].Accounts[0].Character.ID
+	want, got := onTime.World.Entities[id].Body, late.World.Entities[id].Body
+	if want.Position.X == 5000 {
+		t.Fatalf("Player never moved.")
+	}
+	if want.Velocity.X != int32(Kits[ScoutKit].Speed) {
+		t.Fatalf("Player should move at its speed %d, moved at %v", Kits[ScoutKit].Speed, want.Velocity)
+	}
+	if got.Position != want.Position || got.Velocity != want.Velocity || late.World.Space.TickID != onTime.World.Space.TickID {
+		t.Fatalf("Late move ended at %v, on time move ended at %v", got.Position, want.Position)
+	}
+
+	// Too old to replay, gets applied at the oldest tick still in history instead.
+	old, c := newPlayerGame()
+	for old.World.Space.TickID < historyTicks*2 {
+		old.simulate()
+	}
+	old.MoveEntity(c, move)
+	if old.rewindTo != historyTicks+1 {
+		t.Fatalf("Old command should rewind to tick %d, got %d", historyTicks+1, old.rewindTo)
+	}
+	old.simulate()
+	if old.World.Entities[id].Body.Velocity.X == 0 {
+		t.Fatalf("Old command was not applied.")
+	}
+}
+
+func TestGameWorldRestore(t *testing.T) {
+	g := NewGame("A", nil, nil, nil)
+	g.Seed = 10
+	g.SpawnChunk(0, 0)
+	g.addPlayer(AddPlayer{Entity: &Entity{Name: "player"}, Character: NewCharacter("player", Stats{}), Client: &Client{ID: 1}})
+	g.MoveEntity(&Client{ID: 1}, &messages.MovePlayer{X: 1, Y: 1})
+	g.simulate()
+
+	positions := func(w *GameWorld) map[uint32]physics.Vect2 {
+		pos := map[uint32]physics.Vect2{}
+		for id, e := range w.Entities {
+			pos[id] = e.Body.Position
+		}
+		return pos
+	}
+	snap := g.World.Clone()
+	for i := 0; i < 10; i++ {
+		g.World.Space.Tick(true)
+	}
+	want := positions(g.World)
+
+	g.World.Restore(snap)
+	if !reflect.DeepEqual(positions(g.World), positions(snap)) {
+		t.Fatalf("Restored world doesn't match the snapshot.")
+	}
+	player := g.World.Entities[g.Clients[1].Accounts[0].Character.ID]
+	found := false
+	for _, b := range g.World.Space.Entities {
+		found = found || b == player.Body
+	}
+	if !found || player.Body == snap.Entities[player.ID].Body {
+		t.Fatalf("Restored player body is not the one in the restored space.")
+	}
+	for i := 0; i < 10; i++ {
+		g.World.Space.Tick(true)
+	}
+	if got := positions(g.World); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Restored world did not simulate the same as the original.")
+	}
+}
+
+func TestChunkEntities(t *testing.T) {
+	g := NewGame("A", nil, nil, nil)
+	g.Seed = 10
+	g.addPlayer(AddPlayer{Entity: &Entity{Name: "player"}, Character: NewCharacter("player", Stats{}), Client: &Client{ID: 1}})
+	playerID := g.Clients[1].Accounts[0].Character.ID
+
+	kinds := map[uint16]int{}
+	for x := int32(0); x < 3; x++ {
+		for y := int32(0); y < 3; y++ {
+			g.SpawnChunk(x, y)
+		}
+	}
+	g.SpawnChunk(0, 0) // Already spawned, should not add anything.
+
+	fixed := map[*physics.RigidBody]bool{}
+	for _, b := range g.World.Space.Fixed {
+		if b != nil {
+			fixed[b] = true
+		}
+	}
+	if len(fixed) != len(g.World.Entities)-len(g.creatures)-1 {
+		t.Fatalf("Expected %d fixed bodies, got %d", len(g.World.Entities)-len(g.creatures)-1, len(fixed))
+	}
+	for id, e := range g.World.Entities {
+		if e.ID != id || e.Body.ID != id || id == 0 {
+			t.Fatalf("Entity has bad ID %d (map %d, body %d)", e.ID, id, e.Body.ID)
+		}
+		if id == playerID || g.creatures[id] != nil {
+			continue
+		}
+		if !fixed[e.Body] {
+			t.Fatalf("Entity %d is not a fixed body in the space.", id)
+		}
+		if len(g.World.Space.Query(e.Body.Bounds())) == 0 {
+			t.Fatalf("Entity %d is not in the space tree.", id)
+		}
+		kinds[e.EType]++
+	}
+	if kinds[RockEType] == 0 || kinds[BushEType] == 0 || kinds[TreeEType] == 0 {
+		t.Fatalf("Expected rocks, bushes and trees, got %v", kinds)
+	}
+
+	// Same seed makes the same chunk.
+	other := NewGame("B", nil, nil, nil)
+	other.Seed = g.Seed
+	a, b := g.generateChunk(1, 2), other.generateChunk(1, 2)
+	if len(a) != len(b) {
+		t.Fatalf("Chunk generated %d entities, then %d", len(a), len(b))
+	}
+	for i := range a {
+		if a[i].Seed != b[i].Seed || a[i].Body.Position != b[i].Body.Position || a[i].Body.Height != b[i].Body.Height {
+			t.Fatalf("Chunk generated differently with the same seed.")
+		}
+	}
+}
+
+func TestStreamChunks(t *testing.T) {
+	g := NewGame("A", nil, nil, nil)
+	g.Seed = 10
+	c := &Client{ID: 1}
+	g.addPlayer(AddPlayer{Entity: &Entity{Name: "player"}, Character: NewCharacter("player", Stats{}), Client: c})
+	player := g.World.Entities[g.Clients[1].Accounts[0].Character.ID]
+
+	g.StreamChunks()
+	if len(g.World.Chunks) != 9 {
+		t.Fatalf("Expected 3x3 chunks around the player, got %d", len(g.World.Chunks))
+	}
+	first := map[uint64]physics.Vect2{}
+	for _, id := range g.World.Chunks[ChunkCoord{X: -1, Y: -1}] {
+		e := g.World.Entities[id]
+		first[e.Seed] = e.Body.Position
+		if ChunkAt(e.Body.Position) != (ChunkCoord{X: -1, Y: -1}) {
+			t.Fatalf("Entity at %v is outside of its chunk.", e.Body.Position)
+		}
+	}
+
+	// Walk far away into negative space, old chunks should go away.
+	player.Body.Position = physics.Vect2{X: -5 * ChunkSize, Y: -3*ChunkSize - 1}
+	g.World.Space.UpdateEntity(player.Body)
+	g.StreamChunks()
+	if len(g.World.Chunks) != 9 {
+		t.Fatalf("Expected only chunks around the player to be loaded, got %d", len(g.World.Chunks))
+	}
+	if _, ok := g.World.Chunks[ChunkCoord{X: -5, Y: -4}]; !ok {
+		t.Fatalf("Chunk the player is in was not loaded.")
+	}
+	if _, ok := g.World.Chunks[ChunkCoord{X: -1, Y: -1}]; ok {
+		t.Fatalf("Chunk far from the player was not unloaded.")
+	}
+	if n := len(g.World.Space.Query(quadtree.NewBoundingBox(-ChunkSize, 2*ChunkSize, -ChunkSize, 2*ChunkSize))); n != 0 {
+		t.Fatalf("Unloaded chunks left %d bodies in the space.", n)
+	}
+
+	// One chunk over is still within the unload radius so nothing changes.
+	player.Body.Position.X += ChunkSize
+	g.StreamChunks()
+	if _, ok := g.World.Chunks[ChunkCoord{X: -6, Y: -4}]; !ok {
+		t.Fatalf("Chunk within the unload radius was unloaded.")
+	}
+
+	// Coming back makes the same chunk again.
+	player.Body.Position = physics.Vect2{X: 5000, Y: 5000}
+	g.StreamChunks()
+	ids := g.World.Chunks[ChunkCoord{X: -1, Y: -1}]
+	if len(ids) != len(first) {
+		t.Fatalf("Chunk came back with %d entities, had %d", len(ids), len(first))
+	}
+	for _, id := range ids {
+		e := g.World.Entities[id]
+		if pos, ok := first[e.Seed]; !ok || pos != e.Body.Position {
+			t.Fatalf("Chunk was not generated the same way again.")
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package server
+
+import (
+	"sort"
+
+	"github.com/lologarithm/survival/server/messages"
+)
+
+// HarvestRange is how far from the edge of a node a character can harvest it.
+const HarvestRange = 60
+
+// harvestStamina is the stamina it takes to harvest once.
+const harvestStamina int16 = 5
+
+// regrowTicks is the shortest time it takes a node to grow back one harvest.
+// Each node takes between regrowTicks and twice that, picked from its Seed.
+const regrowTicks = 900
+
+// resourceNode is what harvesting a kind of entity gives.
+type resourceNode struct {
+	Item       uint16 // Kind of item harvested.
+	PerHarvest uint16 // How many items each harvest gives.
+	Shrink     int32  // How much smaller the node gets each harvest, bigger nodes can be harvested more times.
+}
+
+var resourceNodes = map[uint16]resourceNode{
+	RockEType: {Item: StoneItem, PerHarvest: 2, Shrink: 5},
+	BushEType: {Item: BerryItem, PerHarvest: 2, Shrink: 20},
+	TreeEType: {Item: WoodItem, PerHarvest: 3, Shrink: 25},
+}
+
+// depletion is how much of a node has been harvested. It is kept by the game by the node's Seed,
+// which is the same every time its chunk is generated, so unloading a chunk doesn't reset it.
+type depletion struct {
+	Node  Entity // Node as it was generated, with its full size.
+	Steps int32  // Harvests taken that haven't grown back yet.
+	Since uint32 // Tick of the last harvest or regrowth.
+}
+
+// size is how big the node is with the harvests taken, 0 or less once it is used up.
+func (d *depletion) size() (int32, int32) {
+	shrink := resourceNodes[d.Node.EType].Shrink * d.Steps
+	return d.Node.Body.Height - shrink, d.Node.Body.Width - shrink
+}
+
+// regrowAfter is how many ticks it takes this node to grow back a step.
+func (d *depletion) regrowAfter() uint32 {
+	return regrowTicks + uint32(d.Node.Seed%regrowTicks)
+}
+
+// Harvest takes resources from a node next to the player's character and puts them in its backpack.
+// The node shrinks with each harvest and is removed once it is used up.
+func (g *GameSession) Harvest(c *Client, tmsg *messages.Harvest) {
+	user := g.Clients[c.ID]
+	if user == nil {
+		return
+	}
+	defer g.sendInventory(user)
+	char := user.Accounts[0].Character
+	ent, node := g.World.Entities[char.ID], g.World.Entities[tmsg.EntityID]
+	if ent == nil || node == nil || char.CurrentStats.Stamina < harvestStamina {
+		return
+	}
+	res, ok := resourceNodes[node.EType]
+	if !ok {
+		return
+	}
+	bb := node.Body.Bounds()
+	pos := ent.Body.Position
+	if pos.X < bb.MinX-HarvestRange || pos.X > bb.MaxX+HarvestRange || pos.Y < bb.MinY-HarvestRange || pos.Y > bb.MaxY+HarvestRange {
+		return
+	}
+	if !char.giveItems(res.Item, res.PerHarvest, g.nextID) {
+		return
+	}
+	char.CurrentStats.Stamina -= harvestStamina
+
+	d := g.depleted[node.Seed]
+	if d == nil {
+		d = &depletion{Node: *node}
+		body := *node.Body
+		d.Node.Body = &body
+		g.depleted[node.Seed] = d
+	}
+	d.Steps++
+	d.Since = g.World.Space.TickID
+	g.resizeNode(d)
+}
+
+// RegrowResources grows back a step of every harvested node whose time is up,
+// whether or not its chunk is loaded.
+func (g *GameSession) RegrowResources() {
+	now := g.World.Space.TickID
+	seeds := make([]uint64, 0, len(g.depleted))
+	for seed := range g.depleted {
+		seeds = append(seeds, seed)
+	}
+	// Regrow in a fixed order so replays add entities the same way.
+	sort.Slice(seeds, func(i, j int) bool { return seeds[i] < seeds[j] })
+	for _, seed := range seeds {
+		d := g.depleted[seed]
+		if now-d.Since < d.regrowAfter() {
+			continue
+		}
+		d.Steps--
+		d.Since = now
+		if d.Steps == 0 {
+			delete(g.depleted, seed)
+		}
+		if _, ok := g.World.Chunks[ChunkAt(d.Node.Body.Position)]; ok {
+			g.resizeNode(d)
+		}
+	}
+}
+
+// resizeNode sets the node in the world to the size its depletion says,
+// adding it back if it had been used up or removing it if it is now.
+func (g *GameSession) resizeNode(d *depletion) {
+	height, width := d.size()
+	node := d.Node
+	body := *node.Body
+	node.Body = &body
+	id := node.ID
+	g.invalidateNav(body.Bounds()) // The node is never bigger than when it was generated.
+	g.applyNow(func(w *GameWorld) {
+		// Fixed entities are shared with the rewind history, so the node is replaced instead of changed.
+		if e := w.Entities[id]; e != nil {
+			w.Space.RemoveEntity(e.Body, true)
+			delete(w.Entities, id)
+		}
+		if width <= 0 {
+			return
+		}
+		ne := node
+		body := *node.Body
+		body.Height, body.Width = height, width
+		ne.Body = &body
+		w.Entities[id] = &ne
+		w.Space.AddEntity(ne.Body, true)
+	})
+}
+
+// applyDepletion shrinks freshly generated nodes that were harvested before their chunk was unloaded.
+// Nodes that are used up are left out of the returned list.
+func (g *GameSession) applyDepletion(entities []*Entity) []*Entity {
+	kept := entities[:0]
+	for _, e := range entities {
+		d := g.depleted[e.Seed]
+		if d == nil {
+			kept = append(kept, e)
+			continue
+		}
+		d.Node.ID = e.ID
+		d.Node.Body.ID = e.ID
+		height, width := d.size()
+		if width <= 0 {
+			continue
+		}
+		e.Body.Height, e.Body.Width = height, width
+		kept = append(kept, e)
+	}
+	return kept
+}
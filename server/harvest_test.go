@@ -0,0 +1,76 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/lologarithm/survival/physics"
+	"github.com/lologarithm/survival/server/messages"
+)
+
+func TestHarvest(t *testing.T) {
+	out := make(chan OutgoingMessage, 100)
+	c := &Client{ID: 1}
+	g, chars := newTestGame(out, c)
+	g.Seed = 10
+	g.SpawnChunk(0, 0)
+	char := chars[0]
+
+	var tree *Entity
+	for _, id := range g.World.Chunks[ChunkCoord{}] {
+		if e := g.World.Entities[id]; e.EType == TreeEType && (tree == nil || e.Body.Width > tree.Body.Width) {
+			tree = e
+		}
+	}
+	if tree == nil {
+		t.Fatalf("Expected a tree in the chunk.")
+	}
+	id, width := tree.ID, tree.Body.Width
+	body := g.World.Entities[char.ID].Body
+
+	harvests := 0
+	for g.World.Entities[id] != nil {
+		// Keep next to the tree as it shrinks.
+		body.Position = physics.Vect2{X: g.World.Entities[id].Body.Bounds().MaxX + 30, Y: tree.Body.Position.Y}
+		g.World.Space.UpdateEntity(body)
+		char.CurrentStats.Stamina = harvestStamina
+		g.Harvest(c, &messages.Harvest{EntityID: id})
+		harvests++
+		if e := g.World.Entities[id]; e != nil && e.Body.Width != width-int32(harvests)*resourceNodes[TreeEType].Shrink {
+			t.Fatalf("Tree did not shrink, width %d after %d harvests", e.Body.Width, harvests)
+		}
+		if harvests > 100 {
+			t.Fatalf("Tree never ran out.")
+		}
+	}
+	if want := int((width + resourceNodes[TreeEType].Shrink - 1) / resourceNodes[TreeEType].Shrink); harvests != want {
+		t.Fatalf("Expected a tree of width %d to last %d harvests, got %d", width, want, harvests)
+	}
+	if len(char.InventoryItems) != 1 || char.InventoryItems[0].Kind != WoodItem || int(char.InventoryItems[0].Count) != harvests*3 {
+		t.Fatalf("Expected %d wood, got %v", harvests*3, char.InventoryItems[0])
+	}
+	char.CurrentStats.Stamina = 0
+	g.Harvest(c, &messages.Harvest{EntityID: g.World.Chunks[ChunkCoord{}][0]})
+	if int(char.InventoryItems[0].Count) != harvests*3 || len(char.InventoryItems) != 1 {
+		t.Fatalf("Harvested without stamina.")
+	}
+
+	// Still used up after the chunk is generated again.
+	g.UnloadChunk(0, 0)
+	g.SpawnChunk(0, 0)
+	seed := tree.Seed
+	for _, id := range g.World.Chunks[ChunkCoord{}] {
+		if e := g.World.Entities[id]; e != nil && e.Seed == seed {
+			t.Fatalf("Used up tree came back with its chunk.")
+		}
+	}
+
+	d := g.depleted[seed]
+	for i := uint32(0); i < d.regrowAfter(); i++ {
+		g.simulate()
+		g.RegrowResources()
+	}
+	regrown := g.World.Entities[d.Node.ID]
+	if regrown == nil || regrown.Seed != seed || regrown.Body.Width != width-int32(harvests-1)*resourceNodes[TreeEType].Shrink {
+		t.Fatalf("Expected the tree to grow back one step: %v", regrown)
+	}
+}
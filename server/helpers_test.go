@@ -0,0 +1,31 @@
+package server
+
+import (
+	"fmt"
+
+	"github.com/lologarithm/survival/server/messages"
+)
+
+// drainType returns the queued messages of the type sent to the client, everything else queued for the client is dropped.
+func drainType(out chan OutgoingMessage, c *Client, mtype messages.MessageType) []messages.Net {
+	msgs := []messages.Net{}
+	for _, p := range drainFor(out, c) {
+		if p.Frame.MsgType == mtype {
+			msgs = append(msgs, p.NetMsg)
+		}
+	}
+	return msgs
+}
+
+// newTestGame makes a game that sends to out with a fighter in it for each client.
+// Returns the game and the characters in the same order as the clients.
+func newTestGame(out chan OutgoingMessage, clients ...*Client) (*GameSession, []*Character) {
+	g := NewGame("A", nil, nil, out)
+	chars := make([]*Character, len(clients))
+	for i, c := range clients {
+		name := fmt.Sprintf("player%d", c.ID)
+		g.addPlayer(AddPlayer{Entity: &Entity{Name: name}, Character: NewCharacter(name, Kits[FighterKit]), Client: c})
+		chars[i] = g.Clients[c.ID].Accounts[0].Character
+	}
+	return g, chars
+}
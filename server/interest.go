@@ -0,0 +1,120 @@
+package server
+
+import (
+	"math"
+	"sort"
+
+	"github.com/lologarithm/survival/physics/quadtree"
+	"github.com/lologarithm/survival/server/messages"
+)
+
+// DefaultViewRadius is how far from their character a player can see entities, in units.
+// The client's camera sits 150 units behind the player and draws up to 1000 units out,
+// so this covers everything it can draw with a little room to spare.
+const DefaultViewRadius int32 = 1200
+
+// visibleTo returns the IDs of all entities whose center is within the view radius of the user's character.
+// The character itself is always visible to its player.
+func (g *GameSession) visibleTo(user *User) map[uint32]bool {
+	visible := map[uint32]bool{}
+	id := user.Accounts[0].Character.ID
+	ent := g.World.Entities[id]
+	if ent == nil {
+		return visible
+	}
+	visible[id] = true
+	pos := ent.Body.Position
+	view := quadtree.NewBoundingBox(pos.X-g.ViewRadius, pos.X+g.ViewRadius, pos.Y-g.ViewRadius, pos.Y+g.ViewRadius)
+	radius := int64(g.ViewRadius) * int64(g.ViewRadius)
+	for _, body := range g.World.Space.Query(view) {
+		dx, dy := int64(body.Position.X-pos.X), int64(body.Position.Y-pos.Y)
+		if dx*dx+dy*dy > radius {
+			continue // In the query box but past the corners of the circle.
+		}
+		if _, ok := g.World.Entities[body.ID]; ok {
+			visible[body.ID] = true
+		}
+	}
+	return visible
+}
+
+// UpdateInterest recalculates what each player can see and tells them about
+// entities that came into view or went out of view since the last update.
+func (g *GameSession) UpdateInterest() {
+	for _, user := range g.Clients {
+		visible := g.visibleTo(user)
+		entered := []*messages.Entity{}
+		for id := range visible {
+			if !user.visible[id] {
+				entered = append(entered, g.entityMsg(g.World.Entities[id]))
+			}
+		}
+		left := []uint32{}
+		for id := range user.visible {
+			if !visible[id] {
+				left = append(left, id)
+			}
+		}
+		user.visible = visible
+
+		if len(left) > 0 {
+			sort.Slice(left, func(i, j int) bool { return left[i] < left[j] })
+			perMsg := (math.MaxUint16 - (&messages.EntitiesLeft{}).Len()) / 4
+			for len(left) > 0 {
+				n := perMsg
+				if n > len(left) {
+					n = len(left)
+				}
+				g.ToNetwork <- NewReliableMsg(user.Client, messages.EntitiesLeftMsgType, &messages.EntitiesLeft{
+					GameID: g.ID,
+					TickID: g.World.Space.TickID,
+					IDs:    left[:n],
+				})
+				left = left[n:]
+			}
+		}
+		if len(entered) > 0 {
+			sortEntities(entered)
+			for _, part := range splitEntities(entered, (&messages.EntitiesEntered{}).Len()) {
+				g.ToNetwork <- NewReliableMsg(user.Client, messages.EntitiesEnteredMsgType, &messages.EntitiesEntered{
+					GameID:   g.ID,
+					TickID:   g.World.Space.TickID,
+					Entities: part,
+				})
+			}
+		}
+	}
+}
+
+// visibleEntities returns the entities the user currently knows about, sorted by ID.
+func (g *GameSession) visibleEntities(user *User) []*messages.Entity {
+	es := make([]*messages.Entity, 0, len(user.visible))
+	for id := range user.visible {
+		if e := g.World.Entities[id]; e != nil {
+			es = append(es, g.entityMsg(e))
+		}
+	}
+	sortEntities(es)
+	return es
+}
+
+func sortEntities(es []*messages.Entity) {
+	sort.Slice(es, func(i, j int) bool { return es[i].ID < es[j].ID })
+}
+
+// splitEntities cuts a list of entities into groups small enough that a message
+// with headerLen bytes of other fields still fits in a frame.
+func splitEntities(entities []*messages.Entity, headerLen int) [][]*messages.Entity {
+	parts := [][]*messages.Entity{}
+	start := 0
+	size := headerLen
+	for i, e := range entities {
+		if size+e.Len() > math.MaxUint16 && i > start {
+			parts = append(parts, entities[start:i])
+			start = i
+			size = headerLen
+		}
+		size += e.Len()
+	}
+	return append(parts, entities[start:])
+}
@@ -0,0 +1,111 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/lologarithm/survival/physics"
+	"github.com/lologarithm/survival/server/messages"
+)
+
+// drainFor returns all queued messages sent to the client.
+func drainFor(out chan OutgoingMessage, c *Client) []messages.Packet {
+	msgs := []messages.Packet{}
+	for {
+		select {
+		case msg := <-out:
+			if msg.dest == c {
+				msgs = append(msgs, msg.msg)
+			}
+		default:
+			return msgs
+		}
+	}
+}
+
+func TestInterestManagement(t *testing.T) {
+	out := make(chan OutgoingMessage, 1000)
+	g := NewGame("A", nil, nil, out)
+	g.Seed = 10
+	a, b := &Client{ID: 1}, &Client{ID: 2}
+	g.addPlayer(AddPlayer{Entity: &Entity{Name: "a"}, Character: NewCharacter("a", Stats{}), Client: a})
+	g.addPlayer(AddPlayer{Entity: &Entity{Name: "b"}, Character: NewCharacter("b", Stats{}), Client: b})
+	aID, bID := g.Clients[1].Accounts[0].Character.ID, g.Clients[2].Accounts[0].Character.ID
+	bBody := g.World.Entities[bID].Body
+	moveB := func(pos physics.Vect2) {
+		bBody.Position = pos
+		g.World.Space.UpdateEntity(bBody)
+	}
+	moveB(physics.Vect2{X: 5000 + 3*g.ViewRadius, Y: 5000})
+	g.StreamChunks()
+
+	g.UpdateInterest()
+	seen := map[uint32]bool{}
+	for _, p := range drainFor(out, a) {
+		for _, e := range p.NetMsg.(*messages.EntitiesEntered).Entities {
+			seen[e.ID] = true
+		}
+	}
+	if !seen[aID] || seen[bID] {
+		t.Fatalf("Player should see themselves but not the far away player.")
+	}
+	aPos := g.World.Entities[aID].Body.Position
+	for id := range seen {
+		pos := g.World.Entities[id].Body.Position
+		if physics.SubVect2(pos, aPos).Magnitude() > float64(g.ViewRadius) {
+			t.Fatalf("Entity %d at %v is too far away to be sent.", id, pos)
+		}
+	}
+	if len(seen) == len(g.World.Entities) {
+		t.Fatalf("Expected some entities to be out of view.")
+	}
+
+	// Nothing changed, nothing sent.
+	g.UpdateInterest()
+	if msgs := drainFor(out, a); len(msgs) != 0 {
+		t.Fatalf("Expected no updates, got %d messages.", len(msgs))
+	}
+
+	// Inside the box around the view radius but outside the circle.
+	moveB(physics.Vect2{X: aPos.X + g.ViewRadius*9/10, Y: aPos.Y + g.ViewRadius*9/10})
+	g.UpdateInterest()
+	if msgs := drainFor(out, a); len(msgs) != 0 {
+		t.Fatalf("Player past the view radius should not be sent, got %d messages.", len(msgs))
+	}
+
+	moveB(physics.Vect2{X: 5100, Y: 5000})
+	g.UpdateInterest()
+	msgs := drainFor(out, a)
+	if len(msgs) != 1 || msgs[0].Frame.MsgType != messages.EntitiesEnteredMsgType {
+		t.Fatalf("Expected one enter message, got %v", msgs)
+	}
+	if ents := msgs[0].NetMsg.(*messages.EntitiesEntered).Entities; len(ents) != 1 || ents[0].ID != bID {
+		t.Fatalf("Expected the other player to enter view.")
+	}
+
+	g.SendMasterFrame()
+	inFrame := false
+	for _, p := range drainFor(out, a) {
+		for _, e := range p.NetMsg.(*messages.GameMasterFrame).Entities {
+			if !g.Clients[1].visible[e.ID] {
+				t.Fatalf("Master frame has entity %d that is not visible.", e.ID)
+			}
+			inFrame = inFrame || e.ID == bID
+		}
+	}
+	if !inFrame {
+		t.Fatalf("Master frame is missing the visible player.")
+	}
+
+	moveB(physics.Vect2{X: 5000 - 3*g.ViewRadius, Y: 5000})
+	g.UpdateInterest()
+	msgs = drainFor(out, a)
+	left := []uint32{}
+	for _, p := range msgs {
+		if l, ok := p.NetMsg.(*messages.EntitiesLeft); ok {
+			left = append(left, l.IDs...)
+		}
+	}
+	if len(left) != 1 || left[0] != bID {
+		t.Fatalf("Expected the other player to leave view, got %v", left)
+	}
+}
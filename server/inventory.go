@@ -0,0 +1,229 @@
+package server
+
+import (
+	"github.com/lologarithm/survival/physics"
+	"github.com/lologarithm/survival/server/messages"
+)
+
+// MaxInventory is how many items a character can carry in its backpack.
+const MaxInventory = 20
+
+// PickupRange is how close a character has to be to an item to pick it up.
+const PickupRange = 100
+
+// ItemSize is the height and width of an item lying on the ground.
+const ItemSize = 10
+
+// MaxStats are the character's own stats plus the stats of everything it has equipped.
+func (c *Character) MaxStats() Stats {
+	stats := c.Stats
+	for _, item := range c.EquippedItems {
+		if item != nil {
+			stats = stats.Add(item.Stats)
+		}
+	}
+	return stats
+}
+
+// updateStats recalculates CurrentStats after equipment changed, old is what MaxStats was before.
+// HP, Stamina and Concentration go up or down by the change but never past the new max,
+// and taking off equipment never kills the character.
+func (c *Character) updateStats(old Stats) {
+	max := c.MaxStats()
+	cur := c.CurrentStats
+	cur.HP = min32(cur.HP+max.HP-old.HP, max.HP)
+	if cur.HP < 1 {
+		cur.HP = 1
+	}
+	cur.Stamina = min16(cur.Stamina+max.Stamina-old.Stamina, max.Stamina)
+	if cur.Stamina < 0 {
+		cur.Stamina = 0
+	}
+	cur.Concentration = min16(cur.Concentration+max.Concentration-old.Concentration, max.Concentration)
+	if cur.Concentration < 0 {
+		cur.Concentration = 0
+	}
+	cur.Speed = max.Speed
+	cur.MagicStrength = max.MagicStrength
+	cur.PhysicalStrength = max.PhysicalStrength
+	c.CurrentStats = cur
+}
+
+// inventoryIndex returns where the item is in the backpack, or -1.
+func (c *Character) inventoryIndex(id uint32) int {
+	for i, item := range c.InventoryItems {
+		if item.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// giveItems adds count of a resource to the backpack, filling existing stacks first.
+// Returns false without changing anything if they don't all fit.
+func (c *Character) giveItems(kind uint16, count uint16, newID func() uint32) bool {
+	room := (MaxInventory - len(c.InventoryItems)) * MaxStack
+	for _, item := range c.InventoryItems {
+		if item.stacks(kind) {
+			room += MaxStack - int(item.Count)
+		}
+	}
+	if room < int(count) {
+		return false
+	}
+	for _, item := range c.InventoryItems {
+		if count == 0 {
+			break
+		}
+		if item.stacks(kind) {
+			n := min16(int16(count), int16(MaxStack-item.Count))
+			item.Count += uint16(n)
+			count -= uint16(n)
+		}
+	}
+	for count > 0 {
+		n := min16(int16(count), MaxStack)
+		c.InventoryItems = append(c.InventoryItems, &Item{ID: newID(), Kind: kind, Count: uint16(n)})
+		count -= uint16(n)
+	}
+	return true
+}
+
+// takeItem removes an item from the backpack and returns it, or nil if it isn't there.
+func (c *Character) takeItem(id uint32) *Item {
+	idx := c.inventoryIndex(id)
+	if idx == -1 {
+		return nil
+	}
+	item := c.InventoryItems[idx]
+	c.InventoryItems = append(c.InventoryItems[:idx], c.InventoryItems[idx+1:]...)
+	return item
+}
+
+// validSlot is true if an item can be equipped in the slot.
+func validSlot(item *Item, slot ItemSlot) bool {
+	return slot > OtherSlot && slot <= SpiritSlot && item.Slot == slot
+}
+
+// PickupItem moves an item lying near the player's character into its backpack.
+func (g *GameSession) PickupItem(c *Client, tmsg *messages.PickupItem) {
+	user := g.Clients[c.ID]
+	if user == nil {
+		return
+	}
+	defer g.sendInventory(user)
+	char := user.Accounts[0].Character
+	ent, ground := g.World.Entities[char.ID], g.World.Entities[tmsg.EntityID]
+	if ent == nil || ground == nil || ground.Item == nil || len(char.InventoryItems) >= MaxInventory {
+		return
+	}
+	if physics.SubVect2(ground.Body.Position, ent.Body.Position).Magnitude() > PickupRange {
+		return
+	}
+	item := *ground.Item
+	char.InventoryItems = append(char.InventoryItems, &item)
+	id := ground.ID
+	g.applyNow(func(w *GameWorld) {
+		if e := w.Entities[id]; e != nil {
+			w.removeEntity(e)
+		}
+	})
+}
+
+// DropItem takes an item out of the player's backpack and leaves it on the ground at its character.
+func (g *GameSession) DropItem(c *Client, tmsg *messages.DropItem) {
+	user := g.Clients[c.ID]
+	if user == nil {
+		return
+	}
+	defer g.sendInventory(user)
+	char := user.Accounts[0].Character
+	ent := g.World.Entities[char.ID]
+	if ent == nil || char.inventoryIndex(tmsg.ItemID) == -1 {
+		return
+	}
+	g.dropItem(char.takeItem(tmsg.ItemID), ent.Body.Position)
+}
+
+// dropItem puts an item in the world as a new entity.
+func (g *GameSession) dropItem(item *Item, pos physics.Vect2) uint32 {
+	id := g.nextID()
+	g.applyNow(func(w *GameWorld) {
+		if _, ok := w.Entities[id]; ok {
+			return
+		}
+		body := physics.NewRigidBody(id, ItemSize, ItemSize, pos, physics.Vect2{}, 0, 0)
+		body.Sensor = true
+		w.Entities[id] = &Entity{
+			ID:    id,
+			EType: ItemEType,
+			Seed:  uint64(item.ID),
+			Body:  body,
+			Item:  item,
+		}
+		w.Space.AddEntity(body, false)
+	})
+	return id
+}
+
+// EquipItem moves an item from the backpack into an equipment slot. Anything already
+// in the slot goes back into the backpack.
+func (g *GameSession) EquipItem(c *Client, tmsg *messages.EquipItem) {
+	user := g.Clients[c.ID]
+	if user == nil {
+		return
+	}
+	defer g.sendInventory(user)
+	char := user.Accounts[0].Character
+	idx := char.inventoryIndex(tmsg.ItemID)
+	slot := ItemSlot(tmsg.Slot)
+	if user.respawnAt != 0 || idx == -1 || !validSlot(char.InventoryItems[idx], slot) {
+		return
+	}
+	old := char.MaxStats()
+	item := char.takeItem(tmsg.ItemID)
+	if prev := char.EquippedItems[slot]; prev != nil {
+		char.InventoryItems = append(char.InventoryItems, prev)
+	}
+	char.EquippedItems[slot] = item
+	char.updateStats(old)
+}
+
+// UnequipItem moves the item in an equipment slot back into the backpack.
+func (g *GameSession) UnequipItem(c *Client, tmsg *messages.UnequipItem) {
+	user := g.Clients[c.ID]
+	if user == nil {
+		return
+	}
+	defer g.sendInventory(user)
+	char := user.Accounts[0].Character
+	slot := ItemSlot(tmsg.Slot)
+	if user.respawnAt != 0 || slot <= OtherSlot || slot > SpiritSlot || char.EquippedItems[slot] == nil || len(char.InventoryItems) >= MaxInventory {
+		return
+	}
+	old := char.MaxStats()
+	char.InventoryItems = append(char.InventoryItems, char.EquippedItems[slot])
+	char.EquippedItems[slot] = nil
+	char.updateStats(old)
+}
+
+// sendInventory sends the player everything its character has equipped and carries, and its stats.
+// It is sent after every inventory request, even ones that failed, so the client is always in sync.
+func (g *GameSession) sendInventory(user *User) {
+	char := user.Accounts[0].Character
+	update := &messages.InventoryUpdate{
+		Equipped:  []*messages.Item{},
+		Inventory: make([]*messages.Item, 0, len(char.InventoryItems)),
+		Current:   char.CurrentStats.toMsg(),
+		Max:       char.MaxStats().toMsg(),
+	}
+	for _, item := range char.EquippedItems {
+		if item != nil {
+			update.Equipped = append(update.Equipped, item.toMsg())
+		}
+	}
+	for _, item := range char.InventoryItems {
+		update.Inventory = append(update.Inventory, item.toMsg())
+	}
+	g.ToNetwork <- NewReliableMsg(user.Client, messages.InventoryUpdateMsgType, update)
+}
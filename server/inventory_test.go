@@ -0,0 +1,100 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/lologarithm/survival/physics"
+	"github.com/lologarithm/survival/server/messages"
+)
+
+func lastInventory(t *testing.T, out chan OutgoingMessage, c *Client) *messages.InventoryUpdate {
+	var update *messages.InventoryUpdate
+	for _, p := range drainFor(out, c) {
+		if u, ok := p.NetMsg.(*messages.InventoryUpdate); ok {
+			update = u
+		}
+	}
+	if update == nil {
+		t.Fatalf("Expected an inventory update.")
+	}
+	return update
+}
+
+func TestEquipItems(t *testing.T) {
+	out := make(chan OutgoingMessage, 100)
+	c := &Client{ID: 1}
+	g, chars := newTestGame(out, c)
+	char := chars[0]
+	sword := &Item{ID: 100, Slot: MainHandSlot, Stats: Stats{PhysicalStrength: 5}}
+	axe := &Item{ID: 101, Slot: MainHandSlot, Stats: Stats{PhysicalStrength: 8}}
+	armor := &Item{ID: 102, Slot: BodySlot, Stats: Stats{HP: 30}}
+	char.InventoryItems = []*Item{sword, axe, armor}
+	base := Kits[FighterKit]
+
+	g.EquipItem(c, &messages.EquipItem{ItemID: sword.ID, Slot: byte(OffHandSlot)})
+	if char.EquippedItems[OffHandSlot] != nil || len(char.InventoryItems) != 3 {
+		t.Fatalf("Item should only go in its own slot.")
+	}
+	g.EquipItem(c, &messages.EquipItem{ItemID: sword.ID, Slot: byte(MainHandSlot)})
+	g.EquipItem(c, &messages.EquipItem{ItemID: armor.ID, Slot: byte(BodySlot)})
+	if char.CurrentStats.PhysicalStrength != base.PhysicalStrength+5 || char.CurrentStats.HP != base.HP+30 {
+		t.Fatalf("Equipped stats not added: %v", char.CurrentStats)
+	}
+	g.EquipItem(c, &messages.EquipItem{ItemID: axe.ID, Slot: byte(MainHandSlot)})
+	if char.EquippedItems[MainHandSlot] != axe || char.inventoryIndex(sword.ID) == -1 || char.CurrentStats.PhysicalStrength != base.PhysicalStrength+8 {
+		t.Fatalf("Axe should replace the sword: %v", char.CurrentStats)
+	}
+
+	char.CurrentStats.HP = 10
+	g.UnequipItem(c, &messages.UnequipItem{Slot: byte(BodySlot)})
+	if char.CurrentStats.HP != 1 || char.MaxStats().HP != base.HP {
+		t.Fatalf("Taking off armor should not kill, HP: %d", char.CurrentStats.HP)
+	}
+	update := lastInventory(t, out, c)
+	if len(update.Equipped) != 1 || update.Equipped[0].ID != axe.ID || len(update.Inventory) != 2 || update.Max.PhysicalStrength != base.PhysicalStrength+8 {
+		t.Fatalf("Inventory update out of sync: %v", update)
+	}
+}
+
+func TestPickupAndDrop(t *testing.T) {
+	out := make(chan OutgoingMessage, 100)
+	a, b := &Client{ID: 1}, &Client{ID: 2}
+	g, chars := newTestGame(out, a, b)
+	charA, charB := chars[0], chars[1]
+	charA.InventoryItems = []*Item{{ID: 100, Slot: FeetSlot}}
+
+	g.DropItem(a, &messages.DropItem{ItemID: 100})
+	if len(charA.InventoryItems) != 0 {
+		t.Fatalf("Item is still in the backpack.")
+	}
+	var dropped *Entity
+	for _, e := range g.World.Entities {
+		if e.Item != nil {
+			dropped = e
+		}
+	}
+	if dropped == nil || dropped.Item.ID != 100 || dropped.Body.Position != g.World.Entities[charA.ID].Body.Position {
+		t.Fatalf("Expected the item on the ground at the character.")
+	}
+
+	body := g.World.Entities[charB.ID].Body
+	body.Position = physics.Vect2{X: 6000, Y: 5000}
+	g.World.Space.UpdateEntity(body)
+	g.PickupItem(b, &messages.PickupItem{EntityID: dropped.ID})
+	if len(charB.InventoryItems) != 0 {
+		t.Fatalf("Picked up an item out of range.")
+	}
+	body.Position = physics.Vect2{X: 5050, Y: 5000}
+	g.World.Space.UpdateEntity(body)
+	g.PickupItem(b, &messages.PickupItem{EntityID: dropped.ID})
+	g.PickupItem(a, &messages.PickupItem{EntityID: dropped.ID})
+	if len(charB.InventoryItems) != 1 || len(charA.InventoryItems) != 0 {
+		t.Fatalf("Expected only b to pick up the item.")
+	}
+	if _, ok := g.World.Entities[dropped.ID]; ok {
+		t.Fatalf("Picked up item should leave the world.")
+	}
+	if update := lastInventory(t, out, b); len(update.Inventory) != 1 || update.Inventory[0].ID != 100 {
+		t.Fatalf("Expected b's inventory to have the item: %v", update)
+	}
+}
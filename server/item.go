@@ -1,5 +1,7 @@
 package server
 
+import "github.com/lologarithm/survival/server/messages"
+
 // ItemSlot is the constant for slot type
 type ItemSlot uint8
 
@@ -13,10 +15,46 @@ const (
 	SpiritSlot
 )
 
+// NumSlots is how many slots a character can equip items in, EquippedItems is indexed by ItemSlot.
+const NumSlots = int(SpiritSlot) + 1
+
+// Item kinds
+const (
+	UnknownItem uint16 = iota
+	StoneItem
+	WoodItem
+	BerryItem
+	ClubItem
+	StoneAxeItem
+	ShieldItem
+	CharmItem
+	TeaItem
+)
+
+// MaxStack is how many of a resource can be stacked in one item.
+const MaxStack = 50
+
 // Item represents
 type Item struct {
 	Stats // Item stats -- added to the bearer's own stats when equipped.
 
-	ID   uint32   // Unique ID for this item
-	Slot ItemSlot // Slot this item can be equipped in.
+	ID    uint32   // Unique ID for this item
+	Kind  uint16   // What kind of item this is.
+	Count uint16   // How many are stacked in this item, resources stack and equipment is always 1.
+	Slot  ItemSlot // Slot this item can be equipped in.
+}
+
+// stacks is true if more of the kind can be added to this item.
+func (it *Item) stacks(kind uint16) bool {
+	return it.Kind == kind && it.Slot == OtherSlot && it.Count < MaxStack
+}
+
+func (it *Item) toMsg() *messages.Item {
+	return &messages.Item{
+		ID:    it.ID,
+		Kind:  it.Kind,
+		Count: it.Count,
+		Slot:  byte(it.Slot),
+		Stats: it.Stats.toMsg(),
+	}
 }
@@ -15,16 +15,27 @@ type GameStatus byte
 const (
 	UnknownStatus GameStatus = 0
 	RunningStatus GameStatus = iota
+	EndedStatus
+)
+
+// Reasons sent in JoinGameFailed when a player can't join or create a game.
+const (
+	JoinGameNotFound    byte = iota + 1 // No game with that ID
+	JoinGameFull                        // Game already has MaxPlayers
+	JoinGameEnded                       // Game is no longer running
+	JoinGameNoCharacter                 // User hasn't logged in to an account yet
+	JoinGameAlreadyIn                   // User is already in a game
 )
 
 // GameManager manages all connected users and games.
 type GameManager struct {
 	// Player data
-	Users      []*User
-	Games      map[uint32]*GameSession
-	NextGameID uint32 // TODO: this shouldn't just be a number..
+	Users       []*User
+	Games       map[uint32]*GameSession
+	NextGameID  uint32         // TODO: this shouldn't just be a number..
+	GamePlayers map[uint32]int // Number of users in each game.
 
-	FromGames   chan GameMessage // Manager reads this only, all games created write only
+	FromGames   chan InternalMessage // Manager reads this only, all games created write only
 	FromNetwork <-chan GameMessage
 	ToNetwork   chan<- OutgoingMessage
 	Exit        chan int
@@ -42,7 +53,8 @@ func NewGameManager(exit chan int, fromNetwork chan GameMessage, toNetwork chan
 	gm := &GameManager{
 		Users:       make([]*User, math.MaxUint16),
 		Games:       map[uint32]*GameSession{},
-		FromGames:   make(chan GameMessage, 100),
+		GamePlayers: map[uint32]int{},
+		FromGames:   make(chan InternalMessage, 100),
 		FromNetwork: fromNetwork,
 		ToNetwork:   toNetwork,
 		Exit:        exit,
@@ -81,7 +93,7 @@ func (gm *GameManager) ProcessNetMsg(msg GameMessage) {
 	case messages.LoginMsgType:
 		gm.loginUser(msg)
 	case messages.JoinGameMsgType:
-		// TODO: make this work
+		gm.joinGame(msg)
 	case messages.CreateGameMsgType:
 		gm.createGame(msg)
 	case messages.ListGamesMsgType:
@@ -90,10 +102,13 @@ func (gm *GameManager) ProcessNetMsg(msg GameMessage) {
 			Names: []string{},
 		}
 		for key, g := range gm.Games {
+			if g.Status() != RunningStatus {
+				continue
+			}
 			gameList.IDs = append(gameList.IDs, uint32(key))
 			gameList.Names = append(gameList.Names, g.Name)
 		}
-		resp := NewOutgoingMsg(msg.client, messages.ListGamesRespMsgType, gameList)
+		resp := NewReliableMsg(msg.client, messages.ListGamesRespMsgType, gameList)
 		gm.ToNetwork <- resp
 	case messages.EndGameMsgType:
 		tmsg := msg.net.(*messages.EndGame)
@@ -101,8 +116,7 @@ func (gm *GameManager) ProcessNetMsg(msg GameMessage) {
 		if msg.client != nil {
 			gameid = gm.Users[msg.client.ID].GameID
 		}
-		fmt.Printf("Ended game: %d", gameid)
-		gm.Games[gameid] = nil
+		gm.endGame(gameid)
 
 	default:
 		// These messages probably go to a game?
@@ -110,41 +124,114 @@ func (gm *GameManager) ProcessNetMsg(msg GameMessage) {
 	}
 }
 
+// createGame starts a new game with the user in it. Creating a game joins it, so it fails for the
+// same reasons joining does and is answered with a JoinGameFailed for game 0.
 func (gm *GameManager) createGame(msg GameMessage) {
 	cgm := msg.net.(*messages.CreateGame)
+	reason := byte(0)
+	user := gm.Users[msg.client.ID]
+	_, inGame := gm.Games[user.GameID]
+	switch {
+	case inGame:
+		reason = JoinGameAlreadyIn
+	case len(user.Accounts) == 0:
+		reason = JoinGameNoCharacter
+	}
+	if reason != 0 {
+		gm.ToNetwork <- NewReliableMsg(msg.client, messages.JoinGameFailedMsgType, &messages.JoinGameFailed{
+			Reason: reason,
+		})
+		return
+	}
 	gm.NextGameID++
 
 	netchan := make(chan GameMessage, 100)
 	g := NewGame(cgm.Name, gm.FromGames, netchan, gm.ToNetwork)
 	g.ID = gm.NextGameID
+	g.toGame = netchan
+	g.setStatus(RunningStatus)
 	g.SpawnChunk(0, 0)
+
+	// Build the response before starting the game so the world isn't read while it runs.
+	cgr := &messages.CreateGameResp{
+		Name: cgm.Name,
+		Game: &messages.GameConnected{
+			ID:       g.ID,
+			Seed:     g.Seed,
+			Entities: g.World.EntitiesMsg(),
+		},
+	}
 	go g.Run()
 
-	for _, a := range gm.Users[msg.client.ID].Accounts {
+	gm.Games[g.ID] = g
+	gm.addUserToGame(msg.client, g, false)
+	resp := NewReliableMsg(msg.client, messages.CreateGameRespMsgType, cgr)
+	gm.ToNetwork <- resp
+}
+
+// joinGame adds the user to an existing game. The game itself replies with GameConnected
+// once the player is in the world, failures are sent back as JoinGameFailed.
+func (gm *GameManager) joinGame(msg GameMessage) {
+	tmsg := msg.net.(*messages.JoinGame)
+	reason := byte(0)
+	user := gm.Users[msg.client.ID]
+	_, inGame := gm.Games[user.GameID]
+	g, ok := gm.Games[tmsg.ID]
+	switch {
+	case !ok:
+		reason = JoinGameNotFound
+	case g.Status() != RunningStatus:
+		reason = JoinGameEnded
+	case inGame:
+		reason = JoinGameAlreadyIn
+	case gm.GamePlayers[g.ID] >= MaxPlayers:
+		reason = JoinGameFull
+	case len(user.Accounts) == 0:
+		reason = JoinGameNoCharacter
+	}
+	if reason != 0 {
+		gm.ToNetwork <- NewReliableMsg(msg.client, messages.JoinGameFailedMsgType, &messages.JoinGameFailed{
+			ID:     tmsg.ID,
+			Reason: reason,
+		})
+		return
+	}
+	gm.addUserToGame(msg.client, g, true)
+}
+
+// addUserToGame adds all the user's characters to the game and hooks the client up to the game's channel.
+// Each game gets its own copy of the character, the game sends it back with SaveCharacter when the player leaves.
+func (gm *GameManager) addUserToGame(client *Client, g *GameSession, join bool) {
+	user := gm.Users[client.ID]
+	for idx, a := range user.Accounts {
 		g.FromGameManager <- AddPlayer{
 			Entity: &Entity{
 				Name: a.Character.Name,
 			},
-			Client: msg.client,
+			AccountID: a.ID,
+			Character: a.Character.clone(),
+			Client:    client,
+			Join:      join && idx == len(user.Accounts)-1,
 		}
 	}
-
-	gm.Games[gm.NextGameID] = g
-	cgr := &messages.CreateGameResp{
-		Name: cgm.Name,
-		Game: &messages.GameConnected{
-			ID:       gm.NextGameID,
-			Seed:     g.Seed,
-			Entities: g.World.EntitiesMsg(),
-		},
+	user.GameID = g.ID
+	gm.GamePlayers[g.ID]++
+	client.FromGameManager <- ConnectedGame{
+		ToGame: g.toGame,
+		ID:     g.ID,
 	}
-	gm.Users[msg.client.ID].GameID = msg.client.ID
-	msg.client.FromGameManager <- ConnectedGame{
-		ToGame: netchan,
-		ID:     msg.client.ID,
+}
+
+// endGame marks the game as ended and forgets it so nobody else can join it.
+func (gm *GameManager) endGame(id uint32) {
+	g, ok := gm.Games[id]
+	if !ok {
+		return
 	}
-	resp := NewOutgoingMsg(msg.client, messages.CreateGameRespMsgType, cgr)
-	gm.ToNetwork <- resp
+	fmt.Printf("Ended game: %d\n", id)
+	g.setStatus(EndedStatus)
+	delete(gm.Games, id)
+	delete(gm.GamePlayers, id)
 }
 
 func (gm *GameManager) handleConnection(msg GameMessage) {
@@ -160,8 +247,9 @@ func (gm *GameManager) handleConnection(msg GameMessage) {
 func (gm *GameManager) handleDisconnect(msg GameMessage) {
 	// message active game that player disconnected.
 	gameid := gm.Users[msg.client.ID].GameID
-	if gm.Games[gameid] != nil {
-		gm.Games[gameid].FromGameManager <- RemovePlayer{Client: msg.client}
+	if g, ok := gm.Games[gameid]; ok && g.Status() == RunningStatus {
+		g.FromGameManager <- RemovePlayer{Client: msg.client}
+		gm.GamePlayers[gameid]--
 	}
 	// Then clear out the user.
 	gm.Users[msg.client.ID] = nil
@@ -178,14 +266,10 @@ func (gm *GameManager) createAccount(msg GameMessage) {
 		gm.AccountID++
 		gm.CharID++
 		gm.Accounts[gm.AccountID] = &Account{
-			ID:       gm.AccountID,
-			Name:     netmsg.Name,
-			Password: netmsg.Password,
-			Character: &Character{
-				Name:           netmsg.CharName,
-				EquippedItems:  make([]*Item, 6),
-				InventoryItems: []*Item{},
-			},
+			ID:        gm.AccountID,
+			Name:      netmsg.Name,
+			Password:  netmsg.Password,
+			Character: NewCharacter(netmsg.CharName, KitStats(netmsg.DefaultKit)),
 		}
 
 		ac.AccountID = gm.AccountID
@@ -198,7 +282,7 @@ func (gm *GameManager) createAccount(msg GameMessage) {
 		gm.Users[msg.client.ID].Accounts = append(gm.Users[msg.client.ID].Accounts, gm.Accounts[gm.AccountID])
 	}
 
-	resp := NewOutgoingMsg(msg.client, messages.CreateAcctRespMsgType, ac)
+	resp := NewReliableMsg(msg.client, messages.CreateAcctRespMsgType, ac)
 	gm.ToNetwork <- resp
 }
 
@@ -220,13 +304,22 @@ func (gm *GameManager) loginUser(msg GameMessage) {
 			gm.Users[msg.client.ID].Accounts = append(gm.Users[msg.client.ID].Accounts, acct)
 		}
 	}
-	resp := NewOutgoingMsg(msg.client, messages.LoginRespMsgType, &lr)
+	resp := NewReliableMsg(msg.client, messages.LoginRespMsgType, &lr)
 	gm.ToNetwork <- resp
 }
 
 // ProcessGameMsg is used to process messages from an individual game to the main server controller.
-func (gm *GameManager) ProcessGameMsg(msg GameMessage) {
-	switch msg.mtype {
+func (gm *GameManager) ProcessGameMsg(msg InternalMessage) {
+	switch tmsg := msg.(type) {
+	case GameMessage:
+		switch tmsg.mtype {
+		case messages.EndGameMsgType:
+			gm.endGame(tmsg.net.(*messages.EndGame).GameID)
+		}
+	case SaveCharacter:
+		if acct := gm.Accounts[tmsg.AccountID]; acct != nil {
+			acct.Character = tmsg.Character
+		}
 	}
 }
 
@@ -234,7 +327,6 @@ func (gm *GameManager) ProcessGameMsg(msg GameMessage) {
 func NewOutgoingMsg(dest *Client, tp messages.MessageType, msg messages.Net) OutgoingMessage {
 	frame := messages.Frame{
 		MsgType:       tp,
-		Seq:           1,
 		ContentLength: uint16(msg.Len()),
 	}
 	resp := OutgoingMessage{
@@ -246,3 +338,10 @@ func NewOutgoingMsg(dest *Client, tp messages.MessageType, msg messages.Net) Out
 	}
 	return resp
 }
+
+// NewReliableMsg creates a new message that will be resent to the client until it is acked.
+func NewReliableMsg(dest *Client, tp messages.MessageType, msg messages.Net) OutgoingMessage {
+	resp := NewOutgoingMsg(dest, tp, msg)
+	resp.reliable = true
+	return resp
+}
@@ -0,0 +1,162 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lologarithm/survival/server/messages"
+)
+
+// waitForMsg reads outgoing messages until one of the given type shows up.
+func waitForMsg(t *testing.T, out chan OutgoingMessage, mtype messages.MessageType) OutgoingMessage {
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case msg := <-out:
+			if msg.msg.Frame.MsgType == mtype {
+				return msg
+			}
+		case <-timeout:
+			t.Fatalf("Timed out waiting for message type %d", mtype)
+		}
+	}
+}
+
+func newTestUser(gm *GameManager, id uint32, name string) *Client {
+	c := &Client{ID: id, FromGameManager: make(chan InternalMessage, 10)}
+	gm.ProcessNetMsg(GameMessage{client: c, net: &messages.Connected{}, mtype: messages.ConnectedMsgType})
+	if name != "" {
+		gm.ProcessNetMsg(GameMessage{client: c, net: &messages.CreateAcct{Name: name, CharName: name}, mtype: messages.CreateAcctMsgType})
+	}
+	return c
+}
+
+func TestJoinGame(t *testing.T) {
+	out := make(chan OutgoingMessage, 100)
+	gm := NewGameManager(make(chan int, 1), nil, out)
+
+	host := newTestUser(gm, 1, "host")
+	gm.ProcessNetMsg(GameMessage{client: host, net: &messages.CreateGame{Name: "game"}, mtype: messages.CreateGameMsgType})
+	cgr := waitForMsg(t, out, messages.CreateGameRespMsgType).msg.NetMsg.(*messages.CreateGameResp)
+	g := gm.Games[cgr.Game.ID]
+	defer func() { g.Exit <- 1 }()
+
+	joinFails := func(c *Client, id uint32, reason byte) {
+		gm.ProcessNetMsg(GameMessage{client: c, net: &messages.JoinGame{ID: id}, mtype: messages.JoinGameMsgType})
+		failed := waitForMsg(t, out, messages.JoinGameFailedMsgType).msg.NetMsg.(*messages.JoinGameFailed)
+		if failed.Reason != reason || failed.ID != id {
+			t.Fatalf("Expected join to fail with %d, got %d", reason, failed.Reason)
+		}
+	}
+
+	player := newTestUser(gm, 2, "player")
+	joinFails(player, g.ID+1, JoinGameNotFound)
+	joinFails(newTestUser(gm, 3, ""), g.ID, JoinGameNoCharacter)
+
+	gm.ProcessNetMsg(GameMessage{client: player, net: &messages.JoinGame{ID: g.ID}, mtype: messages.JoinGameMsgType})
+	connected := (<-player.FromGameManager).(ConnectedGame)
+	if connected.ID != g.ID || connected.ToGame == nil {
+		t.Fatalf("Client was not connected to the game: %v", connected)
+	}
+	gc := waitForMsg(t, out, messages.GameConnectedMsgType).msg.NetMsg.(*messages.GameConnected)
+	if gc.ID != g.ID || gc.Seed != g.Seed || len(gc.Entities) == 0 {
+		t.Fatalf("Incorrect game connected message: %d %d %d", gc.ID, gc.Seed, len(gc.Entities))
+	}
+	if gm.Users[player.ID].GameID != g.ID || gm.GamePlayers[g.ID] != 2 {
+		t.Fatalf("Manager did not track the player in the game.")
+	}
+
+	joinFails(player, g.ID, JoinGameAlreadyIn)
+
+	gm.GamePlayers[g.ID] = MaxPlayers
+	joinFails(newTestUser(gm, 4, "late"), g.ID, JoinGameFull)
+
+	gm.ProcessGameMsg(GameMessage{net: &messages.EndGame{GameID: g.ID}, mtype: messages.EndGameMsgType})
+	if _, ok := gm.Games[g.ID]; ok || g.Status() != EndedStatus {
+		t.Fatalf("Ended game was not removed from the manager.")
+	}
+	joinFails(newTestUser(gm, 5, "later"), g.ID, JoinGameNotFound)
+}
+
+// TestCreateGameFails checks that users already in a game or without a character can't create one.
+func TestCreateGameFails(t *testing.T) {
+	out := make(chan OutgoingMessage, 100)
+	gm := NewGameManager(make(chan int, 1), nil, out)
+
+	host := newTestUser(gm, 1, "host")
+	gm.ProcessNetMsg(GameMessage{client: host, net: &messages.CreateGame{Name: "game"}, mtype: messages.CreateGameMsgType})
+	cgr := waitForMsg(t, out, messages.CreateGameRespMsgType).msg.NetMsg.(*messages.CreateGameResp)
+	g := gm.Games[cgr.Game.ID]
+	defer func() { g.Exit <- 1 }()
+
+	createFails := func(c *Client, reason byte) {
+		gm.ProcessNetMsg(GameMessage{client: c, net: &messages.CreateGame{Name: "other"}, mtype: messages.CreateGameMsgType})
+		failed := waitForMsg(t, out, messages.JoinGameFailedMsgType).msg.NetMsg.(*messages.JoinGameFailed)
+		if failed.Reason != reason {
+			t.Fatalf("Expected create to fail with %d, got %d", reason, failed.Reason)
+		}
+	}
+	createFails(host, JoinGameAlreadyIn)
+	createFails(newTestUser(gm, 2, ""), JoinGameNoCharacter)
+	if len(gm.Games) != 1 || gm.Users[host.ID].GameID != g.ID || gm.GamePlayers[g.ID] != 1 {
+		t.Fatalf("Failed creates changed the manager: %d games, host in %d, %d players", len(gm.Games), gm.Users[host.ID].GameID, gm.GamePlayers[g.ID])
+	}
+}
+
+// TestJoinStoppedGame checks that a game that stopped running can't be joined
+// before the manager hears that it ended.
+func TestJoinStoppedGame(t *testing.T) {
+	out := make(chan OutgoingMessage, 100)
+	gm := NewGameManager(make(chan int, 1), nil, out)
+
+	host := newTestUser(gm, 1, "host")
+	gm.ProcessNetMsg(GameMessage{client: host, net: &messages.CreateGame{Name: "game"}, mtype: messages.CreateGameMsgType})
+	cgr := waitForMsg(t, out, messages.CreateGameRespMsgType).msg.NetMsg.(*messages.CreateGameResp)
+	g := gm.Games[cgr.Game.ID]
+
+	g.Exit <- 1
+	timeout := time.After(time.Second)
+	for g.Status() != EndedStatus {
+		select {
+		case <-timeout:
+			t.Fatalf("Game did not mark itself ended when it stopped.")
+		case <-time.After(time.Millisecond):
+		}
+	}
+	player := newTestUser(gm, 2, "player")
+	gm.ProcessNetMsg(GameMessage{client: player, net: &messages.JoinGame{ID: g.ID}, mtype: messages.JoinGameMsgType})
+	failed := waitForMsg(t, out, messages.JoinGameFailedMsgType).msg.NetMsg.(*messages.JoinGameFailed)
+	if failed.Reason != JoinGameEnded {
+		t.Fatalf("Expected join to fail with %d, got %d", JoinGameEnded, failed.Reason)
+	}
+}
+
+// TestCharacterCarriesOver checks that what happens to a character in a game is saved to
+// its account when the player leaves and brought into the next game.
+func TestCharacterCarriesOver(t *testing.T) {
+	gm := NewGameManager(make(chan int, 1), nil, make(chan OutgoingMessage, 100))
+	c := newTestUser(gm, 1, "player")
+	acct := gm.Users[c.ID].Accounts[0]
+
+	out := make(chan OutgoingMessage, 100)
+	g := NewGame("A", gm.FromGames, nil, out)
+	g.addPlayer(AddPlayer{Entity: &Entity{Name: "player"}, AccountID: acct.ID, Character: acct.Character.clone(), Client: c})
+	char := g.Clients[c.ID].Accounts[0].Character
+	char.CurrentStats.HP = 50
+	char.giveItems(WoodItem, 3, g.nextID)
+	if acct.Character.CurrentStats.HP == 50 || len(acct.Character.InventoryItems) != 0 {
+		t.Fatalf("Game changed the account's character while playing.")
+	}
+
+	g.removePlayer(RemovePlayer{Client: c})
+	gm.ProcessGameMsg(<-gm.FromGames)
+	if acct.Character.CurrentStats.HP != 50 || len(acct.Character.InventoryItems) != 1 || acct.Character.InventoryItems[0].Count != 3 {
+		t.Fatalf("Character was not saved when the player left: %v", acct.Character)
+	}
+
+	next := NewGame("B", gm.FromGames, nil, out)
+	next.addPlayer(AddPlayer{Entity: &Entity{Name: "player"}, AccountID: acct.ID, Character: acct.Character.clone(), Client: c})
+	if got := next.Clients[c.ID].Accounts[0].Character; got.CurrentStats.HP != 50 || len(got.InventoryItems) != 1 {
+		t.Fatalf("Character did not carry over into the next game: %v", got)
+	}
+}
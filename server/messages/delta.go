@@ -0,0 +1,194 @@
+package messages
+
+import (
+	"bytes"
+	"encoding/binary"
+	"sort"
+)
+
+// Bits in an entity delta mask, one per Entity field that is included.
+const (
+	DeltaEType uint16 = 1 << iota
+	DeltaSeed
+	DeltaX
+	DeltaY
+	DeltaHeight
+	DeltaWidth
+	DeltaAngle
+	DeltaHealth
+	DeltaRemoved // Entity is gone, no fields follow.
+
+	DeltaAll = DeltaEType | DeltaSeed | DeltaX | DeltaY | DeltaHeight | DeltaWidth | DeltaAngle | DeltaHealth
+)
+
+// DiffEntity returns the mask of fields that differ between old and cur.
+// A nil old means every field, a nil cur means the entity was removed.
+func DiffEntity(old, cur *Entity) uint16 {
+	if cur == nil {
+		if old == nil {
+			return 0
+		}
+		return DeltaRemoved
+	}
+	if old == nil {
+		return DeltaAll
+	}
+	mask := uint16(0)
+	if old.EType != cur.EType {
+		mask |= DeltaEType
+	}
+	if old.Seed != cur.Seed {
+		mask |= DeltaSeed
+	}
+	if old.X != cur.X {
+		mask |= DeltaX
+	}
+	if old.Y != cur.Y {
+		mask |= DeltaY
+	}
+	if old.Height != cur.Height {
+		mask |= DeltaHeight
+	}
+	if old.Width != cur.Width {
+		mask |= DeltaWidth
+	}
+	if old.Angle != cur.Angle {
+		mask |= DeltaAngle
+	}
+	if old.HealthPercent != cur.HealthPercent {
+		mask |= DeltaHealth
+	}
+	return mask
+}
+
+// AppendEntityDelta writes the fields of cur that changed since old to the buffer.
+// Writes nothing if nothing changed. Use the same rules as DiffEntity for nil entities.
+func AppendEntityDelta(buffer *bytes.Buffer, old, cur *Entity) {
+	mask := DiffEntity(old, cur)
+	if mask == 0 {
+		return
+	}
+	id := uint32(0)
+	if cur != nil {
+		id = cur.ID
+	} else {
+		id = old.ID
+	}
+	binary.Write(buffer, binary.LittleEndian, id)
+	binary.Write(buffer, binary.LittleEndian, mask)
+	if mask&DeltaEType != 0 {
+		binary.Write(buffer, binary.LittleEndian, cur.EType)
+	}
+	if mask&DeltaSeed != 0 {
+		binary.Write(buffer, binary.LittleEndian, cur.Seed)
+	}
+	if mask&DeltaX != 0 {
+		binary.Write(buffer, binary.LittleEndian, cur.X)
+	}
+	if mask&DeltaY != 0 {
+		binary.Write(buffer, binary.LittleEndian, cur.Y)
+	}
+	if mask&DeltaHeight != 0 {
+		binary.Write(buffer, binary.LittleEndian, cur.Height)
+	}
+	if mask&DeltaWidth != 0 {
+		binary.Write(buffer, binary.LittleEndian, cur.Width)
+	}
+	if mask&DeltaAngle != 0 {
+		binary.Write(buffer, binary.LittleEndian, cur.Angle)
+	}
+	if mask&DeltaHealth != 0 {
+		buffer.WriteByte(cur.HealthPercent)
+	}
+}
+
+// EntityDeltas encodes the changes to get from the base state to the current one, both keyed by entity ID.
+// Entities are written in ID order so the same states always encode the same way.
+func EntityDeltas(base, cur map[uint32]*Entity) []byte {
+	ids := make([]uint32, 0, len(cur))
+	for id := range cur {
+		ids = append(ids, id)
+	}
+	for id := range base {
+		if _, ok := cur[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	buf := &bytes.Buffer{}
+	for _, id := range ids {
+		AppendEntityDelta(buf, base[id], cur[id])
+	}
+	return buf.Bytes()
+}
+
+// ApplyEntityDeltas returns a new state from base with the encoded deltas applied. The base is not changed.
+// Returns false if the deltas are malformed or change an entity the base doesn't have.
+func ApplyEntityDeltas(base map[uint32]*Entity, deltas []byte) (map[uint32]*Entity, bool) {
+	state := make(map[uint32]*Entity, len(base))
+	for id, e := range base {
+		state[id] = e
+	}
+	buffer := bytes.NewBuffer(deltas)
+	for buffer.Len() > 0 {
+		if buffer.Len() < 6 {
+			return nil, false
+		}
+		var id uint32
+		var mask uint16
+		binary.Read(buffer, binary.LittleEndian, &id)
+		binary.Read(buffer, binary.LittleEndian, &mask)
+		if mask&DeltaRemoved != 0 {
+			delete(state, id)
+			continue
+		}
+		var e Entity
+		if old, ok := state[id]; ok {
+			e = *old
+		} else if mask != DeltaAll {
+			return nil, false
+		}
+		e.ID = id
+		if buffer.Len() < deltaLen(mask) {
+			return nil, false
+		}
+		if mask&DeltaEType != 0 {
+			binary.Read(buffer, binary.LittleEndian, &e.EType)
+		}
+		if mask&DeltaSeed != 0 {
+			binary.Read(buffer, binary.LittleEndian, &e.Seed)
+		}
+		if mask&DeltaX != 0 {
+			binary.Read(buffer, binary.LittleEndian, &e.X)
+		}
+		if mask&DeltaY != 0 {
+			binary.Read(buffer, binary.LittleEndian, &e.Y)
+		}
+		if mask&DeltaHeight != 0 {
+			binary.Read(buffer, binary.LittleEndian, &e.Height)
+		}
+		if mask&DeltaWidth != 0 {
+			binary.Read(buffer, binary.LittleEndian, &e.Width)
+		}
+		if mask&DeltaAngle != 0 {
+			binary.Read(buffer, binary.LittleEndian, &e.Angle)
+		}
+		if mask&DeltaHealth != 0 {
+			e.HealthPercent, _ = buffer.ReadByte()
+		}
+		state[id] = &e
+	}
+	return state, true
+}
+
+// deltaLen is the number of bytes of fields that follow the header for a mask.
+func deltaLen(mask uint16) int {
+	n := 0
+	sizes := []int{2, 8, 4, 4, 4, 4, 2, 1}
+	for i, size := range sizes {
+		if mask&(1<<uint(i)) != 0 {
+			n += size
+		}
+	}
+	return n
+}
@@ -0,0 +1,45 @@
+package messages
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEntityDeltas(t *testing.T) {
+	base := map[uint32]*Entity{
+		1: {ID: 1, EType: 4, Seed: 99, X: 10, Y: 20, Height: 22, Width: 46},
+		2: {ID: 2, EType: 1, X: 500, Y: 500, Height: 10, Width: 10},
+		3: {ID: 3, EType: 3, X: 900, Y: 100, Height: 100, Width: 100},
+	}
+	cur := map[uint32]*Entity{
+		1: {ID: 1, EType: 4, Seed: 99, X: 15, Y: 20, Height: 22, Width: 46, Angle: 90},
+		2: {ID: 2, EType: 1, X: 500, Y: 500, Height: 10, Width: 10},
+		4: {ID: 4, EType: 5, Seed: 7, X: -30, Y: -40, Height: 5, Width: 5, HealthPercent: 100},
+	}
+	deltas := EntityDeltas(base, cur)
+	// Moved entity sends X and Angle, unchanged sends nothing, removed sends a header, new sends everything.
+	if want := (6 + 4 + 2) + 6 + (6 + deltaLen(DeltaAll)); len(deltas) != want {
+		t.Fatalf("Expected %d bytes of deltas, got %d", want, len(deltas))
+	}
+	state, ok := ApplyEntityDeltas(base, deltas)
+	if !ok {
+		t.Fatalf("Failed to apply deltas.")
+	}
+	if !reflect.DeepEqual(state, cur) {
+		t.Fatalf("Applied state doesn't match: %v", state)
+	}
+	if base[1].X != 10 || len(base) != 3 {
+		t.Fatalf("Applying deltas changed the base state.")
+	}
+
+	if len(EntityDeltas(cur, cur)) != 0 {
+		t.Fatalf("Expected no deltas between equal states.")
+	}
+	if _, ok := ApplyEntityDeltas(base, deltas[:len(deltas)-1]); ok {
+		t.Fatalf("Truncated deltas should fail to apply.")
+	}
+	// Changing only some fields of an entity the base doesn't have can't be applied.
+	if _, ok := ApplyEntityDeltas(map[uint32]*Entity{}, EntityDeltas(base, cur)[:12]); ok {
+		t.Fatalf("Partial delta for an unknown entity should fail to apply.")
+	}
+}
@@ -0,0 +1,44 @@
+package messages
+
+import (
+	"fmt"
+	"math"
+)
+
+// MultipartOverhead is the number of bytes each part adds on top of its content (frame + multipart header).
+var MultipartOverhead = (&Multipart{}).Len() + FrameLen
+
+// SplitPacket cuts packed message bytes into Multipart packets that are each at most
+// maxPacketSize bytes long once packed. Use a Reassembler to put them back together.
+// Returns an error if maxPacketSize is too small to fit any content or the data
+// needs more parts than a Multipart can number.
+func SplitPacket(groupID uint32, data []byte, maxPacketSize int) ([]*Packet, error) {
+	maxsize := maxPacketSize - MultipartOverhead
+	if maxsize <= 0 {
+		return nil, fmt.Errorf("packet size %d has no room for content", maxPacketSize)
+	}
+	parts := (len(data) + maxsize - 1) / maxsize
+	if parts == 0 {
+		parts = 1
+	}
+	if parts > math.MaxUint16 {
+		return nil, fmt.Errorf("%d bytes needs %d parts, more than %d", len(data), parts, math.MaxUint16)
+	}
+	packets := make([]*Packet, parts)
+	bstart := 0
+	for i := 0; i < parts; i++ {
+		bend := bstart + maxsize
+		if bend > len(data) {
+			bend = len(data)
+		}
+		wrapper := &Multipart{
+			ID:       uint16(i),
+			GroupID:  groupID,
+			NumParts: uint16(parts),
+			Content:  data[bstart:bend],
+		}
+		packets[i] = NewPacket(MultipartMsgType, wrapper)
+		bstart = bend
+	}
+	return packets, nil
+}
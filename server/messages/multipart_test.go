@@ -0,0 +1,115 @@
+package messages
+
+import (
+	"bytes"
+	"math"
+	"math/rand"
+	"testing"
+	"testing/quick"
+	"time"
+)
+
+func TestSplitExactMultiple(t *testing.T) {
+	maxPacket := 512
+	maxsize := maxPacket - MultipartOverhead
+	for _, n := range []int{1, 2, 3, 10} {
+		data := make([]byte, maxsize*n)
+		packets, err := SplitPacket(1, data, maxPacket)
+		if err != nil || len(packets) != n {
+			t.Fatalf("Payload of exactly %d parts was split into %d parts.", n, len(packets))
+		}
+		for _, p := range packets {
+			if l := len(p.NetMsg.(*Multipart).Content); l != maxsize {
+				t.Fatalf("Part has %d bytes, expected every part to be full (%d).", l, maxsize)
+			}
+		}
+	}
+}
+
+func TestSplitTooManyParts(t *testing.T) {
+	if _, err := SplitPacket(1, make([]byte, 10), MultipartOverhead); err == nil {
+		t.Fatalf("Expected an error for a packet size with no room for content.")
+	}
+	data := make([]byte, math.MaxUint16+1)
+	if _, err := SplitPacket(1, data, MultipartOverhead+1); err == nil {
+		t.Fatalf("Expected an error for more parts than a Multipart can number.")
+	}
+	if packets, err := SplitPacket(1, data[:math.MaxUint16], MultipartOverhead+1); err != nil || len(packets) != math.MaxUint16 {
+		t.Fatalf("Expected %d parts, got %d: %v", math.MaxUint16, len(packets), err)
+	}
+}
+
+// TestSplitRoundTrip checks that any payload split at any packet size is never over
+// the packet size and comes back out of a Reassembler unchanged, in any order.
+func TestSplitRoundTrip(t *testing.T) {
+	roundTrip := func(seed int64, size uint16, maxPacket uint16) bool {
+		rng := rand.New(rand.NewSource(seed))
+		maxPacketSize := int(maxPacket%1024) + MultipartOverhead + 1
+		// Keep the message small enough that its length fits in the frame.
+		content := make([]byte, int(size)%(math.MaxUint16-(&Multipart{}).Len()))
+		rng.Read(content)
+		data := NewPacket(MultipartMsgType, &Multipart{Content: content}).Pack()
+
+		packets, err := SplitPacket(uint32(seed), data, maxPacketSize)
+		if err != nil {
+			return false
+		}
+		if len(packets) > DefaultMaxParts {
+			return true
+		}
+		r := NewReassembler()
+		r.MaxParts = len(packets)
+		now := time.Now()
+		var result Packet
+		ok := false
+		for i, idx := range rng.Perm(len(packets)) {
+			p := packets[idx]
+			if len(p.Pack()) > maxPacketSize || p.Len() != len(p.Pack()) {
+				return false
+			}
+			// Re-parse from bytes like the network would.
+			wire, pok := NextPacket(p.Pack())
+			if !pok {
+				return false
+			}
+			result, ok = r.Add(wire.NetMsg.(*Multipart), now)
+			if ok != (i == len(packets)-1) {
+				return false
+			}
+		}
+		return ok && bytes.Equal(result.NetMsg.(*Multipart).Content, content)
+	}
+	if err := quick.Check(roundTrip, &quick.Config{MaxCount: 500}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLargeMasterFrameRoundTrip(t *testing.T) {
+	mf := &GameMasterFrame{ID: 7, Entities: make([]*Entity, 1900)}
+	for i := range mf.Entities {
+		mf.Entities[i] = &Entity{ID: uint32(i), EType: 1, Seed: uint64(i) * 31, X: int32(i), Y: -int32(i), Height: 10, Width: 10}
+	}
+	data := NewPacket(GameMasterFrameMsgType, mf).Pack()
+	r := NewReassembler()
+	var result Packet
+	ok := false
+	packets, err := SplitPacket(1, data, 512)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range packets {
+		result, ok = r.Add(p.NetMsg.(*Multipart), time.Now())
+	}
+	if !ok {
+		t.Fatalf("Master frame was not reassembled.")
+	}
+	out := result.NetMsg.(*GameMasterFrame)
+	if out.ID != mf.ID || len(out.Entities) != len(mf.Entities) {
+		t.Fatalf("Reassembled frame doesn't match, ID: %d entities: %d", out.ID, len(out.Entities))
+	}
+	for i, e := range out.Entities {
+		if *e != *mf.Entities[i] {
+			t.Fatalf("Entity %d doesn't match: %v != %v", i, e, mf.Entities[i])
+		}
+	}
+}
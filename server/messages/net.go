@@ -38,12 +38,38 @@ const (
 	UseAbilityMsgType
 	AbilityResultMsgType
 	EndGameMsgType
+	KeyRequestMsgType
+	ServerKeyMsgType
+	SessionKeyMsgType
+	SessionReadyMsgType
+	SecureMsgType
+	JoinGameFailedMsgType
+	EntitiesEnteredMsgType
+	EntitiesLeftMsgType
+	GameDeltaFrameMsgType
+	FrameAckMsgType
+	ProjectileHitMsgType
+	CharacterDiedMsgType
+	StatsMsgType
+	ItemMsgType
+	PickupItemMsgType
+	DropItemMsgType
+	EquipItemMsgType
+	UnequipItemMsgType
+	InventoryUpdateMsgType
+	HarvestMsgType
+	CraftMsgType
+	CraftResultMsgType
+	ConsumeMsgType
+	CharacterStatusMsgType
 )
 
 // ParseNetMessage accepts input of raw bytes from a NetMessage. Parses and returns a Net message.
 func ParseNetMessage(packet Packet, content []byte) Net {
 	var msg Net
 	switch packet.Frame.MsgType {
+	case AckMsgType:
+		msg = &Ack{}
 	case MultipartMsgType:
 		msg = &Multipart{}
 	case HeartbeatMsgType:
@@ -86,6 +112,54 @@ func ParseNetMessage(packet Packet, content []byte) Net {
 		msg = &AbilityResult{}
 	case EndGameMsgType:
 		msg = &EndGame{}
+	case KeyRequestMsgType:
+		msg = &KeyRequest{}
+	case ServerKeyMsgType:
+		msg = &ServerKey{}
+	case SessionKeyMsgType:
+		msg = &SessionKey{}
+	case SessionReadyMsgType:
+		msg = &SessionReady{}
+	case SecureMsgType:
+		msg = &Secure{}
+	case JoinGameFailedMsgType:
+		msg = &JoinGameFailed{}
+	case EntitiesEnteredMsgType:
+		msg = &EntitiesEntered{}
+	case EntitiesLeftMsgType:
+		msg = &EntitiesLeft{}
+	case GameDeltaFrameMsgType:
+		msg = &GameDeltaFrame{}
+	case FrameAckMsgType:
+		msg = &FrameAck{}
+	case ProjectileHitMsgType:
+		msg = &ProjectileHit{}
+	case CharacterDiedMsgType:
+		msg = &CharacterDied{}
+	case StatsMsgType:
+		msg = &Stats{}
+	case ItemMsgType:
+		msg = &Item{}
+	case PickupItemMsgType:
+		msg = &PickupItem{}
+	case DropItemMsgType:
+		msg = &DropItem{}
+	case EquipItemMsgType:
+		msg = &EquipItem{}
+	case UnequipItemMsgType:
+		msg = &UnequipItem{}
+	case InventoryUpdateMsgType:
+		msg = &InventoryUpdate{}
+	case HarvestMsgType:
+		msg = &Harvest{}
+	case CraftMsgType:
+		msg = &Craft{}
+	case CraftResultMsgType:
+		msg = &CraftResult{}
+	case ConsumeMsgType:
+		msg = &Consume{}
+	case CharacterStatusMsgType:
+		msg = &CharacterStatus{}
 	default:
 		log.Printf("Unknown message type: %d", packet.Frame.MsgType)
 		return nil
@@ -94,6 +168,28 @@ func ParseNetMessage(packet Packet, content []byte) Net {
 	return msg
 }
 
+type Ack struct {
+	Seq uint16
+	Bits uint32
+}
+
+func (m *Ack) Serialize(buffer *bytes.Buffer) {
+	binary.Write(buffer, binary.LittleEndian, m.Seq)
+	binary.Write(buffer, binary.LittleEndian, m.Bits)
+}
+
+func (m *Ack) Deserialize(buffer *bytes.Buffer) {
+	binary.Read(buffer, binary.LittleEndian, &m.Seq)
+	binary.Read(buffer, binary.LittleEndian, &m.Bits)
+}
+
+func (m *Ack) Len() int {
+	mylen := 0
+	mylen += 2
+	mylen += 4
+	return mylen
+}
+
 type Multipart struct {
 	ID uint16
 	GroupID uint32
@@ -115,6 +211,9 @@ func (m *Multipart) Deserialize(buffer *bytes.Buffer) {
 	binary.Read(buffer, binary.LittleEndian, &m.NumParts)
 	var l3_1 int32
 	binary.Read(buffer, binary.LittleEndian, &l3_1)
+	if l3_1 < 0 || int(l3_1) > buffer.Len() {
+		return
+	}
 	m.Content = make([]byte, l3_1)
 	for i := 0; i < int(l3_1); i++ {
 		m.Content[i], _ = buffer.ReadByte()
@@ -196,16 +295,25 @@ func (m *CreateAcct) Serialize(buffer *bytes.Buffer) {
 func (m *CreateAcct) Deserialize(buffer *bytes.Buffer) {
 	var l0_1 int32
 	binary.Read(buffer, binary.LittleEndian, &l0_1)
+	if l0_1 < 0 || int(l0_1) > buffer.Len() {
+		return
+	}
 	temp0_1 := make([]byte, l0_1)
 	buffer.Read(temp0_1)
 	m.Name = string(temp0_1)
 	var l1_1 int32
 	binary.Read(buffer, binary.LittleEndian, &l1_1)
+	if l1_1 < 0 || int(l1_1) > buffer.Len() {
+		return
+	}
 	temp1_1 := make([]byte, l1_1)
 	buffer.Read(temp1_1)
 	m.Password = string(temp1_1)
 	var l2_1 int32
 	binary.Read(buffer, binary.LittleEndian, &l2_1)
+	if l2_1 < 0 || int(l2_1) > buffer.Len() {
+		return
+	}
 	temp2_1 := make([]byte, l2_1)
 	buffer.Read(temp2_1)
 	m.CharName = string(temp2_1)
@@ -238,6 +346,9 @@ func (m *CreateAcctResp) Deserialize(buffer *bytes.Buffer) {
 	binary.Read(buffer, binary.LittleEndian, &m.AccountID)
 	var l1_1 int32
 	binary.Read(buffer, binary.LittleEndian, &l1_1)
+	if l1_1 < 0 || int(l1_1) > buffer.Len() {
+		return
+	}
 	temp1_1 := make([]byte, l1_1)
 	buffer.Read(temp1_1)
 	m.Name = string(temp1_1)
@@ -268,11 +379,17 @@ func (m *Login) Serialize(buffer *bytes.Buffer) {
 func (m *Login) Deserialize(buffer *bytes.Buffer) {
 	var l0_1 int32
 	binary.Read(buffer, binary.LittleEndian, &l0_1)
+	if l0_1 < 0 || int(l0_1) > buffer.Len() {
+		return
+	}
 	temp0_1 := make([]byte, l0_1)
 	buffer.Read(temp0_1)
 	m.Name = string(temp0_1)
 	var l1_1 int32
 	binary.Read(buffer, binary.LittleEndian, &l1_1)
+	if l1_1 < 0 || int(l1_1) > buffer.Len() {
+		return
+	}
 	temp1_1 := make([]byte, l1_1)
 	buffer.Read(temp1_1)
 	m.Password = string(temp1_1)
@@ -304,6 +421,9 @@ func (m *LoginResp) Deserialize(buffer *bytes.Buffer) {
 	m.Success, _ = buffer.ReadByte()
 	var l1_1 int32
 	binary.Read(buffer, binary.LittleEndian, &l1_1)
+	if l1_1 < 0 || int(l1_1) > buffer.Len() {
+		return
+	}
 	temp1_1 := make([]byte, l1_1)
 	buffer.Read(temp1_1)
 	m.Name = string(temp1_1)
@@ -336,6 +456,9 @@ func (m *Character) Deserialize(buffer *bytes.Buffer) {
 	binary.Read(buffer, binary.LittleEndian, &m.ID)
 	var l1_1 int32
 	binary.Read(buffer, binary.LittleEndian, &l1_1)
+	if l1_1 < 0 || int(l1_1) > buffer.Len() {
+		return
+	}
 	temp1_1 := make([]byte, l1_1)
 	buffer.Read(temp1_1)
 	m.Name = string(temp1_1)
@@ -382,16 +505,25 @@ func (m *ListGamesResp) Serialize(buffer *bytes.Buffer) {
 func (m *ListGamesResp) Deserialize(buffer *bytes.Buffer) {
 	var l0_1 int32
 	binary.Read(buffer, binary.LittleEndian, &l0_1)
+	if l0_1 < 0 || int(l0_1) > buffer.Len() {
+		return
+	}
 	m.IDs = make([]uint32, l0_1)
 	for i := 0; i < int(l0_1); i++ {
 		binary.Read(buffer, binary.LittleEndian, &m.IDs[i])
 	}
 	var l1_1 int32
 	binary.Read(buffer, binary.LittleEndian, &l1_1)
+	if l1_1 < 0 || int(l1_1) > buffer.Len() {
+		return
+	}
 	m.Names = make([]string, l1_1)
 	for i := 0; i < int(l1_1); i++ {
 		var l0_2 int32
 		binary.Read(buffer, binary.LittleEndian, &l0_2)
+		if l0_2 < 0 || int(l0_2) > buffer.Len() {
+			return
+		}
 		temp0_2 := make([]byte, l0_2)
 		buffer.Read(temp0_2)
 		m.Names[i] = string(temp0_2)
@@ -427,6 +559,9 @@ func (m *CreateGame) Serialize(buffer *bytes.Buffer) {
 func (m *CreateGame) Deserialize(buffer *bytes.Buffer) {
 	var l0_1 int32
 	binary.Read(buffer, binary.LittleEndian, &l0_1)
+	if l0_1 < 0 || int(l0_1) > buffer.Len() {
+		return
+	}
 	temp0_1 := make([]byte, l0_1)
 	buffer.Read(temp0_1)
 	m.Name = string(temp0_1)
@@ -452,6 +587,9 @@ func (m *CreateGameResp) Serialize(buffer *bytes.Buffer) {
 func (m *CreateGameResp) Deserialize(buffer *bytes.Buffer) {
 	var l0_1 int32
 	binary.Read(buffer, binary.LittleEndian, &l0_1)
+	if l0_1 < 0 || int(l0_1) > buffer.Len() {
+		return
+	}
 	temp0_1 := make([]byte, l0_1)
 	buffer.Read(temp0_1)
 	m.Name = string(temp0_1)
@@ -504,6 +642,9 @@ func (m *GameConnected) Deserialize(buffer *bytes.Buffer) {
 	binary.Read(buffer, binary.LittleEndian, &m.Seed)
 	var l2_1 int32
 	binary.Read(buffer, binary.LittleEndian, &l2_1)
+	if l2_1 < 0 || int(l2_1) > buffer.Len() {
+		return
+	}
 	m.Entities = make([]*Entity, l2_1)
 	for i := 0; i < int(l2_1); i++ {
 		m.Entities[i] = new(Entity)
@@ -526,11 +667,13 @@ func (m *GameConnected) Len() int {
 
 type GameMasterFrame struct {
 	ID uint32
+	TickID uint32
 	Entities []*Entity
 }
 
 func (m *GameMasterFrame) Serialize(buffer *bytes.Buffer) {
 	binary.Write(buffer, binary.LittleEndian, m.ID)
+	binary.Write(buffer, binary.LittleEndian, m.TickID)
 	binary.Write(buffer, binary.LittleEndian, int32(len(m.Entities)))
 	for _, v2 := range m.Entities {
 		v2.Serialize(buffer)
@@ -539,10 +682,14 @@ func (m *GameMasterFrame) Serialize(buffer *bytes.Buffer) {
 
 func (m *GameMasterFrame) Deserialize(buffer *bytes.Buffer) {
 	binary.Read(buffer, binary.LittleEndian, &m.ID)
-	var l1_1 int32
-	binary.Read(buffer, binary.LittleEndian, &l1_1)
-	m.Entities = make([]*Entity, l1_1)
-	for i := 0; i < int(l1_1); i++ {
+	binary.Read(buffer, binary.LittleEndian, &m.TickID)
+	var l2_1 int32
+	binary.Read(buffer, binary.LittleEndian, &l2_1)
+	if l2_1 < 0 || int(l2_1) > buffer.Len() {
+		return
+	}
+	m.Entities = make([]*Entity, l2_1)
+	for i := 0; i < int(l2_1); i++ {
 		m.Entities[i] = new(Entity)
 		m.Entities[i].Deserialize(buffer)
 	}
@@ -552,6 +699,7 @@ func (m *GameMasterFrame) Len() int {
 	mylen := 0
 	mylen += 4
 	mylen += 4
+	mylen += 4
 	for _, v2 := range m.Entities {
 	_ = v2
 		mylen += v2.Len()
@@ -671,18 +819,24 @@ func (m *UseAbility) Len() int {
 }
 
 type AbilityResult struct {
+	EntityID uint32
+	AbilityID uint32
 	Target *Entity
 	Damage int32
 	State byte
 }
 
 func (m *AbilityResult) Serialize(buffer *bytes.Buffer) {
+	binary.Write(buffer, binary.LittleEndian, m.EntityID)
+	binary.Write(buffer, binary.LittleEndian, m.AbilityID)
 	m.Target.Serialize(buffer)
 	binary.Write(buffer, binary.LittleEndian, m.Damage)
 	buffer.WriteByte(m.State)
 }
 
 func (m *AbilityResult) Deserialize(buffer *bytes.Buffer) {
+	binary.Read(buffer, binary.LittleEndian, &m.EntityID)
+	binary.Read(buffer, binary.LittleEndian, &m.AbilityID)
 	m.Target = new(Entity)
 	m.Target.Deserialize(buffer)
 	binary.Read(buffer, binary.LittleEndian, &m.Damage)
@@ -691,6 +845,8 @@ func (m *AbilityResult) Deserialize(buffer *bytes.Buffer) {
 
 func (m *AbilityResult) Len() int {
 	mylen := 0
+	mylen += 4
+	mylen += 4
 	mylen += m.Target.Len()
 	mylen += 4
 	mylen += 1
@@ -715,3 +871,661 @@ func (m *EndGame) Len() int {
 	return mylen
 }
 
+type KeyRequest struct {
+}
+
+func (m *KeyRequest) Serialize(buffer *bytes.Buffer) {
+}
+
+func (m *KeyRequest) Deserialize(buffer *bytes.Buffer) {
+}
+
+func (m *KeyRequest) Len() int {
+	mylen := 0
+	return mylen
+}
+
+type ServerKey struct {
+	Key []byte
+}
+
+func (m *ServerKey) Serialize(buffer *bytes.Buffer) {
+	binary.Write(buffer, binary.LittleEndian, int32(len(m.Key)))
+	buffer.Write(m.Key)
+}
+
+func (m *ServerKey) Deserialize(buffer *bytes.Buffer) {
+	var l0_1 int32
+	binary.Read(buffer, binary.LittleEndian, &l0_1)
+	if l0_1 < 0 || int(l0_1) > buffer.Len() {
+		return
+	}
+	m.Key = make([]byte, l0_1)
+	for i := 0; i < int(l0_1); i++ {
+		m.Key[i], _ = buffer.ReadByte()
+	}
+}
+
+func (m *ServerKey) Len() int {
+	mylen := 0
+	mylen += 4 + len(m.Key)
+	return mylen
+}
+
+type SessionKey struct {
+	Key []byte
+}
+
+func (m *SessionKey) Serialize(buffer *bytes.Buffer) {
+	binary.Write(buffer, binary.LittleEndian, int32(len(m.Key)))
+	buffer.Write(m.Key)
+}
+
+func (m *SessionKey) Deserialize(buffer *bytes.Buffer) {
+	var l0_1 int32
+	binary.Read(buffer, binary.LittleEndian, &l0_1)
+	if l0_1 < 0 || int(l0_1) > buffer.Len() {
+		return
+	}
+	m.Key = make([]byte, l0_1)
+	for i := 0; i < int(l0_1); i++ {
+		m.Key[i], _ = buffer.ReadByte()
+	}
+}
+
+func (m *SessionKey) Len() int {
+	mylen := 0
+	mylen += 4 + len(m.Key)
+	return mylen
+}
+
+type SessionReady struct {
+}
+
+func (m *SessionReady) Serialize(buffer *bytes.Buffer) {
+}
+
+func (m *SessionReady) Deserialize(buffer *bytes.Buffer) {
+}
+
+func (m *SessionReady) Len() int {
+	mylen := 0
+	return mylen
+}
+
+type Secure struct {
+	Counter uint64
+	Content []byte
+}
+
+func (m *Secure) Serialize(buffer *bytes.Buffer) {
+	binary.Write(buffer, binary.LittleEndian, m.Counter)
+	binary.Write(buffer, binary.LittleEndian, int32(len(m.Content)))
+	buffer.Write(m.Content)
+}
+
+func (m *Secure) Deserialize(buffer *bytes.Buffer) {
+	binary.Read(buffer, binary.LittleEndian, &m.Counter)
+	var l1_1 int32
+	binary.Read(buffer, binary.LittleEndian, &l1_1)
+	if l1_1 < 0 || int(l1_1) > buffer.Len() {
+		return
+	}
+	m.Content = make([]byte, l1_1)
+	for i := 0; i < int(l1_1); i++ {
+		m.Content[i], _ = buffer.ReadByte()
+	}
+}
+
+func (m *Secure) Len() int {
+	mylen := 0
+	mylen += 8
+	mylen += 4 + len(m.Content)
+	return mylen
+}
+
+type JoinGameFailed struct {
+	ID uint32
+	Reason byte
+}
+
+func (m *JoinGameFailed) Serialize(buffer *bytes.Buffer) {
+	binary.Write(buffer, binary.LittleEndian, m.ID)
+	buffer.WriteByte(m.Reason)
+}
+
+func (m *JoinGameFailed) Deserialize(buffer *bytes.Buffer) {
+	binary.Read(buffer, binary.LittleEndian, &m.ID)
+	m.Reason, _ = buffer.ReadByte()
+}
+
+func (m *JoinGameFailed) Len() int {
+	mylen := 0
+	mylen += 4
+	mylen += 1
+	return mylen
+}
+
+type EntitiesEntered struct {
+	GameID uint32
+	TickID uint32
+	Entities []*Entity
+}
+
+func (m *EntitiesEntered) Serialize(buffer *bytes.Buffer) {
+	binary.Write(buffer, binary.LittleEndian, m.GameID)
+	binary.Write(buffer, binary.LittleEndian, m.TickID)
+	binary.Write(buffer, binary.LittleEndian, int32(len(m.Entities)))
+	for _, v2 := range m.Entities {
+		v2.Serialize(buffer)
+	}
+}
+
+func (m *EntitiesEntered) Deserialize(buffer *bytes.Buffer) {
+	binary.Read(buffer, binary.LittleEndian, &m.GameID)
+	binary.Read(buffer, binary.LittleEndian, &m.TickID)
+	var l2_1 int32
+	binary.Read(buffer, binary.LittleEndian, &l2_1)
+	if l2_1 < 0 || int(l2_1) > buffer.Len() {
+		return
+	}
+	m.Entities = make([]*Entity, l2_1)
+	for i := 0; i < int(l2_1); i++ {
+		m.Entities[i] = new(Entity)
+		m.Entities[i].Deserialize(buffer)
+	}
+}
+
+func (m *EntitiesEntered) Len() int {
+	mylen := 0
+	mylen += 4
+	mylen += 4
+	mylen += 4
+	for _, v2 := range m.Entities {
+	_ = v2
+		mylen += v2.Len()
+	}
+
+	return mylen
+}
+
+type EntitiesLeft struct {
+	GameID uint32
+	TickID uint32
+	IDs []uint32
+}
+
+func (m *EntitiesLeft) Serialize(buffer *bytes.Buffer) {
+	binary.Write(buffer, binary.LittleEndian, m.GameID)
+	binary.Write(buffer, binary.LittleEndian, m.TickID)
+	binary.Write(buffer, binary.LittleEndian, int32(len(m.IDs)))
+	for _, v2 := range m.IDs {
+		binary.Write(buffer, binary.LittleEndian, v2)
+	}
+}
+
+func (m *EntitiesLeft) Deserialize(buffer *bytes.Buffer) {
+	binary.Read(buffer, binary.LittleEndian, &m.GameID)
+	binary.Read(buffer, binary.LittleEndian, &m.TickID)
+	var l2_1 int32
+	binary.Read(buffer, binary.LittleEndian, &l2_1)
+	if l2_1 < 0 || int(l2_1) > buffer.Len() {
+		return
+	}
+	m.IDs = make([]uint32, l2_1)
+	for i := 0; i < int(l2_1); i++ {
+		binary.Read(buffer, binary.LittleEndian, &m.IDs[i])
+	}
+}
+
+func (m *EntitiesLeft) Len() int {
+	mylen := 0
+	mylen += 4
+	mylen += 4
+	mylen += 4
+	for _, v2 := range m.IDs {
+	_ = v2
+		mylen += 4
+	}
+
+	return mylen
+}
+
+type GameDeltaFrame struct {
+	ID uint32
+	TickID uint32
+	BaseTick uint32
+	Deltas []byte
+}
+
+func (m *GameDeltaFrame) Serialize(buffer *bytes.Buffer) {
+	binary.Write(buffer, binary.LittleEndian, m.ID)
+	binary.Write(buffer, binary.LittleEndian, m.TickID)
+	binary.Write(buffer, binary.LittleEndian, m.BaseTick)
+	binary.Write(buffer, binary.LittleEndian, int32(len(m.Deltas)))
+	buffer.Write(m.Deltas)
+}
+
+func (m *GameDeltaFrame) Deserialize(buffer *bytes.Buffer) {
+	binary.Read(buffer, binary.LittleEndian, &m.ID)
+	binary.Read(buffer, binary.LittleEndian, &m.TickID)
+	binary.Read(buffer, binary.LittleEndian, &m.BaseTick)
+	var l3_1 int32
+	binary.Read(buffer, binary.LittleEndian, &l3_1)
+	if l3_1 < 0 || int(l3_1) > buffer.Len() {
+		return
+	}
+	m.Deltas = make([]byte, l3_1)
+	for i := 0; i < int(l3_1); i++ {
+		m.Deltas[i], _ = buffer.ReadByte()
+	}
+}
+
+func (m *GameDeltaFrame) Len() int {
+	mylen := 0
+	mylen += 4
+	mylen += 4
+	mylen += 4
+	mylen += 4 + len(m.Deltas)
+	return mylen
+}
+
+type FrameAck struct {
+	GameID uint32
+	TickID uint32
+}
+
+func (m *FrameAck) Serialize(buffer *bytes.Buffer) {
+	binary.Write(buffer, binary.LittleEndian, m.GameID)
+	binary.Write(buffer, binary.LittleEndian, m.TickID)
+}
+
+func (m *FrameAck) Deserialize(buffer *bytes.Buffer) {
+	binary.Read(buffer, binary.LittleEndian, &m.GameID)
+	binary.Read(buffer, binary.LittleEndian, &m.TickID)
+}
+
+func (m *FrameAck) Len() int {
+	mylen := 0
+	mylen += 4
+	mylen += 4
+	return mylen
+}
+
+type ProjectileHit struct {
+	ProjectileID uint32
+	TargetID uint32
+	Damage int32
+}
+
+func (m *ProjectileHit) Serialize(buffer *bytes.Buffer) {
+	binary.Write(buffer, binary.LittleEndian, m.ProjectileID)
+	binary.Write(buffer, binary.LittleEndian, m.TargetID)
+	binary.Write(buffer, binary.LittleEndian, m.Damage)
+}
+
+func (m *ProjectileHit) Deserialize(buffer *bytes.Buffer) {
+	binary.Read(buffer, binary.LittleEndian, &m.ProjectileID)
+	binary.Read(buffer, binary.LittleEndian, &m.TargetID)
+	binary.Read(buffer, binary.LittleEndian, &m.Damage)
+}
+
+func (m *ProjectileHit) Len() int {
+	mylen := 0
+	mylen += 4
+	mylen += 4
+	mylen += 4
+	return mylen
+}
+
+type CharacterDied struct {
+	EntityID uint32
+	KillerID uint32
+	RespawnTick uint32
+}
+
+func (m *CharacterDied) Serialize(buffer *bytes.Buffer) {
+	binary.Write(buffer, binary.LittleEndian, m.EntityID)
+	binary.Write(buffer, binary.LittleEndian, m.KillerID)
+	binary.Write(buffer, binary.LittleEndian, m.RespawnTick)
+}
+
+func (m *CharacterDied) Deserialize(buffer *bytes.Buffer) {
+	binary.Read(buffer, binary.LittleEndian, &m.EntityID)
+	binary.Read(buffer, binary.LittleEndian, &m.KillerID)
+	binary.Read(buffer, binary.LittleEndian, &m.RespawnTick)
+}
+
+func (m *CharacterDied) Len() int {
+	mylen := 0
+	mylen += 4
+	mylen += 4
+	mylen += 4
+	return mylen
+}
+
+type Stats struct {
+	HP int32
+	Stamina int16
+	Concentration int16
+	Speed int16
+	MagicStrength int16
+	PhysicalStrength int16
+}
+
+func (m *Stats) Serialize(buffer *bytes.Buffer) {
+	binary.Write(buffer, binary.LittleEndian, m.HP)
+	binary.Write(buffer, binary.LittleEndian, m.Stamina)
+	binary.Write(buffer, binary.LittleEndian, m.Concentration)
+	binary.Write(buffer, binary.LittleEndian, m.Speed)
+	binary.Write(buffer, binary.LittleEndian, m.MagicStrength)
+	binary.Write(buffer, binary.LittleEndian, m.PhysicalStrength)
+}
+
+func (m *Stats) Deserialize(buffer *bytes.Buffer) {
+	binary.Read(buffer, binary.LittleEndian, &m.HP)
+	binary.Read(buffer, binary.LittleEndian, &m.Stamina)
+	binary.Read(buffer, binary.LittleEndian, &m.Concentration)
+	binary.Read(buffer, binary.LittleEndian, &m.Speed)
+	binary.Read(buffer, binary.LittleEndian, &m.MagicStrength)
+	binary.Read(buffer, binary.LittleEndian, &m.PhysicalStrength)
+}
+
+func (m *Stats) Len() int {
+	mylen := 0
+	mylen += 4
+	mylen += 2
+	mylen += 2
+	mylen += 2
+	mylen += 2
+	mylen += 2
+	return mylen
+}
+
+type Item struct {
+	ID uint32
+	Kind uint16
+	Count uint16
+	Slot byte
+	Stats *Stats
+}
+
+func (m *Item) Serialize(buffer *bytes.Buffer) {
+	binary.Write(buffer, binary.LittleEndian, m.ID)
+	binary.Write(buffer, binary.LittleEndian, m.Kind)
+	binary.Write(buffer, binary.LittleEndian, m.Count)
+	buffer.WriteByte(m.Slot)
+	m.Stats.Serialize(buffer)
+}
+
+func (m *Item) Deserialize(buffer *bytes.Buffer) {
+	binary.Read(buffer, binary.LittleEndian, &m.ID)
+	binary.Read(buffer, binary.LittleEndian, &m.Kind)
+	binary.Read(buffer, binary.LittleEndian, &m.Count)
+	m.Slot, _ = buffer.ReadByte()
+	m.Stats = new(Stats)
+	m.Stats.Deserialize(buffer)
+}
+
+func (m *Item) Len() int {
+	mylen := 0
+	mylen += 4
+	mylen += 2
+	mylen += 2
+	mylen += 1
+	mylen += m.Stats.Len()
+	return mylen
+}
+
+type PickupItem struct {
+	EntityID uint32
+}
+
+func (m *PickupItem) Serialize(buffer *bytes.Buffer) {
+	binary.Write(buffer, binary.LittleEndian, m.EntityID)
+}
+
+func (m *PickupItem) Deserialize(buffer *bytes.Buffer) {
+	binary.Read(buffer, binary.LittleEndian, &m.EntityID)
+}
+
+func (m *PickupItem) Len() int {
+	mylen := 0
+	mylen += 4
+	return mylen
+}
+
+type DropItem struct {
+	ItemID uint32
+}
+
+func (m *DropItem) Serialize(buffer *bytes.Buffer) {
+	binary.Write(buffer, binary.LittleEndian, m.ItemID)
+}
+
+func (m *DropItem) Deserialize(buffer *bytes.Buffer) {
+	binary.Read(buffer, binary.LittleEndian, &m.ItemID)
+}
+
+func (m *DropItem) Len() int {
+	mylen := 0
+	mylen += 4
+	return mylen
+}
+
+type EquipItem struct {
+	ItemID uint32
+	Slot byte
+}
+
+func (m *EquipItem) Serialize(buffer *bytes.Buffer) {
+	binary.Write(buffer, binary.LittleEndian, m.ItemID)
+	buffer.WriteByte(m.Slot)
+}
+
+func (m *EquipItem) Deserialize(buffer *bytes.Buffer) {
+	binary.Read(buffer, binary.LittleEndian, &m.ItemID)
+	m.Slot, _ = buffer.ReadByte()
+}
+
+func (m *EquipItem) Len() int {
+	mylen := 0
+	mylen += 4
+	mylen += 1
+	return mylen
+}
+
+type UnequipItem struct {
+	Slot byte
+}
+
+func (m *UnequipItem) Serialize(buffer *bytes.Buffer) {
+	buffer.WriteByte(m.Slot)
+}
+
+func (m *UnequipItem) Deserialize(buffer *bytes.Buffer) {
+	m.Slot, _ = buffer.ReadByte()
+}
+
+func (m *UnequipItem) Len() int {
+	mylen := 0
+	mylen += 1
+	return mylen
+}
+
+type InventoryUpdate struct {
+	Equipped []*Item
+	Inventory []*Item
+	Current *Stats
+	Max *Stats
+}
+
+func (m *InventoryUpdate) Serialize(buffer *bytes.Buffer) {
+	binary.Write(buffer, binary.LittleEndian, int32(len(m.Equipped)))
+	for _, v2 := range m.Equipped {
+		v2.Serialize(buffer)
+	}
+	binary.Write(buffer, binary.LittleEndian, int32(len(m.Inventory)))
+	for _, v2 := range m.Inventory {
+		v2.Serialize(buffer)
+	}
+	m.Current.Serialize(buffer)
+	m.Max.Serialize(buffer)
+}
+
+func (m *InventoryUpdate) Deserialize(buffer *bytes.Buffer) {
+	var l0_1 int32
+	binary.Read(buffer, binary.LittleEndian, &l0_1)
+	if l0_1 < 0 || int(l0_1) > buffer.Len() {
+		return
+	}
+	m.Equipped = make([]*Item, l0_1)
+	for i := 0; i < int(l0_1); i++ {
+		m.Equipped[i] = new(Item)
+		m.Equipped[i].Deserialize(buffer)
+	}
+	var l1_1 int32
+	binary.Read(buffer, binary.LittleEndian, &l1_1)
+	if l1_1 < 0 || int(l1_1) > buffer.Len() {
+		return
+	}
+	m.Inventory = make([]*Item, l1_1)
+	for i := 0; i < int(l1_1); i++ {
+		m.Inventory[i] = new(Item)
+		m.Inventory[i].Deserialize(buffer)
+	}
+	m.Current = new(Stats)
+	m.Current.Deserialize(buffer)
+	m.Max = new(Stats)
+	m.Max.Deserialize(buffer)
+}
+
+func (m *InventoryUpdate) Len() int {
+	mylen := 0
+	mylen += 4
+	for _, v2 := range m.Equipped {
+	_ = v2
+		mylen += v2.Len()
+	}
+
+	mylen += 4
+	for _, v2 := range m.Inventory {
+	_ = v2
+		mylen += v2.Len()
+	}
+
+	mylen += m.Current.Len()
+	mylen += m.Max.Len()
+	return mylen
+}
+
+type Harvest struct {
+	EntityID uint32
+}
+
+func (m *Harvest) Serialize(buffer *bytes.Buffer) {
+	binary.Write(buffer, binary.LittleEndian, m.EntityID)
+}
+
+func (m *Harvest) Deserialize(buffer *bytes.Buffer) {
+	binary.Read(buffer, binary.LittleEndian, &m.EntityID)
+}
+
+func (m *Harvest) Len() int {
+	mylen := 0
+	mylen += 4
+	return mylen
+}
+
+type Craft struct {
+	RecipeID uint32
+}
+
+func (m *Craft) Serialize(buffer *bytes.Buffer) {
+	binary.Write(buffer, binary.LittleEndian, m.RecipeID)
+}
+
+func (m *Craft) Deserialize(buffer *bytes.Buffer) {
+	binary.Read(buffer, binary.LittleEndian, &m.RecipeID)
+}
+
+func (m *Craft) Len() int {
+	mylen := 0
+	mylen += 4
+	return mylen
+}
+
+type CraftResult struct {
+	RecipeID uint32
+	State byte
+	DoneTick uint32
+	ItemID uint32
+}
+
+func (m *CraftResult) Serialize(buffer *bytes.Buffer) {
+	binary.Write(buffer, binary.LittleEndian, m.RecipeID)
+	buffer.WriteByte(m.State)
+	binary.Write(buffer, binary.LittleEndian, m.DoneTick)
+	binary.Write(buffer, binary.LittleEndian, m.ItemID)
+}
+
+func (m *CraftResult) Deserialize(buffer *bytes.Buffer) {
+	binary.Read(buffer, binary.LittleEndian, &m.RecipeID)
+	m.State, _ = buffer.ReadByte()
+	binary.Read(buffer, binary.LittleEndian, &m.DoneTick)
+	binary.Read(buffer, binary.LittleEndian, &m.ItemID)
+}
+
+func (m *CraftResult) Len() int {
+	mylen := 0
+	mylen += 4
+	mylen += 1
+	mylen += 4
+	mylen += 4
+	return mylen
+}
+
+type Consume struct {
+	ItemID uint32
+}
+
+func (m *Consume) Serialize(buffer *bytes.Buffer) {
+	binary.Write(buffer, binary.LittleEndian, m.ItemID)
+}
+
+func (m *Consume) Deserialize(buffer *bytes.Buffer) {
+	binary.Read(buffer, binary.LittleEndian, &m.ItemID)
+}
+
+func (m *Consume) Len() int {
+	mylen := 0
+	mylen += 4
+	return mylen
+}
+
+type CharacterStatus struct {
+	Hunger byte
+	Thirst byte
+	Temperature int16
+}
+
+func (m *CharacterStatus) Serialize(buffer *bytes.Buffer) {
+	buffer.WriteByte(m.Hunger)
+	buffer.WriteByte(m.Thirst)
+	binary.Write(buffer, binary.LittleEndian, m.Temperature)
+}
+
+func (m *CharacterStatus) Deserialize(buffer *bytes.Buffer) {
+	m.Hunger, _ = buffer.ReadByte()
+	m.Thirst, _ = buffer.ReadByte()
+	binary.Read(buffer, binary.LittleEndian, &m.Temperature)
+}
+
+func (m *CharacterStatus) Len() int {
+	mylen := 0
+	mylen += 1
+	mylen += 1
+	mylen += 2
+	return mylen
+}
+
@@ -0,0 +1,125 @@
+package messages
+
+import (
+	"bytes"
+	"time"
+)
+
+// Default limits used by NewReassembler.
+const (
+	DefaultMaxParts     = 256             // A full 64k packet split into the smallest parts is still under this.
+	DefaultMaxGroups    = 16              // Groups that can be in progress at once per connection.
+	DefaultGroupTimeout = time.Second * 5 // How long to wait for the rest of a group.
+)
+
+// Reassembler collects Multipart messages and rebuilds the original packet once
+// all parts of a group have arrived. Parts are validated before being stored so
+// a bad or malicious Multipart can't grow memory without bound or crash the reader.
+// A Reassembler is not safe for concurrent use.
+type Reassembler struct {
+	MaxParts  int           // Max NumParts accepted for a single group.
+	MaxGroups int           // Max groups in progress, oldest is evicted to make room.
+	Timeout   time.Duration // Groups not completed in this time are thrown away.
+
+	Dropped uint32 // Parts and groups thrown away for being invalid or over limits.
+	Expired uint32 // Groups that timed out before all parts arrived.
+
+	groups map[uint32]*partialGroup
+}
+
+type partialGroup struct {
+	parts    [][]byte
+	have     []bool
+	received int
+	started  time.Time
+}
+
+// NewReassembler creates a Reassembler with the default limits.
+func NewReassembler() *Reassembler {
+	return &Reassembler{
+		MaxParts:  DefaultMaxParts,
+		MaxGroups: DefaultMaxGroups,
+		Timeout:   DefaultGroupTimeout,
+		groups:    map[uint32]*partialGroup{},
+	}
+}
+
+// Add inserts a part into its group. When the group is complete the reassembled
+// packet is returned with ok set to true.
+func (r *Reassembler) Add(part *Multipart, now time.Time) (packet Packet, ok bool) {
+	r.Expire(now)
+	if part.NumParts == 0 || int(part.NumParts) > r.MaxParts || part.ID >= part.NumParts {
+		r.Dropped++
+		return packet, false
+	}
+
+	g := r.groups[part.GroupID]
+	if g != nil && len(g.parts) != int(part.NumParts) {
+		// Parts disagree on the size of the group, nothing in it can be trusted.
+		delete(r.groups, part.GroupID)
+		r.Dropped++
+		return packet, false
+	}
+	if g == nil {
+		if len(r.groups) >= r.MaxGroups {
+			r.evictOldest()
+		}
+		g = &partialGroup{
+			parts:   make([][]byte, part.NumParts),
+			have:    make([]bool, part.NumParts),
+			started: now,
+		}
+		r.groups[part.GroupID] = g
+	}
+	if g.have[part.ID] {
+		// Duplicate part, already have it.
+		return packet, false
+	}
+	g.parts[part.ID] = part.Content
+	g.have[part.ID] = true
+	g.received++
+	if g.received < len(g.parts) {
+		return packet, false
+	}
+
+	delete(r.groups, part.GroupID)
+	buf := &bytes.Buffer{}
+	for _, p := range g.parts {
+		buf.Write(p)
+	}
+	packet, ok = NextPacket(buf.Bytes())
+	if !ok {
+		r.Dropped++
+	}
+	return packet, ok
+}
+
+// Expire removes all groups that have been waiting longer than the Timeout.
+func (r *Reassembler) Expire(now time.Time) {
+	for id, g := range r.groups {
+		if now.Sub(g.started) > r.Timeout {
+			delete(r.groups, id)
+			r.Expired++
+		}
+	}
+}
+
+// Pending returns the number of groups still waiting on parts.
+func (r *Reassembler) Pending() int {
+	return len(r.groups)
+}
+
+func (r *Reassembler) evictOldest() {
+	var oldestID uint32
+	var oldest *partialGroup
+	for id, g := range r.groups {
+		if oldest == nil || g.started.Before(oldest.started) {
+			oldestID = id
+			oldest = g
+		}
+	}
+	if oldest != nil {
+		delete(r.groups, oldestID)
+		r.Dropped++
+	}
+}
@@ -0,0 +1,103 @@
+package messages
+
+import (
+	"testing"
+	"time"
+)
+
+// splitPacket cuts a packed message into n Multipart parts.
+func splitPacket(groupID uint32, data []byte, n int) []*Multipart {
+	parts := make([]*Multipart, n)
+	size := (len(data) + n - 1) / n
+	for i := range parts {
+		start := i * size
+		end := start + size
+		if end > len(data) {
+			end = len(data)
+		}
+		parts[i] = &Multipart{
+			ID:       uint16(i),
+			GroupID:  groupID,
+			NumParts: uint16(n),
+			Content:  data[start:end],
+		}
+	}
+	return parts
+}
+
+func TestReassembleOutOfOrder(t *testing.T) {
+	data := NewPacket(LoginMsgType, &Login{Name: "testuser", Password: "testpass"}).Pack()
+	parts := splitPacket(1, data, 4)
+	r := NewReassembler()
+	now := time.Now()
+	for _, idx := range []int{2, 0, 0, 3} {
+		if _, ok := r.Add(parts[idx], now); ok {
+			t.Fatalf("Group should not be complete yet.")
+		}
+	}
+	packet, ok := r.Add(parts[1], now)
+	if !ok {
+		t.Fatalf("Group should be complete.")
+	}
+	login := packet.NetMsg.(*Login)
+	if login.Name != "testuser" || login.Password != "testpass" {
+		t.Fatalf("Incorrect message reassembled: %v", login)
+	}
+	if r.Pending() != 0 {
+		t.Fatalf("Completed group should be removed.")
+	}
+}
+
+func TestReassembleInvalidParts(t *testing.T) {
+	r := NewReassembler()
+	now := time.Now()
+	bad := []*Multipart{
+		{ID: 0, GroupID: 1, NumParts: 0},
+		{ID: 5, GroupID: 1, NumParts: 5},
+		{ID: 0, GroupID: 1, NumParts: uint16(r.MaxParts + 1)},
+	}
+	for _, p := range bad {
+		if _, ok := r.Add(p, now); ok {
+			t.Fatalf("Invalid part should never complete a group.")
+		}
+	}
+	if r.Dropped != uint32(len(bad)) || r.Pending() != 0 {
+		t.Fatalf("Expected %d dropped and 0 pending, got %d and %d", len(bad), r.Dropped, r.Pending())
+	}
+
+	// Parts that disagree on the group size throw away the group.
+	r.Add(&Multipart{ID: 0, GroupID: 2, NumParts: 3}, now)
+	r.Add(&Multipart{ID: 1, GroupID: 2, NumParts: 4}, now)
+	if r.Pending() != 0 {
+		t.Fatalf("Inconsistent group should have been dropped.")
+	}
+}
+
+func TestReassembleLimits(t *testing.T) {
+	r := NewReassembler()
+	now := time.Now()
+	for i := 0; i < r.MaxGroups*2; i++ {
+		r.Add(&Multipart{ID: 0, GroupID: uint32(i), NumParts: 2}, now.Add(time.Duration(i)))
+	}
+	if r.Pending() != r.MaxGroups {
+		t.Fatalf("Expected %d groups pending, got %d", r.MaxGroups, r.Pending())
+	}
+	if _, ok := r.groups[0]; ok {
+		t.Fatalf("Oldest group should have been evicted.")
+	}
+
+	r.Expire(now.Add(r.Timeout * 2))
+	if r.Pending() != 0 || r.Expired != uint32(r.MaxGroups) {
+		t.Fatalf("All groups should have expired, pending: %d expired: %d", r.Pending(), r.Expired)
+	}
+}
+
+func TestDeserializeBadLength(t *testing.T) {
+	// Content length claims far more bytes than exist.
+	raw := []byte{0, 0, 1, 0, 0, 0, 1, 0, 0xff, 0xff, 0xff, 0x7f}
+	p := Packet{Frame: Frame{MsgType: MultipartMsgType, ContentLength: uint16(len(raw))}}
+	msg := ParseNetMessage(p, raw).(*Multipart)
+	if len(msg.Content) != 0 {
+		t.Fatalf("Content should not be read past the end of the message.")
+	}
+}
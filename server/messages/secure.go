@@ -0,0 +1,172 @@
+package messages
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/binary"
+	"errors"
+	"sync/atomic"
+)
+
+// SessionKeyLen is the size of the AES-256 key the client generates for a session.
+const SessionKeyLen = 32
+
+// SecureOverhead is how many bytes wrapping a packet in a Secure message adds.
+var SecureOverhead = FrameLen + (&Secure{}).Len() + 16 // +16 for the GCM tag
+
+// Directions used in the nonce so both sides can share a key without ever reusing a nonce.
+const (
+	toServer byte = 1
+	toClient byte = 2
+)
+
+// Session encrypts and authenticates packets with AES-GCM once a client and server share a key.
+// The nonce is built from a per direction counter sent with each packet, and the outer
+// frame (including Frame.Seq) is authenticated so it can't be altered or moved to another packet.
+// Replayed counters are rejected using a sliding window.
+type Session struct {
+	aead     cipher.AEAD
+	sendDir  byte
+	recvDir  byte
+	sent     uint64 // Last counter sent, only access atomically.
+	received uint64 // Highest counter received.
+	window   uint64 // Bit n set means received-(n+1) was seen.
+}
+
+// NewSession creates a session from a shared key. The server and client each create
+// their own end, isServer selects which direction this end sends in.
+func NewSession(key []byte, isServer bool) (*Session, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	s := &Session{aead: aead, sendDir: toServer, recvDir: toClient}
+	if isServer {
+		s.sendDir, s.recvDir = toClient, toServer
+	}
+	return s, nil
+}
+
+// NewSessionKey creates a random key for a new session.
+func NewSessionKey() ([]byte, error) {
+	key := make([]byte, SessionKeyLen)
+	_, err := rand.Read(key)
+	return key, err
+}
+
+// MarshalServerKey encodes the server public key to send in a ServerKey message.
+func MarshalServerKey(key *rsa.PublicKey) ([]byte, error) {
+	return x509.MarshalPKIXPublicKey(key)
+}
+
+// EncryptSessionKey is used by a client to encrypt its session key with the server public key from a ServerKey message.
+func EncryptSessionKey(serverKey []byte, sessionKey []byte) ([]byte, error) {
+	pub, err := x509.ParsePKIXPublicKey(serverKey)
+	if err != nil {
+		return nil, err
+	}
+	rsaPub, ok := pub.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("server key is not an RSA key")
+	}
+	return rsa.EncryptOAEP(sha256.New(), rand.Reader, rsaPub, sessionKey, nil)
+}
+
+// DecryptSessionKey is used by the server to get the session key out of a SessionKey message.
+func DecryptSessionKey(key *rsa.PrivateKey, encrypted []byte) ([]byte, error) {
+	sessionKey, err := rsa.DecryptOAEP(sha256.New(), rand.Reader, key, encrypted, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(sessionKey) != SessionKeyLen {
+		return nil, errors.New("session key is the wrong length")
+	}
+	return sessionKey, nil
+}
+
+// Seal encrypts the packet into a Secure packet. The Secure packet keeps the Seq of the original.
+// Safe to call from a different goroutine than Open.
+func (s *Session) Seal(p *Packet) *Packet {
+	counter := atomic.AddUint64(&s.sent, 1)
+	outer := &Packet{
+		Frame: Frame{
+			MsgType: SecureMsgType,
+			Seq:     p.Frame.Seq,
+		},
+	}
+	inner := *p
+	inner.Frame.Seq = 0
+	sec := &Secure{
+		Counter: counter,
+		Content: s.aead.Seal(nil, s.nonce(s.sendDir, counter), inner.Pack(), frameData(outer.Frame)),
+	}
+	outer.Frame.ContentLength = uint16(sec.Len())
+	outer.NetMsg = sec
+	return outer
+}
+
+// Open decrypts and authenticates a Secure packet, returning the packet inside with the outer Seq.
+// fresh is false if this counter has been seen before (or is too old to tell).
+func (s *Session) Open(p Packet) (inner Packet, fresh bool, ok bool) {
+	sec, isSecure := p.NetMsg.(*Secure)
+	if !isSecure {
+		return inner, false, false
+	}
+	plain, err := s.aead.Open(nil, s.nonce(s.recvDir, sec.Counter), sec.Content, frameData(p.Frame))
+	if err != nil {
+		return inner, false, false
+	}
+	inner, ok = NextPacket(plain)
+	if !ok || inner.Len() != len(plain) {
+		return inner, false, false
+	}
+	inner.Frame.Seq = p.Frame.Seq
+	return inner, s.check(sec.Counter), true
+}
+
+// check records the counter in the replay window, returns false if it was already seen.
+func (s *Session) check(counter uint64) bool {
+	if counter > s.received {
+		shift := counter - s.received
+		if shift > 64 {
+			s.window = 0
+		} else {
+			s.window = (s.window << shift) | (1 << (shift - 1))
+		}
+		s.received = counter
+		return true
+	}
+	diff := s.received - counter
+	if diff == 0 || diff > 64 {
+		return false
+	}
+	bit := uint64(1) << (diff - 1)
+	if s.window&bit != 0 {
+		return false
+	}
+	s.window |= bit
+	return true
+}
+
+func (s *Session) nonce(dir byte, counter uint64) []byte {
+	nonce := make([]byte, s.aead.NonceSize())
+	nonce[0] = dir
+	binary.LittleEndian.PutUint64(nonce[len(nonce)-8:], counter)
+	return nonce
+}
+
+// frameData is the part of the outer frame that is authenticated.
+func frameData(f Frame) []byte {
+	b := make([]byte, 4)
+	binary.LittleEndian.PutUint16(b[0:2], uint16(f.MsgType))
+	binary.LittleEndian.PutUint16(b[2:4], f.Seq)
+	return b
+}
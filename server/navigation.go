@@ -0,0 +1,88 @@
+package server
+
+import (
+	"github.com/lologarithm/survival/physics"
+	"github.com/lologarithm/survival/physics/quadtree"
+	"github.com/lologarithm/survival/server/pathfind"
+)
+
+// navCellSize is how big each cell of a chunk's navigation grid is, in units.
+const navCellSize int32 = 100
+
+// navClearance is how far paths keep from obstacles, half the size of the biggest creature.
+const navClearance int32 = 20
+
+// navCacheSize is how many paths each chunk remembers.
+const navCacheSize = 64
+
+// chunkNav is the navigation grid for a chunk, built from its fixed bodies the first time
+// it is needed and again after the obstacles near it change. It is kept by the game.
+type chunkNav struct {
+	grid  *pathfind.NavGrid
+	cache *pathfind.Cache
+	dirty bool
+}
+
+// navFor returns the navigation grid for the chunk, building it if it is out of date.
+func (g *GameSession) navFor(c ChunkCoord) *chunkNav {
+	nav := g.nav[c]
+	if nav != nil && !nav.dirty {
+		return nav
+	}
+	origin := physics.Vect2{X: c.X * ChunkSize, Y: c.Y * ChunkSize}
+	area := quadtree.NewBoundingBox(origin.X-navClearance, origin.X+ChunkSize+navClearance, origin.Y-navClearance, origin.Y+ChunkSize+navClearance)
+	bodies := []*physics.RigidBody{}
+	for _, body := range g.World.Space.Fixed {
+		if body != nil && body.Bounds().Intersects(area) {
+			bodies = append(bodies, body)
+		}
+	}
+	cells := int(ChunkSize / navCellSize)
+	grid := pathfind.NewNavGrid(origin, cells, cells, navCellSize, navClearance, bodies)
+	if nav == nil {
+		nav = &chunkNav{cache: pathfind.NewCache(grid, navCacheSize)}
+		g.nav[c] = nav
+	} else {
+		nav.cache.Invalidate(grid)
+	}
+	nav.grid = grid
+	nav.dirty = false
+	return nav
+}
+
+// invalidateNav marks the navigation grids of every chunk an obstacle with these bounds
+// could block as out of date.
+func (g *GameSession) invalidateNav(b quadtree.BoundingBox) {
+	min := ChunkAt(physics.Vect2{X: b.MinX - navClearance, Y: b.MinY - navClearance})
+	max := ChunkAt(physics.Vect2{X: b.MaxX + navClearance, Y: b.MaxY + navClearance})
+	for x := min.X; x <= max.X; x++ {
+		for y := min.Y; y <= max.Y; y++ {
+			if nav := g.nav[ChunkCoord{X: x, Y: y}]; nav != nil {
+				nav.dirty = true
+			}
+		}
+	}
+}
+
+// invalidateChunkNav marks the chunk's navigation grid and its neighbors' as out of date,
+// obstacles near the edge of a chunk block cells in the chunks next to it.
+func (g *GameSession) invalidateChunkNav(c ChunkCoord) {
+	origin := physics.Vect2{X: c.X * ChunkSize, Y: c.Y * ChunkSize}
+	g.invalidateNav(quadtree.NewBoundingBox(origin.X-1, origin.X+ChunkSize, origin.Y-1, origin.Y+ChunkSize))
+}
+
+// routeDir is the direction to move from one position to get to another around the obstacles between them.
+// Routes are only found within a chunk, anywhere else or when there is no route it is straight at the goal.
+func (g *GameSession) routeDir(from, to physics.Vect2) physics.Vect2 {
+	straight := physics.SubVect2(to, from)
+	c := ChunkAt(from)
+	if ChunkAt(to) != c {
+		return straight
+	}
+	nav := g.navFor(c)
+	path := nav.cache.Path(nav.grid.Cell(from), nav.grid.Cell(to))
+	if len(path) <= 2 {
+		return straight // No route, or nothing in the way so head right at it instead of the middle of its cell.
+	}
+	return physics.SubVect2(nav.grid.Center(path[1]), from)
+}
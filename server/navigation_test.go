@@ -0,0 +1,30 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/lologarithm/survival/physics"
+)
+
+func TestRouteDir(t *testing.T) {
+	out := make(chan OutgoingMessage, 100)
+	g := NewGame("A", nil, nil, out)
+	from, to := physics.Vect2{X: 1050, Y: 1050}, physics.Vect2{X: 1650, Y: 1050}
+	if dir := g.routeDir(from, to); dir != physics.SubVect2(to, from) {
+		t.Fatalf("Expected to head straight at the goal, got %v", dir)
+	}
+
+	// A wall of workbenches between them.
+	for y := int32(750); y <= 1350; y += StationSize {
+		g.build(WorkbenchEType, physics.Vect2{X: 1350, Y: y})
+	}
+	dir := g.routeDir(from, to)
+	if dir.Y == 0 || g.nav[ChunkCoord{}].dirty {
+		t.Fatalf("Expected to go around the new wall, got %v", dir)
+	}
+
+	across := physics.Vect2{X: ChunkSize + 100, Y: 1050}
+	if dir := g.routeDir(from, across); dir != physics.SubVect2(across, from) {
+		t.Fatalf("Routes to other chunks should be straight, got %v", dir)
+	}
+}
@@ -0,0 +1,182 @@
+package server
+
+import (
+	"math"
+
+	"github.com/lologarithm/survival/physics"
+	"github.com/lologarithm/survival/physics/quadtree"
+	"github.com/lologarithm/survival/server/messages"
+)
+
+// MaxNeed is how full Hunger and Thirst can be.
+const MaxNeed int16 = 10000
+
+// Hunger and Thirst go down by this much every decayInterval ticks. At 30 ticks a second
+// full to empty takes a bit over 5 minutes without water and 11 minutes without food.
+const (
+	decayInterval       = 2
+	hungerDecay   int16 = 1
+	thirstDecay   int16 = 2
+)
+
+// Body temperatures, in tenths of a degree. Outside of Cold to Hot the character takes damage.
+const (
+	NormalTemperature int16 = 370
+	ColdTemperature   int16 = 355
+	HotTemperature    int16 = 385
+)
+
+// Air temperatures, in tenths of a degree. The air swings DaySwing above and below
+// ComfortTemperature over a day, and is CampfireWarmth warmer within CampfireRange of a campfire.
+const (
+	ComfortTemperature int16 = 200
+	DaySwing           int16 = 100
+	CampfireWarmth     int16 = 150
+	CampfireRange            = 300
+)
+
+// DayTicks is how long a day is, 10 minutes at 30 ticks a second.
+const DayTicks = 30 * 60 * 10
+
+// Every needInterval ticks body temperature moves a step toward the air and
+// each need that has run out does needDamage to the character.
+const (
+	needInterval       = 30
+	needDamage   int32 = 2
+)
+
+// FullNeeds is how a character starts and comes back after dying.
+var FullNeeds = Needs{Hunger: MaxNeed, Thirst: MaxNeed, Temperature: NormalTemperature}
+
+// Consumables are the items that can be consumed and what they do to the needs.
+var Consumables = map[uint16]Needs{
+	BerryItem: {Hunger: 600, Thirst: 200},
+	TeaItem:   {Hunger: 100, Thirst: 1500, Temperature: 15},
+}
+
+// decay takes one step of Hunger and Thirst.
+func (n *Needs) decay() {
+	n.Hunger = max16(n.Hunger-hungerDecay, 0)
+	n.Thirst = max16(n.Thirst-thirstDecay, 0)
+}
+
+// warm moves body temperature a step toward what the air would settle it at.
+// Every 4 tenths the air is away from ComfortTemperature moves the body a tenth away from NormalTemperature.
+func (n *Needs) warm(air int16) {
+	target := NormalTemperature + (air-ComfortTemperature)/4
+	if n.Temperature < target {
+		n.Temperature++
+	} else if n.Temperature > target {
+		n.Temperature--
+	}
+}
+
+// consume adds what an item gives to the needs, Hunger and Thirst can't go past MaxNeed.
+func (n *Needs) consume(c Needs) {
+	n.Hunger = min16(n.Hunger+c.Hunger, MaxNeed)
+	n.Thirst = min16(n.Thirst+c.Thirst, MaxNeed)
+	n.Temperature += c.Temperature
+}
+
+// damage is how much HP the needs take this interval.
+func (n *Needs) damage() int32 {
+	dmg := int32(0)
+	if n.Hunger == 0 {
+		dmg += needDamage
+	}
+	if n.Thirst == 0 {
+		dmg += needDamage
+	}
+	if n.Temperature < ColdTemperature || n.Temperature > HotTemperature {
+		dmg += needDamage
+	}
+	return dmg
+}
+
+// status is the compact form of the needs sent to the client.
+func (n *Needs) status() messages.CharacterStatus {
+	return messages.CharacterStatus{
+		Hunger:      byte(int32(n.Hunger) * 100 / int32(MaxNeed)),
+		Thirst:      byte(int32(n.Thirst) * 100 / int32(MaxNeed)),
+		Temperature: n.Temperature,
+	}
+}
+
+// airTemperature is how warm the air is at the position at the tick.
+func (gw *GameWorld) airTemperature(pos physics.Vect2, tick uint32) int16 {
+	day := math.Sin(2 * math.Pi * float64(tick%DayTicks) / DayTicks)
+	air := ComfortTemperature + int16(float64(DaySwing)*day)
+	area := quadtree.NewBoundingBox(pos.X-CampfireRange, pos.X+CampfireRange, pos.Y-CampfireRange, pos.Y+CampfireRange)
+	for _, body := range gw.Space.Query(area) {
+		if e := gw.Entities[body.ID]; e != nil && e.EType == CampfireEType {
+			air += CampfireWarmth
+			break
+		}
+	}
+	return air
+}
+
+// UpdateNeeds decays every living character's needs, hurts the ones whose needs ran out,
+// and tells each client when its character's status changed.
+func (g *GameSession) UpdateNeeds() {
+	now := g.World.Space.TickID
+	for _, user := range g.Clients {
+		char := user.Accounts[0].Character
+		ent := g.World.Entities[char.ID]
+		if user.respawnAt != 0 || ent == nil {
+			continue
+		}
+		if now%decayInterval == 0 {
+			char.Needs.decay()
+		}
+		if now%needInterval == 0 {
+			char.Needs.warm(g.World.airTemperature(ent.Body.Position, now))
+			if dmg := char.Needs.damage(); dmg > 0 {
+				g.damageCharacter(char.ID, 0, dmg)
+			}
+		}
+		g.sendStatus(user)
+	}
+}
+
+// sendStatus sends the character's status to its client if it changed since the last one sent.
+func (g *GameSession) sendStatus(user *User) {
+	status := user.Accounts[0].Character.Needs.status()
+	if status == user.status {
+		return
+	}
+	user.status = status
+	g.ToNetwork <- NewReliableMsg(user.Client, messages.CharacterStatusMsgType, &status)
+}
+
+// Consume eats or drinks one of an item in the player's backpack.
+func (g *GameSession) Consume(c *Client, tmsg *messages.Consume) {
+	user := g.Clients[c.ID]
+	if user == nil {
+		return
+	}
+	defer g.sendInventory(user)
+	char := user.Accounts[0].Character
+	idx := char.inventoryIndex(tmsg.ItemID)
+	if user.respawnAt != 0 || idx == -1 {
+		return
+	}
+	item := char.InventoryItems[idx]
+	effect, ok := Consumables[item.Kind]
+	if !ok {
+		return
+	}
+	item.Count--
+	if item.Count == 0 {
+		char.takeItem(item.ID)
+	}
+	char.Needs.consume(effect)
+	g.sendStatus(user)
+}
+
+func max16(a, b int16) int16 {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,95 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/lologarithm/survival/physics"
+	"github.com/lologarithm/survival/server/messages"
+)
+
+func TestNeeds(t *testing.T) {
+	n := FullNeeds
+	for i := 0; i < int(MaxNeed); i++ {
+		n.decay()
+	}
+	if n.Hunger != 0 || n.Thirst != 0 || n.damage() != 2*needDamage {
+		t.Fatalf("Expected needs to run out and hurt: %v", n)
+	}
+	n.consume(Consumables[BerryItem])
+	if n.Hunger != 600 || n.Thirst != 200 || n.damage() != 0 {
+		t.Fatalf("Berries should feed the character: %v", n)
+	}
+
+	cold := ComfortTemperature - DaySwing
+	for i := 0; i < 100; i++ {
+		n.warm(cold)
+	}
+	if n.Temperature != NormalTemperature-DaySwing/4 || n.damage() != needDamage {
+		t.Fatalf("Expected the body to cool to the night air: %d", n.Temperature)
+	}
+	for i := 0; i < 100; i++ {
+		n.warm(cold + CampfireWarmth)
+	}
+	if n.Temperature < ColdTemperature || n.Temperature > HotTemperature {
+		t.Fatalf("A campfire should keep the character warm at night: %d", n.Temperature)
+	}
+}
+
+func TestUpdateNeeds(t *testing.T) {
+	out := make(chan OutgoingMessage, 100)
+	c := &Client{ID: 1}
+	g, chars := newTestGame(out, c)
+	char := chars[0]
+	// Needs only go down every decayInterval ticks.
+	for i := 0; i < needInterval; i++ {
+		g.simulate()
+		g.UpdateNeeds()
+	}
+	if decays := int16(needInterval / decayInterval); char.Needs.Hunger != MaxNeed-decays*hungerDecay || char.Needs.Thirst != MaxNeed-decays*thirstDecay {
+		t.Fatalf("Expected %d decays of hunger and thirst: %v", decays, char.Needs)
+	}
+	drainFor(out, c)
+
+	char.Needs.Hunger = 0
+	char.Needs.Thirst = 0
+
+	for i := 0; i < needInterval; i++ {
+		g.simulate()
+		g.UpdateNeeds()
+	}
+	if char.CurrentStats.HP != Kits[FighterKit].HP-2*needDamage {
+		t.Fatalf("Expected hunger and thirst to hurt, HP is %d", char.CurrentStats.HP)
+	}
+	msgs := drainFor(out, c)
+	if len(msgs) != 1 {
+		t.Fatalf("Expected one status update, got %v", msgs)
+	}
+	if status := msgs[0].NetMsg.(*messages.CharacterStatus); status.Hunger != 0 || status.Thirst != 0 || status.Temperature != NormalTemperature {
+		t.Fatalf("Wrong status: %v", status)
+	}
+
+	char.giveItems(BerryItem, 1, g.nextID)
+	g.Consume(c, &messages.Consume{ItemID: char.InventoryItems[0].ID})
+	if len(char.InventoryItems) != 0 || char.Needs.Hunger != Consumables[BerryItem].Hunger {
+		t.Fatalf("Berry was not eaten: %v", char.Needs)
+	}
+	var status *messages.CharacterStatus
+	for _, p := range drainFor(out, c) {
+		if s, ok := p.NetMsg.(*messages.CharacterStatus); ok {
+			status = s
+		}
+	}
+	if status == nil || status.Hunger != 6 {
+		t.Fatalf("Expected a status update after eating: %v", status)
+	}
+
+	pos := g.World.Entities[char.ID].Body.Position
+	air := g.World.airTemperature(pos, 0)
+	g.build(CampfireEType, physics.Vect2{X: pos.X + StationSize, Y: pos.Y})
+	if g.World.airTemperature(pos, 0) != air+CampfireWarmth {
+		t.Fatalf("Campfire should warm the air.")
+	}
+	if night := g.World.airTemperature(physics.Vect2{}, DayTicks*3/4); night != ComfortTemperature-DaySwing {
+		t.Fatalf("Expected the coldest air at night, got %d", night)
+	}
+}
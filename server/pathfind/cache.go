@@ -0,0 +1,44 @@
+package pathfind
+
+// Cache remembers paths found on a grid so asking for the same path again is free.
+// Invalidate has to be called whenever the grid changes.
+type Cache struct {
+	Grid  Grid
+	size  int
+	paths map[[2]Point][]Point
+	order [][2]Point // Keys in the order they were added, the oldest is dropped first.
+}
+
+// NewCache makes a cache over the grid holding up to size paths, 0 holds any number.
+func NewCache(g Grid, size int) *Cache {
+	return &Cache{Grid: g, size: size, paths: map[[2]Point][]Point{}}
+}
+
+// Path finds a smoothed path from start to goal with JPS, or nil if there is none.
+// Missing paths are cached too so unreachable goals aren't searched for over and over.
+func (c *Cache) Path(start, goal Point) []Point {
+	key := [2]Point{start, goal}
+	if path, ok := c.paths[key]; ok {
+		return path
+	}
+	path := Smooth(c.Grid, JPS(c.Grid, start, goal))
+	if len(c.order) >= c.size && c.size > 0 {
+		delete(c.paths, c.order[0])
+		c.order = c.order[1:]
+	}
+	c.paths[key] = path
+	c.order = append(c.order, key)
+	return path
+}
+
+// Len is how many paths are cached.
+func (c *Cache) Len() int {
+	return len(c.paths)
+}
+
+// Invalidate forgets every cached path and searches the grid it is given from now on.
+func (c *Cache) Invalidate(g Grid) {
+	c.Grid = g
+	c.paths = map[[2]Point][]Point{}
+	c.order = nil
+}
@@ -0,0 +1,95 @@
+package pathfind
+
+import (
+	"github.com/lologarithm/survival/physics"
+	"github.com/lologarithm/survival/server/directedPath"
+)
+
+// MapGrid searches a directedPath map, flat tiles are walkable.
+type MapGrid struct {
+	Map *directedPath.Map
+}
+
+// Size is the width and height of the map in tiles.
+func (m MapGrid) Size() (int, int) {
+	if len(m.Map.Tiles) == 0 {
+		return 0, 0
+	}
+	return len(m.Map.Tiles[0]), len(m.Map.Tiles)
+}
+
+// Walkable is true if the tile at x,y is flat.
+func (m MapGrid) Walkable(x, y int) bool {
+	return m.Map.Tiles[y][x].Walkable()
+}
+
+// NavGrid is an area of the world cut into square cells, a cell is blocked
+// if any fixed body comes within the clearance of it.
+type NavGrid struct {
+	Origin   physics.Vect2 // World position of the bottom left corner of cell 0,0.
+	CellSize int32
+	w, h     int
+	blocked  []bool
+}
+
+// NewNavGrid makes a w by h grid of cells starting at origin and blocks the cells under the bodies.
+// Bodies are grown by clearance first so whatever follows a path has room to pass them.
+func NewNavGrid(origin physics.Vect2, w, h int, cellSize, clearance int32, bodies []*physics.RigidBody) *NavGrid {
+	ng := &NavGrid{Origin: origin, CellSize: cellSize, w: w, h: h, blocked: make([]bool, w*h)}
+	for _, body := range bodies {
+		if body == nil {
+			continue
+		}
+		b := body.Bounds()
+		// Cells that only touch the edge of the body are left open.
+		min := ng.Cell(physics.Vect2{X: b.MinX - clearance, Y: b.MinY - clearance})
+		max := ng.Cell(physics.Vect2{X: b.MaxX + clearance - 1, Y: b.MaxY + clearance - 1})
+		for y := maxInt(min.Y, 0); y <= max.Y && y < h; y++ {
+			for x := maxInt(min.X, 0); x <= max.X && x < w; x++ {
+				ng.blocked[y*w+x] = true
+			}
+		}
+	}
+	return ng
+}
+
+// Size is the width and height of the grid in cells.
+func (ng *NavGrid) Size() (int, int) {
+	return ng.w, ng.h
+}
+
+// Walkable is true if no body blocks the cell.
+func (ng *NavGrid) Walkable(x, y int) bool {
+	return !ng.blocked[y*ng.w+x]
+}
+
+// Cell is the cell a world position is in, which can be outside of the grid.
+func (ng *NavGrid) Cell(pos physics.Vect2) Point {
+	return Point{
+		X: floorDiv(pos.X-ng.Origin.X, ng.CellSize),
+		Y: floorDiv(pos.Y-ng.Origin.Y, ng.CellSize),
+	}
+}
+
+// Center is the world position of the middle of the cell.
+func (ng *NavGrid) Center(p Point) physics.Vect2 {
+	return physics.Vect2{
+		X: ng.Origin.X + int32(p.X)*ng.CellSize + ng.CellSize/2,
+		Y: ng.Origin.Y + int32(p.Y)*ng.CellSize + ng.CellSize/2,
+	}
+}
+
+func floorDiv(a, b int32) int {
+	q := a / b
+	if a%b != 0 && a < 0 {
+		q--
+	}
+	return int(q)
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
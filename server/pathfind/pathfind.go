@@ -0,0 +1,298 @@
+// Package pathfind finds paths over grids of walkable cells, like directedPath maps or a
+// navigation grid made from the obstacles in the world.
+//
+// Paths move in 8 directions but never cut the corner of a blocked cell, so anything that
+// fits through a cell can follow them.
+package pathfind
+
+import (
+	"container/heap"
+	"math"
+)
+
+// Grid is anything that can be searched for paths.
+type Grid interface {
+	Size() (w, h int)
+	Walkable(x, y int) bool
+}
+
+// Point is a cell in a grid.
+type Point struct {
+	X, Y int
+}
+
+// walkable is Grid.Walkable that is false outside of the grid.
+func walkable(g Grid, x, y int) bool {
+	w, h := g.Size()
+	return x >= 0 && y >= 0 && x < w && y < h && g.Walkable(x, y)
+}
+
+// Cost is the length of the path with straight steps costing 1 and diagonal steps costing √2.
+func Cost(path []Point) float64 {
+	cost := 0.0
+	for i := 1; i < len(path); i++ {
+		cost += octile(path[i-1], path[i])
+	}
+	return cost
+}
+
+// octile is the shortest distance between two cells moving in 8 directions.
+func octile(a, b Point) float64 {
+	dx, dy := abs(a.X-b.X), abs(a.Y-b.Y)
+	if dx < dy {
+		dx, dy = dy, dx
+	}
+	return float64(dx-dy) + math.Sqrt2*float64(dy)
+}
+
+// node is a cell that has been reached by the search.
+type node struct {
+	Point
+	parent *node
+	g, f   float64
+	order  int // Order nodes were opened in, breaks ties so searches always go the same way.
+	index  int // Position in the open heap, -1 once closed.
+}
+
+type openHeap []*node
+
+func (h openHeap) Len() int { return len(h) }
+func (h openHeap) Less(i, j int) bool {
+	if h[i].f != h[j].f {
+		return h[i].f < h[j].f
+	}
+	return h[i].order < h[j].order
+}
+func (h openHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+func (h *openHeap) Push(x interface{}) {
+	n := x.(*node)
+	n.index = len(*h)
+	*h = append(*h, n)
+}
+func (h *openHeap) Pop() interface{} {
+	old := *h
+	n := old[len(old)-1]
+	old[len(old)-1] = nil
+	n.index = -1
+	*h = old[:len(old)-1]
+	return n
+}
+
+// search is the A* loop shared by AStar and JPS, successors gives the cells reachable from a node.
+func search(g Grid, start, goal Point, successors func(*node) []Point) []Point {
+	if !walkable(g, start.X, start.Y) || !walkable(g, goal.X, goal.Y) {
+		return nil
+	}
+	nodes := map[Point]*node{}
+	open := &openHeap{}
+	first := &node{Point: start, f: octile(start, goal)}
+	nodes[start] = first
+	heap.Push(open, first)
+	opened := 1
+
+	for open.Len() > 0 {
+		cur := heap.Pop(open).(*node)
+		if cur.Point == goal {
+			return expand(cur)
+		}
+		for _, p := range successors(cur) {
+			g := cur.g + octile(cur.Point, p)
+			n, ok := nodes[p]
+			if ok && (n.index == -1 || g >= n.g) {
+				continue
+			}
+			if !ok {
+				n = &node{Point: p, order: opened}
+				opened++
+				nodes[p] = n
+			}
+			n.parent = cur
+			n.g = g
+			n.f = g + octile(p, goal)
+			if ok {
+				heap.Fix(open, n.index)
+			} else {
+				heap.Push(open, n)
+			}
+		}
+	}
+	return nil
+}
+
+// expand walks back from the goal to make the path, filling in the cells between
+// jump points so every step is to a neighboring cell.
+func expand(n *node) []Point {
+	rev := []Point{}
+	for ; n != nil; n = n.parent {
+		if n.parent == nil {
+			rev = append(rev, n.Point)
+			break
+		}
+		dx, dy := sign(n.parent.X-n.X), sign(n.parent.Y-n.Y)
+		for p := n.Point; p != n.parent.Point; p = (Point{p.X + dx, p.Y + dy}) {
+			rev = append(rev, p)
+		}
+	}
+	path := make([]Point, len(rev))
+	for i, p := range rev {
+		path[len(rev)-1-i] = p
+	}
+	return path
+}
+
+// AStar finds the shortest path from start to goal, including both, or nil if there is none.
+func AStar(g Grid, start, goal Point) []Point {
+	return search(g, start, goal, func(n *node) []Point {
+		return neighbors(g, n.Point)
+	})
+}
+
+// neighbors are the cells that can be stepped to from p without cutting a corner.
+func neighbors(g Grid, p Point) []Point {
+	out := make([]Point, 0, 8)
+	for dx := -1; dx <= 1; dx++ {
+		for dy := -1; dy <= 1; dy++ {
+			if (dx != 0 || dy != 0) && canStep(g, p, dx, dy) {
+				out = append(out, Point{p.X + dx, p.Y + dy})
+			}
+		}
+	}
+	return out
+}
+
+// canStep is true if moving one cell in the direction is allowed. Diagonal steps need both cells beside them open.
+func canStep(g Grid, p Point, dx, dy int) bool {
+	if !walkable(g, p.X+dx, p.Y+dy) {
+		return false
+	}
+	return dx == 0 || dy == 0 || (walkable(g, p.X+dx, p.Y) && walkable(g, p.X, p.Y+dy))
+}
+
+// JPS finds the same length of path as AStar using jump point search, which skips over
+// open areas instead of adding every cell to the open list. It is much faster on open grids.
+func JPS(g Grid, start, goal Point) []Point {
+	return search(g, start, goal, func(n *node) []Point {
+		out := []Point{}
+		for _, p := range pruned(g, n) {
+			if jp, ok := jump(g, p, n.Point, goal); ok {
+				out = append(out, jp)
+			}
+		}
+		return out
+	})
+}
+
+// pruned returns the neighbors of a node worth looking at given the direction it was reached from.
+func pruned(g Grid, n *node) []Point {
+	if n.parent == nil {
+		return neighbors(g, n.Point)
+	}
+	x, y := n.X, n.Y
+	dx, dy := sign(x-n.parent.X), sign(y-n.parent.Y)
+	out := make([]Point, 0, 5)
+	add := func(px, py int) {
+		out = append(out, Point{px, py})
+	}
+	switch {
+	case dx != 0 && dy != 0:
+		nextY, nextX := walkable(g, x, y+dy), walkable(g, x+dx, y)
+		if nextY {
+			add(x, y+dy)
+		}
+		if nextX {
+			add(x+dx, y)
+		}
+		if nextX && nextY && walkable(g, x+dx, y+dy) {
+			add(x+dx, y+dy)
+		}
+	case dx != 0:
+		next, up, down := walkable(g, x+dx, y), walkable(g, x, y+1), walkable(g, x, y-1)
+		if next {
+			add(x+dx, y)
+			if up && walkable(g, x+dx, y+1) {
+				add(x+dx, y+1)
+			}
+			if down && walkable(g, x+dx, y-1) {
+				add(x+dx, y-1)
+			}
+		}
+		if up {
+			add(x, y+1)
+		}
+		if down {
+			add(x, y-1)
+		}
+	default:
+		next, right, left := walkable(g, x, y+dy), walkable(g, x+1, y), walkable(g, x-1, y)
+		if next {
+			add(x, y+dy)
+			if right && walkable(g, x+1, y+dy) {
+				add(x+1, y+dy)
+			}
+			if left && walkable(g, x-1, y+dy) {
+				add(x-1, y+dy)
+			}
+		}
+		if right {
+			add(x+1, y)
+		}
+		if left {
+			add(x-1, y)
+		}
+	}
+	return out
+}
+
+// jump moves from p away from its parent until it finds the goal, a cell with a forced
+// neighbor, or a blocked cell. Returns the jump point and true if one was found.
+func jump(g Grid, p, parent, goal Point) (Point, bool) {
+	dx, dy := p.X-parent.X, p.Y-parent.Y
+	for {
+		x, y := p.X, p.Y
+		if !walkable(g, x, y) || (dx != 0 && dy != 0 && !(walkable(g, x-dx, y) && walkable(g, x, y-dy))) {
+			return Point{}, false
+		}
+		if p == goal {
+			return p, true
+		}
+		switch {
+		case dx != 0 && dy != 0:
+			if _, ok := jump(g, Point{x + dx, y}, p, goal); ok {
+				return p, true
+			}
+			if _, ok := jump(g, Point{x, y + dy}, p, goal); ok {
+				return p, true
+			}
+		case dx != 0:
+			// A wall beside us ending opens a way around it.
+			if (walkable(g, x, y+1) && !walkable(g, x-dx, y+1)) || (walkable(g, x, y-1) && !walkable(g, x-dx, y-1)) {
+				return p, true
+			}
+		default:
+			if (walkable(g, x+1, y) && !walkable(g, x+1, y-dy)) || (walkable(g, x-1, y) && !walkable(g, x-1, y-dy)) {
+				return p, true
+			}
+		}
+		p = Point{x + dx, y + dy}
+	}
+}
+
+func sign(v int) int {
+	if v < 0 {
+		return -1
+	} else if v > 0 {
+		return 1
+	}
+	return 0
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,228 @@
+package pathfind
+
+import (
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/lologarithm/survival/physics"
+	"github.com/lologarithm/survival/server/directedPath"
+)
+
+// testGrid is a grid read from rows of text, '#' is blocked. Row 0 is the top.
+type testGrid []string
+
+func (g testGrid) Size() (int, int)       { return len(g[0]), len(g) }
+func (g testGrid) Walkable(x, y int) bool { return g[y][x] != '#' }
+
+// randomGrid blocks about a third of the cells.
+func randomGrid(r *rand.Rand, w, h int) testGrid {
+	g := make(testGrid, h)
+	for y := range g {
+		row := make([]byte, w)
+		for x := range row {
+			row[x] = '.'
+			if r.Intn(3) == 0 {
+				row[x] = '#'
+			}
+		}
+		g[y] = string(row)
+	}
+	return g
+}
+
+// checkPath fails if the path doesn't go from start to goal in legal single steps.
+func checkPath(t *testing.T, g Grid, path []Point, start, goal Point) {
+	if path[0] != start || path[len(path)-1] != goal {
+		t.Fatalf("Path should go from %v to %v: %v", start, goal, path)
+	}
+	for i := 1; i < len(path); i++ {
+		dx, dy := path[i].X-path[i-1].X, path[i].Y-path[i-1].Y
+		if abs(dx) > 1 || abs(dy) > 1 || !canStep(g, path[i-1], dx, dy) {
+			t.Fatalf("Illegal step from %v to %v in %v", path[i-1], path[i], path)
+		}
+	}
+}
+
+func TestShortestPath(t *testing.T) {
+	g := testGrid{
+		"..........",
+		".######...",
+		"......#...",
+		"......#...",
+		"..#####...",
+		"..........",
+	}
+	start, goal := Point{0, 3}, Point{8, 3}
+	for name, find := range map[string]func(Grid, Point, Point) []Point{"AStar": AStar, "JPS": JPS} {
+		path := find(g, start, goal)
+		checkPath(t, g, path, start, goal)
+		// Under the wall: along the bottom row with a diagonal step on and off it at each end.
+		if want := 8 + 2*math.Sqrt2; math.Abs(Cost(path)-want) > 1e-9 {
+			t.Fatalf("%s path cost %f, expected %f: %v", name, Cost(path), want, path)
+		}
+	}
+
+	if path := JPS(g, start, Point{1, 1}); path != nil {
+		t.Fatalf("Blocked goal should have no path: %v", path)
+	}
+	walled := testGrid{
+		"..#..",
+		"..#..",
+		"..#..",
+	}
+	if AStar(walled, Point{0, 0}, Point{4, 0}) != nil || JPS(walled, Point{0, 0}, Point{4, 0}) != nil {
+		t.Fatalf("Expected no path through a wall.")
+	}
+	corner := testGrid{
+		".#",
+		"#.",
+	}
+	if JPS(corner, Point{0, 0}, Point{1, 1}) != nil {
+		t.Fatalf("Paths shouldn't cut corners.")
+	}
+}
+
+func TestJPSMatchesAStar(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 200; i++ {
+		g := randomGrid(r, 30, 20)
+		start, goal := Point{r.Intn(30), r.Intn(20)}, Point{r.Intn(30), r.Intn(20)}
+		a, j := AStar(g, start, goal), JPS(g, start, goal)
+		if (a == nil) != (j == nil) {
+			t.Fatalf("AStar found %v but JPS found %v on\n%s", a, j, strings.Join(g, "\n"))
+		}
+		if a == nil {
+			continue
+		}
+		checkPath(t, g, j, start, goal)
+		if math.Abs(Cost(a)-Cost(j)) > 1e-9 {
+			t.Fatalf("JPS cost %f but AStar cost %f from %v to %v on\n%s", Cost(j), Cost(a), start, goal, strings.Join(g, "\n"))
+		}
+	}
+}
+
+func TestSmooth(t *testing.T) {
+	g := testGrid{
+		"........",
+		"...#....",
+		"........",
+	}
+	path := JPS(g, Point{0, 2}, Point{7, 0})
+	smooth := Smooth(g, path)
+	if len(smooth) >= len(path) || smooth[0] != path[0] || smooth[len(smooth)-1] != path[len(path)-1] {
+		t.Fatalf("Expected fewer waypoints with the same ends: %v from %v", smooth, path)
+	}
+	for i := 1; i < len(smooth); i++ {
+		if !LineOfSight(g, smooth[i-1], smooth[i]) {
+			t.Fatalf("No line of sight from %v to %v", smooth[i-1], smooth[i])
+		}
+	}
+	if LineOfSight(g, Point{0, 0}, Point{7, 2}) || !LineOfSight(g, Point{0, 0}, Point{7, 0}) {
+		t.Fatalf("Line of sight is wrong around the block.")
+	}
+	corner := testGrid{
+		".#",
+		"..",
+	}
+	if LineOfSight(corner, Point{0, 0}, Point{1, 1}) {
+		t.Fatalf("Line of sight shouldn't squeeze past a corner.")
+	}
+}
+
+func TestMapGrid(t *testing.T) {
+	rows := []string{
+		"#######",
+		"#__#__#",
+		"#__#__#",
+		"#_____#",
+		"#######",
+	}
+	m := directedPath.NewMap()
+	for _, row := range rows {
+		tiles := make([]directedPath.Tile, len(row))
+		for x, c := range row {
+			tiles[x] = directedPath.Wall
+			if c == '_' {
+				tiles[x] = directedPath.Flat
+			}
+		}
+		m.Tiles = append(m.Tiles, tiles)
+	}
+	g := MapGrid{Map: m}
+	if w, h := g.Size(); w != 7 || h != 5 {
+		t.Fatalf("Wrong size %d,%d", w, h)
+	}
+	start, goal := Point{1, 1}, Point{5, 1}
+	path := JPS(g, start, goal)
+	checkPath(t, g, path, start, goal)
+	for _, p := range path {
+		if p.Y == 1 && p.X == 3 || p.Y == 2 && p.X == 3 {
+			t.Fatalf("Path goes through the wall: %v", path)
+		}
+	}
+}
+
+func TestNavGrid(t *testing.T) {
+	rock := physics.NewRigidBody(1, 100, 100, physics.Vect2{X: 500, Y: 500}, physics.Vect2{}, 0, 0)
+	ng := NewNavGrid(physics.Vect2{X: 100, Y: 100}, 10, 10, 100, 0, []*physics.RigidBody{rock})
+	for y := 0; y < 10; y++ {
+		for x := 0; x < 10; x++ {
+			// The rock covers 450 to 550 so it blocks cells 3 and 4 on each axis.
+			blocked := (x == 3 || x == 4) && (y == 3 || y == 4)
+			if ng.Walkable(x, y) == blocked {
+				t.Fatalf("Cell %d,%d should be blocked: %v", x, y, blocked)
+			}
+		}
+	}
+	if c := ng.Cell(physics.Vect2{X: 99, Y: 250}); c != (Point{-1, 1}) {
+		t.Fatalf("Wrong cell %v", c)
+	}
+	if p := ng.Center(Point{2, 3}); p != (physics.Vect2{X: 350, Y: 450}) {
+		t.Fatalf("Wrong center %v", p)
+	}
+
+	clear := NewNavGrid(physics.Vect2{X: 100, Y: 100}, 10, 10, 100, 60, []*physics.RigidBody{rock})
+	blocked := 0
+	for y := 0; y < 10; y++ {
+		for x := 0; x < 10; x++ {
+			if !clear.Walkable(x, y) {
+				blocked++
+			}
+		}
+	}
+	if blocked != 16 {
+		t.Fatalf("Clearance should block a ring of cells around the rock, %d blocked", blocked)
+	}
+}
+
+func TestCache(t *testing.T) {
+	open := testGrid{
+		"....",
+		"....",
+		"....",
+	}
+	c := NewCache(open, 2)
+	path := c.Path(Point{0, 0}, Point{3, 2})
+	if len(path) != 2 {
+		t.Fatalf("Expected a straight path on an open grid: %v", path)
+	}
+	c.Path(Point{0, 0}, Point{3, 0})
+	c.Path(Point{0, 0}, Point{3, 1})
+	if c.Len() != 2 {
+		t.Fatalf("Cache should hold at most 2 paths, has %d", c.Len())
+	}
+
+	c.Invalidate(testGrid{
+		".#..",
+		".#..",
+		"....",
+	})
+	if c.Len() != 0 {
+		t.Fatalf("Invalidate should empty the cache.")
+	}
+	if path := c.Path(Point{0, 0}, Point{3, 0}); len(path) <= 2 {
+		t.Fatalf("Expected a path around the new wall: %v", path)
+	}
+}
@@ -0,0 +1,56 @@
+package pathfind
+
+// Smooth removes the waypoints of a path that can be skipped by walking straight,
+// leaving only the corners. Every cell a straight part passes through is walkable.
+func Smooth(g Grid, path []Point) []Point {
+	if len(path) < 3 {
+		return path
+	}
+	out := []Point{path[0]}
+	anchor := path[0]
+	for i := 2; i < len(path); i++ {
+		if !LineOfSight(g, anchor, path[i]) {
+			anchor = path[i-1]
+			out = append(out, anchor)
+		}
+	}
+	return append(out, path[len(path)-1])
+}
+
+// LineOfSight is true if every cell the straight line between the centers of a and b touches is walkable.
+// Where the line passes exactly through a corner both cells beside it have to be open.
+func LineOfSight(g Grid, a, b Point) bool {
+	dx, dy := abs(b.X-a.X), abs(b.Y-a.Y)
+	sx, sy := sign(b.X-a.X), sign(b.Y-a.Y)
+	x, y := a.X, a.Y
+	if !walkable(g, x, y) {
+		return false
+	}
+	// Walk the cells in the order the line enters them. err compares how far along
+	// the line the next x border is against the next y border, scaled to stay in integers.
+	err := dx - dy
+	for i := 0; i < dx+dy; i++ {
+		e2 := 2 * err
+		switch {
+		case e2 > 0:
+			x += sx
+			err -= 2 * dy
+		case e2 < 0:
+			y += sy
+			err += 2 * dx
+		default:
+			// Through a corner, the cells on both sides are touched.
+			if !walkable(g, x+sx, y) || !walkable(g, x, y+sy) {
+				return false
+			}
+			x += sx
+			y += sy
+			err += 2*dx - 2*dy
+			i++
+		}
+		if !walkable(g, x, y) {
+			return false
+		}
+	}
+	return true
+}
@@ -1,10 +1,20 @@
 package server
 
+import "github.com/lologarithm/survival/server/messages"
+
 // User maps a connection to a list of accounts
 type User struct {
 	Accounts []*Account // List of authenticated accounts
 	Client   *Client    // Client connection
 	GameID   uint32     // Currently connected game ID
+
+	visible   map[uint32]bool          // Entity IDs this user has been told about, kept by the game.
+	states    stateHistory             // States sent to this user, kept by the game.
+	cooldowns map[uint32]uint32        // Ability ID to the tick it can be used again, kept by the game.
+	respawnAt uint32                   // Tick a dead character comes back at, 0 while alive, kept by the game.
+	respawn   func(*GameWorld)         // Command that puts the dead character back in the world, kept by the game.
+	crafting  *crafting                // What the character is crafting right now, kept by the game.
+	status    messages.CharacterStatus // Last status sent to the client, kept by the game.
 }
 
 // Account is mostly a container for character and has a password to use them.
@@ -19,6 +29,7 @@ type Account struct {
 type Character struct {
 	Stats        Stats // Stats of the character when unaltered
 	CurrentStats Stats // Current state of the character.
+	Needs        Needs // What the character has to keep up to survive.
 
 	ID             uint32
 	Name           string
@@ -35,3 +46,33 @@ type Stats struct {
 	MagicStrength    int16 // MagicStrength is how much power is added to magical abilities
 	PhysicalStrength int16 // PhysicalStrength is how much power is added to physical abilities
 }
+
+// Needs are what a character has to keep up to stay alive, each one running out hurts.
+type Needs struct {
+	Hunger      int16 // Hunger is how fed the character is, from MaxNeed down to 0 when it is starving
+	Thirst      int16 // Thirst is how much water the character has, from MaxNeed down to 0 when it is parched
+	Temperature int16 // Temperature is body temperature in tenths of a degree, too far from NormalTemperature hurts
+}
+
+// Add returns the sum of both stats.
+func (s Stats) Add(o Stats) Stats {
+	return Stats{
+		HP:               s.HP + o.HP,
+		Stamina:          s.Stamina + o.Stamina,
+		Concentration:    s.Concentration + o.Concentration,
+		Speed:            s.Speed + o.Speed,
+		MagicStrength:    s.MagicStrength + o.MagicStrength,
+		PhysicalStrength: s.PhysicalStrength + o.PhysicalStrength,
+	}
+}
+
+func (s Stats) toMsg() *messages.Stats {
+	return &messages.Stats{
+		HP:               s.HP,
+		Stamina:          s.Stamina,
+		Concentration:    s.Concentration,
+		Speed:            s.Speed,
+		MagicStrength:    s.MagicStrength,
+		PhysicalStrength: s.PhysicalStrength,
+	}
+}
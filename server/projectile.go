@@ -0,0 +1,213 @@
+package server
+
+import (
+	"math"
+	"sort"
+
+	"github.com/lologarithm/survival/physics"
+	"github.com/lologarithm/survival/physics/quadtree"
+	"github.com/lologarithm/survival/server/messages"
+)
+
+// ProjectileSize is the height and width of a projectile body.
+const ProjectileSize = 6
+
+// Projectile is what makes an entity a projectile. It never changes after the projectile is fired.
+type Projectile struct {
+	Owner  uint32        // Entity that fired it, it is never hit by its own projectile.
+	Damage int32         // HP taken from whatever it hits.
+	Range  int32         // How far it can travel from Origin before it is removed.
+	Origin physics.Vect2 // Where it was fired from.
+}
+
+// projectileHit is a projectile that hit something during a tick.
+type projectileHit struct {
+	TickID     uint32
+	Projectile uint32
+	Owner      uint32
+	Target     uint32
+	Damage     int32
+}
+
+// SpawnProjectile fires a projectile from the owner at the given tick, moving toward dir at speed units/sec.
+// Returns the new projectile's ID, or 0 if dir has no direction.
+func (g *GameSession) SpawnProjectile(tick uint32, owner uint32, dir physics.Vect2, speed int32, damage int32, rng int32) uint32 {
+	if dir.X == 0 && dir.Y == 0 {
+		return 0
+	}
+	id := g.nextID()
+	vel := physics.NormalizeVect2(dir, speed)
+	g.queueCommand(tick, func(w *GameWorld) {
+		if _, ok := w.Entities[id]; ok {
+			return
+		}
+		shooter := w.Entities[owner]
+		if shooter == nil {
+			return
+		}
+		body := physics.NewRigidBody(id, ProjectileSize, ProjectileSize, shooter.Body.Position, vel, 0, 1)
+		body.Sensor = true
+		w.Entities[id] = &Entity{
+			ID:    id,
+			EType: ProjectileEType,
+			Body:  body,
+			Projectile: &Projectile{
+				Owner:  owner,
+				Damage: damage,
+				Range:  rng,
+				Origin: shooter.Body.Position,
+			},
+		}
+		w.Space.AddEntity(body, false)
+	})
+	return id
+}
+
+// stepProjectiles checks every projectile for hits along the path it moved in the last tick,
+// so fast projectiles can't pass through something between ticks. Projectiles that hit
+// something or went past their range are removed.
+func (gw *GameWorld) stepProjectiles() []projectileHit {
+	ids := []uint32{}
+	for id, e := range gw.Entities {
+		if e.Projectile != nil {
+			ids = append(ids, id)
+		}
+	}
+	// Go in ID order so replays always hit the same way.
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	hits := []projectileHit{}
+	for _, id := range ids {
+		e := gw.Entities[id]
+		if e == nil {
+			continue // Removed by an earlier projectile this tick.
+		}
+		end := e.Body.Position
+		start := physics.SubVect2(end, physics.Vect2{
+			X: e.Body.Velocity.X / physics.SimUpdatesPerSecond,
+			Y: e.Body.Velocity.Y / physics.SimUpdatesPerSecond,
+		})
+		if target := gw.sweep(e, start, end); target != nil {
+			hits = append(hits, projectileHit{
+				TickID:     gw.Space.TickID,
+				Projectile: id,
+				Owner:      e.Projectile.Owner,
+				Target:     target.ID,
+				Damage:     e.Projectile.Damage,
+			})
+			gw.removeEntity(e)
+			continue
+		}
+		traveled := physics.SubVect2(end, e.Projectile.Origin).Magnitude()
+		if traveled >= float64(e.Projectile.Range) {
+			gw.removeEntity(e)
+		}
+	}
+	return hits
+}
+
+// sweep finds the first entity the projectile touches moving from start to end.
+func (gw *GameWorld) sweep(e *Entity, start, end physics.Vect2) *Entity {
+	hw, hh := e.Body.Width/2, e.Body.Height/2
+	area := quadtree.NewBoundingBox(
+		min32(start.X, end.X)-hw, max32(start.X, end.X)+hw,
+		min32(start.Y, end.Y)-hh, max32(start.Y, end.Y)+hh,
+	)
+	var first *Entity
+	firstT := math.Inf(1)
+	for _, body := range gw.Space.Query(area) {
+		target := gw.Entities[body.ID]
+		if target == nil || target == e || target.ID == e.Projectile.Owner || target.Projectile != nil || target.Item != nil {
+			continue
+		}
+		// Grow the target by the projectile size so the projectile can be treated as a point.
+		bb := body.Bounds()
+		bb = quadtree.NewBoundingBox(bb.MinX-hw, bb.MaxX+hw, bb.MinY-hh, bb.MaxY+hh)
+		t, ok := segmentHitsBox(start, end, bb)
+		if ok && (t < firstT || (t == firstT && target.ID < first.ID)) {
+			first, firstT = target, t
+		}
+	}
+	return first
+}
+
+// segmentHitsBox returns how far along the segment (0 to 1) it first touches the box.
+func segmentHitsBox(start, end physics.Vect2, bb quadtree.BoundingBox) (float64, bool) {
+	tmin, tmax := 0.0, 1.0
+	slab := func(s, e, min, max int32) bool {
+		d := float64(e - s)
+		if d == 0 {
+			return s >= min && s <= max
+		}
+		t1 := float64(min-s) / d
+		t2 := float64(max-s) / d
+		if t1 > t2 {
+			t1, t2 = t2, t1
+		}
+		tmin = math.Max(tmin, t1)
+		tmax = math.Min(tmax, t2)
+		return tmin <= tmax
+	}
+	if !slab(start.X, end.X, bb.MinX, bb.MaxX) || !slab(start.Y, end.Y, bb.MinY, bb.MaxY) {
+		return 0, false
+	}
+	return tmin, true
+}
+
+// removeEntity takes an entity out of the world and the physics space.
+func (gw *GameWorld) removeEntity(e *Entity) {
+	gw.Space.RemoveEntity(e.Body, false)
+	delete(gw.Entities, e.ID)
+}
+
+// applyHits damages characters hit by projectiles and tells everyone who can see the target.
+// A hit is only applied once even if the tick it happened in is simulated again.
+func (g *GameSession) applyHits(hits []projectileHit) {
+	for _, hit := range hits {
+		if _, ok := g.appliedHits[hit.Projectile]; ok {
+			continue
+		}
+		g.appliedHits[hit.Projectile] = hit.TickID
+		g.damageCharacter(hit.Target, hit.Owner, hit.Damage)
+		for _, user := range g.Clients {
+			if user.visible[hit.Target] || user.visible[hit.Projectile] {
+				g.ToNetwork <- NewReliableMsg(user.Client, messages.ProjectileHitMsgType, &messages.ProjectileHit{
+					ProjectileID: hit.Projectile,
+					TargetID:     hit.Target,
+					Damage:       hit.Damage,
+				})
+			}
+		}
+	}
+	// Hits older than the history can't be simulated again.
+	now := g.World.Space.TickID
+	for id, tick := range g.appliedHits {
+		if now-tick > historyTicks {
+			delete(g.appliedHits, id)
+		}
+	}
+}
+
+// userFor returns the user whose character is the entity, or nil.
+func (g *GameSession) userFor(id uint32) *User {
+	for _, user := range g.Clients {
+		if user.Accounts[0].Character.ID == id {
+			return user
+		}
+	}
+	return nil
+}
+
+func min32(a, b int32) int32 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max32(a, b int32) int32 {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,79 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/lologarithm/survival/physics"
+	"github.com/lologarithm/survival/server/messages"
+)
+
+func TestProjectileHit(t *testing.T) {
+	out := make(chan OutgoingMessage, 1000)
+	a, b := &Client{ID: 1}, &Client{ID: 2}
+	g, chars := newTestGame(out, a, b)
+	aID, bID := chars[0].ID, chars[1].ID
+	chars[1].CurrentStats.HP = 100
+	target := g.World.Entities[bID].Body
+	target.Position = physics.Vect2{X: 6000, Y: 5000} // Still in view of the shooter.
+	g.World.Space.UpdateEntity(target)
+	g.UpdateInterest()
+	drainFor(out, a)
+
+	// Moves 1000 units a tick, much more than the target is wide.
+	id := g.SpawnProjectile(g.World.Space.TickID, aID, physics.Vect2{X: 1, Y: 0}, 50000, 30, 10000)
+	hits := []projectileHit{}
+	for i := 0; i < 5; i++ {
+		hits = append(hits, g.simulate()...)
+	}
+	if len(hits) != 1 || hits[0].Projectile != id || hits[0].Target != bID {
+		t.Fatalf("Expected the projectile to hit the target once, got %v", hits)
+	}
+	if _, ok := g.World.Entities[id]; ok {
+		t.Fatalf("Projectile should be removed after it hits.")
+	}
+
+	g.applyHits(hits)
+	g.applyHits(hits) // Same hit again, like after a rewind.
+	if hp := chars[1].CurrentStats.HP; hp != 70 {
+		t.Fatalf("Expected target to have 70 HP after one hit, has %d", hp)
+	}
+	msgs := drainFor(out, a)
+	if len(msgs) != 1 || msgs[0].NetMsg.(*messages.ProjectileHit).TargetID != bID {
+		t.Fatalf("Expected one hit notification, got %v", msgs)
+	}
+}
+
+func TestProjectileRange(t *testing.T) {
+	g, chars := newTestGame(nil, &Client{ID: 1})
+	aID := chars[0].ID
+
+	id := g.SpawnProjectile(g.World.Space.TickID, aID, physics.Vect2{X: 0, Y: -1}, 1000, 30, 100)
+	g.simulate()
+	if _, ok := g.World.Entities[id]; !ok {
+		t.Fatalf("Projectile was not spawned.")
+	}
+	for i := 0; i < 5; i++ {
+		if hits := g.simulate(); len(hits) != 0 {
+			t.Fatalf("Projectile hit something in empty space: %v", hits)
+		}
+	}
+	if _, ok := g.World.Entities[id]; ok {
+		t.Fatalf("Projectile should be removed after traveling its range.")
+	}
+	if n := len(g.World.Space.Query(g.World.Entities[aID].Body.Bounds())); n != 1 {
+		t.Fatalf("Expected only the player left in the space, found %d bodies.", n)
+	}
+}
+
+func TestSegmentHitsBox(t *testing.T) {
+	box := physics.NewRigidBody(0, 10, 10, physics.Vect2{X: 50, Y: 0}, physics.Vect2{}, 0, 0).Bounds()
+	if tHit, ok := segmentHitsBox(physics.Vect2{}, physics.Vect2{X: 100}, box); !ok || tHit != 0.45 {
+		t.Fatalf("Expected hit at 0.45, got %v %v", tHit, ok)
+	}
+	if _, ok := segmentHitsBox(physics.Vect2{}, physics.Vect2{X: 40}, box); ok {
+		t.Fatalf("Segment stops short of the box.")
+	}
+	if _, ok := segmentHitsBox(physics.Vect2{Y: 20}, physics.Vect2{X: 100, Y: 20}, box); ok {
+		t.Fatalf("Segment passes beside the box.")
+	}
+}
@@ -0,0 +1,166 @@
+package server
+
+import (
+	"sync"
+	"time"
+
+	"github.com/lologarithm/survival/server/messages"
+)
+
+// Reliable delivery settings.
+const (
+	reliableTick       = time.Millisecond * 50  // How often pending packets are checked for resend.
+	reliableTimeout    = time.Millisecond * 200 // Time before the first resend of a packet.
+	reliableMaxTimeout = time.Second * 2        // Backoff never waits longer than this.
+	reliableMaxTries   = 10                     // After this many sends the packet is dropped.
+	ackWindow          = 32                     // Number of older seqs tracked in an Ack bitfield.
+)
+
+// reliableChannel tracks sequence numbers for a single client.
+// Frame.Seq of 0 marks a packet as unreliable, any other value is a reliable packet
+// that must be acked by the receiving side.
+type reliableChannel struct {
+	mu sync.Mutex
+
+	// Outgoing state
+	localSeq uint16                    // Last seq assigned to an outgoing packet.
+	pending  map[uint16]*pendingPacket // Sent but not yet acked packets.
+
+	// Incoming state
+	remoteSeq uint16 // Most recent seq received from the other side.
+	received  uint32 // Bit n set means remoteSeq-(n+1) was received.
+	hasRemote bool
+}
+
+type pendingPacket struct {
+	data     []byte
+	resendAt time.Time
+	timeout  time.Duration
+	tries    int
+}
+
+func newReliableChannel() *reliableChannel {
+	return &reliableChannel{
+		pending: map[uint16]*pendingPacket{},
+	}
+}
+
+// seqGreater returns true if a is more recent than b, accounting for wrap around.
+func seqGreater(a, b uint16) bool {
+	return (a > b && a-b <= 32768) || (a < b && b-a > 32768)
+}
+
+// nextSeq returns the next outgoing sequence number. 0 is reserved for unreliable packets.
+func (rc *reliableChannel) nextSeq() uint16 {
+	rc.mu.Lock()
+	rc.localSeq++
+	if rc.localSeq == 0 {
+		rc.localSeq++
+	}
+	seq := rc.localSeq
+	rc.mu.Unlock()
+	return seq
+}
+
+// track stores a sent packet so it can be resent until acked.
+func (rc *reliableChannel) track(seq uint16, data []byte, now time.Time) {
+	rc.mu.Lock()
+	rc.pending[seq] = &pendingPacket{
+		data:     data,
+		resendAt: now.Add(reliableTimeout),
+		timeout:  reliableTimeout,
+		tries:    1,
+	}
+	rc.mu.Unlock()
+}
+
+// acked removes all packets covered by the ack from the pending list.
+func (rc *reliableChannel) acked(ack *messages.Ack) {
+	rc.mu.Lock()
+	delete(rc.pending, ack.Seq)
+	for i := uint16(0); i < ackWindow; i++ {
+		if ack.Bits&(1<<i) != 0 {
+			delete(rc.pending, ack.Seq-(i+1))
+		}
+	}
+	rc.mu.Unlock()
+}
+
+// due returns all packets that need to be resent now. Each resend doubles the wait before
+// the next one. Packets that were sent reliableMaxTries times are dropped and counted.
+func (rc *reliableChannel) due(now time.Time) (resend [][]byte, dropped int) {
+	rc.mu.Lock()
+	for seq, p := range rc.pending {
+		if now.Before(p.resendAt) {
+			continue
+		}
+		if p.tries >= reliableMaxTries {
+			delete(rc.pending, seq)
+			dropped++
+			continue
+		}
+		p.tries++
+		p.timeout *= 2
+		if p.timeout > reliableMaxTimeout {
+			p.timeout = reliableMaxTimeout
+		}
+		p.resendAt = now.Add(p.timeout)
+		resend = append(resend, p.data)
+	}
+	rc.mu.Unlock()
+	return resend, dropped
+}
+
+// receive records an incoming reliable seq. Returns false if the seq was already seen (or is too old to tell).
+func (rc *reliableChannel) receive(seq uint16) bool {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if !rc.hasRemote {
+		rc.remoteSeq = seq
+		rc.received = 0
+		rc.hasRemote = true
+		return true
+	}
+	if seq == rc.remoteSeq {
+		return false
+	}
+	if seqGreater(seq, rc.remoteSeq) {
+		shift := seq - rc.remoteSeq
+		if shift > ackWindow {
+			rc.received = 0
+		} else {
+			rc.received = (rc.received << shift) | (1 << (shift - 1))
+		}
+		rc.remoteSeq = seq
+		return true
+	}
+	diff := rc.remoteSeq - seq
+	if diff > ackWindow {
+		return false
+	}
+	bit := uint32(1) << (diff - 1)
+	if rc.received&bit != 0 {
+		return false
+	}
+	rc.received |= bit
+	return true
+}
+
+// ack creates an ack message for everything received so far.
+func (rc *reliableChannel) ack() *messages.Ack {
+	rc.mu.Lock()
+	a := &messages.Ack{
+		Seq:  rc.remoteSeq,
+		Bits: rc.received,
+	}
+	rc.mu.Unlock()
+	return a
+}
+
+// numPending returns how many packets are waiting for an ack.
+func (rc *reliableChannel) numPending() int {
+	rc.mu.Lock()
+	n := len(rc.pending)
+	rc.mu.Unlock()
+	return n
+}
@@ -0,0 +1,86 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lologarithm/survival/server/messages"
+)
+
+func TestReliableDuplicates(t *testing.T) {
+	rc := newReliableChannel()
+	for _, seq := range []uint16{1, 2, 4, 3} {
+		if !rc.receive(seq) {
+			t.Fatalf("Seq %d should have been new.", seq)
+		}
+	}
+	for _, seq := range []uint16{1, 2, 3, 4} {
+		if rc.receive(seq) {
+			t.Fatalf("Seq %d should have been a duplicate.", seq)
+		}
+	}
+	ack := rc.ack()
+	if ack.Seq != 4 || ack.Bits != 0x7 {
+		t.Fatalf("Incorrect ack. Expected: 4/0x7 Actual: %d/%#x", ack.Seq, ack.Bits)
+	}
+}
+
+func TestReliableWrap(t *testing.T) {
+	rc := newReliableChannel()
+	rc.receive(65534)
+	if !rc.receive(2) {
+		t.Fatalf("Seq after wrap should be new.")
+	}
+	if rc.receive(65534) {
+		t.Fatalf("Seq before wrap should be a duplicate.")
+	}
+	if !rc.receive(65535) {
+		t.Fatalf("Missed seq before wrap should be new.")
+	}
+}
+
+func TestReliableAckAndResend(t *testing.T) {
+	rc := newReliableChannel()
+	now := time.Now()
+	for i := 0; i < 3; i++ {
+		seq := rc.nextSeq()
+		rc.track(seq, []byte{byte(seq)}, now)
+	}
+
+	// Acking 3 with bit 1 set covers 3 and 1, leaving 2.
+	rc.acked(&messages.Ack{Seq: 3, Bits: 0x2})
+	if rc.numPending() != 1 {
+		t.Fatalf("Expected 1 pending packet, got %d", rc.numPending())
+	}
+
+	if resend, _ := rc.due(now); len(resend) != 0 {
+		t.Fatalf("Nothing should be due before the timeout.")
+	}
+	now = now.Add(reliableTimeout)
+	resend, _ := rc.due(now)
+	if len(resend) != 1 || resend[0][0] != 2 {
+		t.Fatalf("Expected seq 2 to be resent, got %v", resend)
+	}
+	// Backoff doubles the timeout so it should not be due again yet.
+	if resend, _ := rc.due(now.Add(reliableTimeout)); len(resend) != 0 {
+		t.Fatalf("Resend should have backed off.")
+	}
+
+	dropped := 0
+	for i := 0; i < reliableMaxTries; i++ {
+		now = now.Add(reliableMaxTimeout)
+		_, d := rc.due(now)
+		dropped += d
+	}
+	if dropped != 1 || rc.numPending() != 0 {
+		t.Fatalf("Packet should have been dropped after max tries.")
+	}
+}
+
+func TestReliableSkipsZero(t *testing.T) {
+	rc := newReliableChannel()
+	rc.localSeq = 65535
+	if seq := rc.nextSeq(); seq != 1 {
+		t.Fatalf("Seq 0 is reserved for unreliable packets, got %d", seq)
+	}
+}
@@ -0,0 +1,93 @@
+package server
+
+// historyTicks is how many ticks back a late command can still be applied.
+// At 30 ticks a second this is one second of lag.
+const historyTicks = 30
+
+// tickCommand is a change to the world that happens at the start of a tick.
+// Apply is run again whenever the tick is re-simulated so it must only change the world it is given.
+type tickCommand struct {
+	TickID uint32
+	Apply  func(*GameWorld)
+}
+
+// queueCommand records a command to be applied at the given tick.
+// Commands for a tick that was already simulated rewind the world on the next simulate.
+// Ticks older than the history (or in the future) are clamped into the valid range.
+func (g *GameSession) queueCommand(tick uint32, apply func(*GameWorld)) {
+	tick = g.clampTick(tick)
+	g.commandHistory = append(g.commandHistory, tickCommand{TickID: tick, Apply: apply})
+	if tick < g.rewindTo {
+		g.rewindTo = tick
+	}
+}
+
+// clampTick moves a tick from a client into the range of ticks still in the history.
+func (g *GameSession) clampTick(tick uint32) uint32 {
+	now := g.World.Space.TickID
+	oldest := uint32(0)
+	if now >= historyTicks {
+		oldest = now - historyTicks + 1
+	}
+	if tick > now {
+		return now
+	} else if tick < oldest {
+		return oldest
+	}
+	return tick
+}
+
+// worldAt returns the world as it was at the start of the tick, or the current world
+// if that tick is not in the history. The returned world must not be changed.
+func (g *GameSession) worldAt(tick uint32) *GameWorld {
+	if snap := g.prevWorlds[tick%historyTicks]; snap != nil && snap.Space.TickID == tick {
+		return snap
+	}
+	return g.World
+}
+
+// applyNow changes the current world and records the command so it is replayed after a rewind.
+func (g *GameSession) applyNow(apply func(*GameWorld)) {
+	apply(g.World)
+	g.commandHistory = append(g.commandHistory, tickCommand{TickID: g.World.Space.TickID, Apply: apply})
+}
+
+// simulate advances the world one tick. If commands arrived for older ticks, the world is
+// restored to the oldest of those ticks and every tick since is replayed with all commands.
+// Returns the projectile hits from every tick that was simulated.
+func (g *GameSession) simulate() []projectileHit {
+	now := g.World.Space.TickID
+	if g.rewindTo < now {
+		if snap := g.prevWorlds[g.rewindTo%historyTicks]; snap != nil && snap.Space.TickID == g.rewindTo {
+			g.World.Restore(snap)
+		}
+	}
+
+	var hits []projectileHit
+	for g.World.Space.TickID <= now {
+		tick := g.World.Space.TickID
+		g.prevWorlds[tick%historyTicks] = g.World.Clone()
+		for _, cmd := range g.commandHistory {
+			if cmd.TickID == tick {
+				cmd.Apply(g.World)
+			}
+		}
+		g.World.Space.Tick(false)
+		hits = append(hits, g.World.stepProjectiles()...)
+	}
+
+	// Drop commands that can't be replayed anymore.
+	next := g.World.Space.TickID
+	kept := g.commandHistory[:0]
+	for _, cmd := range g.commandHistory {
+		if next-cmd.TickID < historyTicks {
+			kept = append(kept, cmd)
+		}
+	}
+	for i := len(kept); i < len(g.commandHistory); i++ {
+		g.commandHistory[i] = tickCommand{}
+	}
+	g.commandHistory = kept
+	g.rewindTo = next
+	return hits
+}
@@ -1,11 +1,13 @@
 package server
 
 import (
+	"crypto/rand"
 	"crypto/rsa"
 	"fmt"
 	"log"
 	"net"
 	"os"
+	"sync/atomic"
 	"time"
 
 	"github.com/lologarithm/survival/server/messages"
@@ -22,6 +24,7 @@ type Server struct {
 	toGameManager    chan GameMessage
 	inputBuffer      []byte
 	encryptionKey    *rsa.PrivateKey
+	publicKey        []byte // Encoded public part of encryptionKey.
 
 	connections map[string]*Client
 	gameManager *GameManager
@@ -50,6 +53,9 @@ func (s *Server) handleMessage() {
 			FromGameManager: make(chan InternalMessage, 10),
 			toGameManager:   s.toGameManager,
 			ID:              s.clientID,
+			reliable:        newReliableChannel(),
+			serverKey:       s.encryptionKey,
+			serverPublicKey: s.publicKey,
 		}
 		go s.connections[addrkey].ProcessBytes(s.disconnectPlayer)
 	}
@@ -69,55 +75,64 @@ var maxPacketSize int = 512
 func (s *Server) sendMessages() {
 	for {
 		msg := <-s.outToNetwork
-		msg.msg.Frame.Seq = msg.dest.Seq
+		if msg.raw != nil {
+			// Resend of an already packed reliable packet.
+			s.writeBytes(msg.dest, msg.raw)
+			continue
+		}
+		msg.msg.Frame.Seq = 0
 		msgcontent := msg.msg.Pack()
 		totallen := len(msgcontent)
-		if totallen > maxPacketSize {
-			// calculate how many parts we have to split this into
-			maxsize := maxPacketSize - (&messages.Multipart{}).Len() - messages.FrameLen
-			parts := totallen/maxsize + 1
-			msg.dest.GroupID++
-			bstart := 0
-			for i := 0; i < parts; i++ {
-				bend := bstart + maxsize
-				if i+1 == parts {
-					bend = bstart + (totallen % maxsize)
-				}
-				wrapper := &messages.Multipart{
-					ID:       uint16(i),
-					GroupID:  msg.dest.GroupID,
-					NumParts: uint16(parts),
-					Content:  msgcontent[bstart:bend],
-				}
-				packet := &messages.Packet{
-					Frame: messages.Frame{
-						MsgType:       messages.MultipartMsgType,
-						Seq:           msg.dest.Seq,
-						ContentLength: uint16(wrapper.Len()),
-					},
-					NetMsg: wrapper,
-				}
-				msg.dest.Seq++
-				bstart = bend
-				if n, err := s.conn.WriteToUDP(packet.Pack(), msg.dest.address); err != nil {
-					fmt.Printf("Error writing to client(%v): %s, Bytes Written:  %d", msg.dest, err, n)
-				}
+		limit := maxPacketSize
+		if msg.dest.secureSession() != nil {
+			limit -= messages.SecureOverhead
+		}
+		if totallen > limit {
+			groupID := atomic.AddUint32(&msg.dest.GroupID, 1)
+			packets, err := messages.SplitPacket(groupID, msgcontent, limit)
+			if err != nil {
+				log.Printf("Dropping message to client(%v): %s", msg.dest, err)
+				continue
 			}
-		} else {
-			if n, err := s.conn.WriteToUDP(msgcontent, msg.dest.address); err != nil {
-				fmt.Printf("Error writing to client(%v): %s, Bytes Written:  %d", msg.dest, err, n)
-			} else {
-				// log.Printf("Wrote message (%v) with %d bytes to %v.", msg.msg.Pack(), n, msg.dest.address)
+			for _, packet := range packets {
+				s.writePacket(msg.dest, packet, msg.reliable)
 			}
+		} else {
+			s.writePacket(msg.dest, &msg.msg, msg.reliable)
 		}
 	}
 }
 
+// writePacket sends a single packet to the client, encrypted if the client has a session.
+// Reliable packets are given a sequence number and tracked until the client acks them.
+func (s *Server) writePacket(dest *Client, packet *messages.Packet, reliable bool) {
+	packet.Frame.Seq = 0
+	if reliable {
+		packet.Frame.Seq = dest.reliable.nextSeq()
+	}
+	if session := dest.secureSession(); session != nil {
+		packet = session.Seal(packet)
+	}
+	data := packet.Pack()
+	if !reliable {
+		s.writeBytes(dest, data)
+		return
+	}
+	dest.reliable.track(packet.Frame.Seq, data, time.Now())
+	s.writeBytes(dest, data)
+}
+
+func (s *Server) writeBytes(dest *Client, data []byte) {
+	if n, err := s.conn.WriteToUDP(data, dest.address); err != nil {
+		fmt.Printf("Error writing to client(%v): %s, Bytes Written:  %d", dest, err, n)
+	}
+}
+
 func NewServer(exit chan int) Server {
 	toGameManager := make(chan GameMessage, 1024)
 	outToNetwork := make(chan OutgoingMessage, 1024)
 
-	manager := NewGameManager(exit, toGameManager, outToNetwork)
+	manager := NewGameManager(make(chan int, 1), toGameManager, outToNetwork)
 	go manager.Run()
 
 	udpAddr, err := net.ResolveUDPAddr("udp", port)
@@ -128,11 +143,22 @@ func NewServer(exit chan int) Server {
 	fmt.Println("Now listening on port", port)
 
 	var s Server
+	s.gameManager = manager
 	s.connections = make(map[string]*Client, 512)
 	s.inputBuffer = make([]byte, 8092)
 	s.toGameManager = toGameManager
 	s.outToNetwork = outToNetwork
 	s.disconnectPlayer = make(chan Client, 512)
+	s.encryptionKey, err = rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		log.Printf("Failed to generate server key: %s", err)
+		os.Exit(1)
+	}
+	s.publicKey, err = messages.MarshalServerKey(&s.encryptionKey.PublicKey)
+	if err != nil {
+		log.Printf("Failed to encode server key: %s", err)
+		os.Exit(1)
+	}
 	s.conn, err = net.ListenUDP("udp", udpAddr)
 	if err != nil {
 		log.Printf("Failed to open UDP port: %s", err)
@@ -146,13 +172,20 @@ func RunServer(s Server, exit chan int) {
 	go s.sendMessages()
 	fmt.Println("Server Started!")
 
-	run := true
-	for run {
+	// Closing the socket right away unblocks any pending read so the port is released promptly.
+	closed := make(chan struct{})
+	go func() {
+		<-exit
+		fmt.Println("Killing Socket Server")
+		s.gameManager.Exit <- 1
+		s.conn.Close()
+		close(closed)
+	}()
+
+	for {
 		select {
-		case <-exit:
-			fmt.Println("Killing Socket Server")
-			s.conn.Close()
-			run = false
+		case <-closed:
+			return
 		case client := <-s.disconnectPlayer:
 			s.DisconnectConn(client.address.String())
 		default:
@@ -162,6 +195,8 @@ func RunServer(s Server, exit chan int) {
 }
 
 type OutgoingMessage struct {
-	dest *Client
-	msg  messages.Packet
+	dest     *Client
+	msg      messages.Packet
+	reliable bool   // Resend until the client acks it.
+	raw      []byte // Already packed bytes, used for resending reliable packets.
 }
@@ -1,6 +1,8 @@
 package server
 
 import (
+	"crypto/rand"
+	"crypto/rsa"
 	"fmt"
 	"log"
 	"net"
@@ -10,10 +12,58 @@ import (
 	"github.com/lologarithm/survival/server/messages"
 )
 
-func TestBasicServer(t *testing.T) {
+// startServer runs a server, the returned func stops it and waits until its port is released.
+func startServer() func() {
 	exit := make(chan int, 10)
 	s := NewServer(exit)
-	go RunServer(s, exit)
+	done := make(chan struct{})
+	go func() {
+		RunServer(s, exit)
+		close(done)
+	}()
+	return func() {
+		exit <- 1
+		<-done
+	}
+}
+
+// handshake sets up an encrypted session with the server over the connection.
+func handshake(t *testing.T, conn *net.UDPConn) *messages.Session {
+	conn.Write(messages.NewPacket(messages.KeyRequestMsgType, &messages.KeyRequest{}).Pack())
+	serverKey := readPacket(t, conn, nil, messages.ServerKeyMsgType).NetMsg.(*messages.ServerKey)
+	key, _ := messages.NewSessionKey()
+	encrypted, err := messages.EncryptSessionKey(serverKey.Key, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	session, _ := messages.NewSession(key, false)
+	conn.Write(messages.NewPacket(messages.SessionKeyMsgType, &messages.SessionKey{Key: encrypted}).Pack())
+	readPacket(t, conn, session, messages.SessionReadyMsgType)
+	return session
+}
+
+// readPacket reads from the server until a packet of the type arrives, opening encrypted packets with the session.
+func readPacket(t *testing.T, conn *net.UDPConn, session *messages.Session, mtype messages.MessageType) messages.Packet {
+	buf := make([]byte, 8092)
+	conn.SetReadDeadline(time.Now().Add(time.Second * 5))
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			t.Fatalf("Failed to read message %d: %s", mtype, err)
+		}
+		pack, ok := messages.NextPacket(buf[:n])
+		if ok && session != nil && pack.Frame.MsgType == messages.SecureMsgType {
+			pack, _, ok = session.Open(pack)
+		}
+		if ok && pack.Frame.MsgType == mtype {
+			return pack
+		}
+	}
+}
+
+func TestBasicServer(t *testing.T) {
+	stop := startServer()
+	defer stop()
 
 	time.Sleep(time.Millisecond * 100)
 	ra, err := net.ResolveUDPAddr("udp", "localhost:24816")
@@ -26,10 +76,11 @@ func TestBasicServer(t *testing.T) {
 		fmt.Println(err)
 		t.FailNow()
 	}
-	packet := messages.NewPacket(messages.LoginMsgType, &messages.Login{
+	session := handshake(t, conn)
+	packet := session.Seal(messages.NewPacket(messages.LoginMsgType, &messages.Login{
 		Name:     "testuser",
 		Password: "testpass",
-	})
+	}))
 	msgBytes := packet.Pack()
 	_, err = conn.Write(msgBytes)
 	if err != nil {
@@ -37,45 +88,58 @@ func TestBasicServer(t *testing.T) {
 		fmt.Println(err)
 		t.FailNow()
 	}
-	buf := make([]byte, 512)
-	n, err := conn.Read(buf[0:])
-	if err != nil {
-		fmt.Printf("Failed to read from conn.")
-		fmt.Println(err)
-		t.FailNow()
-	}
-	if n < 5 || buf[0] != byte(messages.LoginRespMsgType) {
-		fmt.Printf("Incorrect response message!")
-		t.FailNow()
-	}
+	readPacket(t, conn, session, messages.LoginRespMsgType)
 	packet = messages.NewPacket(messages.DisconnectedMsgType, &messages.Disconnected{})
 	conn.Write(packet.Pack())
-	exit <- 1
 	conn.Close()
 }
 
 func BenchmarkServerParsing(b *testing.B) {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		b.Fatal(err)
+	}
+	pub, _ := messages.MarshalServerKey(&key.PublicKey)
 	gamechan := make(chan GameMessage, 100)
+	netchan := make(chan OutgoingMessage, 10)
 	donechan := make(chan Client, 1)
 	fakeClient := &Client{
 		address:         &net.UDPAddr{},
 		FromNetwork:     NewBytePipe(0),
+		ToNetwork:       netchan,
 		FromGameManager: make(chan InternalMessage, 10),
 		toGameManager:   gamechan,
 		ID:              1,
+		serverKey:       key,
+		serverPublicKey: pub,
 	}
 	go fakeClient.ProcessBytes(donechan)
+	<-gamechan // Connected message
 
-	packet := messages.NewPacket(messages.LoginMsgType, &messages.Login{
-		Name:     "testuser",
-		Password: "testpass",
-	})
-	msgBytes := packet.Pack()
+	// Logins are only accepted over a session, so do the handshake first.
+	fakeClient.FromNetwork.Write(messages.NewPacket(messages.KeyRequestMsgType, &messages.KeyRequest{}).Pack())
+	sessionKey, _ := messages.NewSessionKey()
+	encrypted, err := messages.EncryptSessionKey((<-netchan).msg.NetMsg.(*messages.ServerKey).Key, sessionKey)
+	if err != nil {
+		b.Fatal(err)
+	}
+	fakeClient.FromNetwork.Write(messages.NewPacket(messages.SessionKeyMsgType, &messages.SessionKey{Key: encrypted}).Pack())
+	<-netchan // Session ready
+	session, _ := messages.NewSession(sessionKey, false)
+
+	// Every sealed packet can only be used once, seal them all before timing.
+	packets := make([][]byte, b.N)
+	for i := range packets {
+		packets[i] = session.Seal(messages.NewPacket(messages.LoginMsgType, &messages.Login{
+			Name:     "testuser",
+			Password: "testpass",
+		})).Pack()
+	}
 	st := time.Now()
 
 	b.ResetTimer()
 	t := 0
-	for i := 0; i < b.N; i++ {
+	for _, msgBytes := range packets {
 		fakeClient.FromNetwork.Write(msgBytes)
 		<-gamechan
 		t += len(msgBytes)
@@ -85,9 +149,8 @@ func BenchmarkServerParsing(b *testing.B) {
 }
 
 func TestMultipartMessage(t *testing.T) {
-	exit := make(chan int, 10)
-	s := NewServer(exit)
-	go RunServer(s, exit)
+	stop := startServer()
+	defer stop()
 
 	time.Sleep(time.Millisecond * 100)
 	ra, err := net.ResolveUDPAddr("udp", "localhost:24816")
@@ -104,21 +167,22 @@ func TestMultipartMessage(t *testing.T) {
 	}
 
 	log.Printf("Opened client conn!")
-	packet := messages.NewPacket(messages.CreateAcctMsgType, &messages.CreateAcct{
+	session := handshake(t, clientconn)
+	packet := session.Seal(messages.NewPacket(messages.CreateAcctMsgType, &messages.CreateAcct{
 		Name:     "testuser",
 		Password: "testpass",
-	})
+	}))
 	msgbytes := packet.Pack()
 	_, err = clientconn.Write(msgbytes)
 	if err != nil {
 		fmt.Printf("Failed to write to connection.")
 		fmt.Println(err)
 	}
-	time.Sleep(time.Millisecond * 100)
+	readPacket(t, clientconn, session, messages.CreateAcctRespMsgType)
 
-	packet = messages.NewPacket(messages.CreateGameMsgType, &messages.CreateGame{
+	packet = session.Seal(messages.NewPacket(messages.CreateGameMsgType, &messages.CreateGame{
 		Name: "testgame",
-	})
+	}))
 	msgbytes = packet.Pack()
 	_, err = clientconn.Write(msgbytes)
 	if err != nil {
@@ -126,34 +190,69 @@ func TestMultipartMessage(t *testing.T) {
 		fmt.Println(err)
 	}
 
-	time.Sleep(time.Millisecond * 100)
-
-	multibuffer := make([]byte, 0)
-	widx := 0
-	buf := make([]byte, 8092)
 	for {
-		n, err := clientconn.Read(buf[widx:])
-		if err != nil {
-			fmt.Printf("Failed to read from conn.")
-			fmt.Println(err)
+		tmsg := readPacket(t, clientconn, session, messages.MultipartMsgType).NetMsg.(*messages.Multipart)
+		if tmsg.ID == tmsg.NumParts-1 {
+			log.Printf("Reassembled the multi-message successfully.")
 			return
 		}
-		widx += n
-		for {
-			pack, ok := messages.NextPacket(buf[:widx])
-			if !ok {
-				break
-			}
-			copy(buf, buf[pack.Len():])
-			widx -= pack.Len()
-			tmsg, ok := pack.NetMsg.(*messages.Multipart)
-			if ok {
-				multibuffer = append(multibuffer, tmsg.Content...)
-				if tmsg.ID == tmsg.NumParts-1 {
-					log.Printf("Reassembled the multi-message successfully.")
-					return
-				}
-			}
-		}
 	}
 }
+
+func TestSecureHandshake(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, _ := messages.MarshalServerKey(&key.PublicKey)
+	gamechan := make(chan GameMessage, 10)
+	netchan := make(chan OutgoingMessage, 10)
+	fakeClient := &Client{
+		address:         &net.UDPAddr{},
+		FromNetwork:     NewBytePipe(0),
+		ToNetwork:       netchan,
+		FromGameManager: make(chan InternalMessage, 10),
+		toGameManager:   gamechan,
+		ID:              1,
+		serverKey:       key,
+		serverPublicKey: pub,
+	}
+	go fakeClient.ProcessBytes(make(chan Client, 1))
+	<-gamechan // Connected message
+
+	// Account messages are dropped until there is a session so passwords are never sent in the clear.
+	fakeClient.FromNetwork.Write(messages.NewPacket(messages.CreateAcctMsgType, &messages.CreateAcct{Name: "early", Password: "testpass"}).Pack())
+	fakeClient.FromNetwork.Write(messages.NewPacket(messages.KeyRequestMsgType, &messages.KeyRequest{}).Pack())
+	out := <-netchan
+	if out.msg.Frame.MsgType != messages.ServerKeyMsgType || !out.reliable {
+		t.Fatalf("Expected reliable server key, got %v", out.msg.Frame)
+	}
+
+	sessionKey, _ := messages.NewSessionKey()
+	encrypted, err := messages.EncryptSessionKey(out.msg.NetMsg.(*messages.ServerKey).Key, sessionKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fakeClient.FromNetwork.Write(messages.NewPacket(messages.SessionKeyMsgType, &messages.SessionKey{Key: encrypted}).Pack())
+	out = <-netchan
+	if out.msg.Frame.MsgType != messages.SessionReadyMsgType || fakeClient.secureSession() == nil {
+		t.Fatalf("Expected session to be ready, got %v", out.msg.Frame)
+	}
+
+	// Unencrypted messages are ignored now, only the sealed login should make it through.
+	session, _ := messages.NewSession(sessionKey, false)
+	fakeClient.FromNetwork.Write(messages.NewPacket(messages.LoginMsgType, &messages.Login{Name: "plain"}).Pack())
+	sealed := session.Seal(messages.NewPacket(messages.LoginMsgType, &messages.Login{Name: "secure", Password: "testpass"}))
+	fakeClient.FromNetwork.Write(sealed.Pack())
+	fakeClient.FromNetwork.Write(sealed.Pack()) // Replay is dropped.
+	fakeClient.FromNetwork.Write(session.Seal(messages.NewPacket(messages.ListGamesMsgType, &messages.ListGames{})).Pack())
+
+	msg := <-gamechan
+	if login, ok := msg.net.(*messages.Login); !ok || login.Name != "secure" {
+		t.Fatalf("Expected only the encrypted login, got %v", msg.net)
+	}
+	if msg = <-gamechan; msg.mtype != messages.ListGamesMsgType {
+		t.Fatalf("Replayed login should have been dropped, got %v", msg.net)
+	}
+	fakeClient.FromNetwork.Write(messages.NewPacket(messages.DisconnectedMsgType, &messages.Disconnected{}).Pack())
+}